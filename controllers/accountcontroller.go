@@ -0,0 +1,277 @@
+package controllers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"yt_backend/models"
+	"yt_backend/repository"
+	"yt_backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// exportHistoryBatch is how many watch history entries are read at a time
+const exportHistoryBatch = 500
+
+// DeleteAccount deletes the signed-in user's account. Users with a password
+// confirm it. The account and its sessions go right away; everything else the
+// user left behind is removed by a background job.
+func DeleteAccount(c *gin.Context) {
+	var request struct {
+		Password string `json:"password"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	user, err := repos.Users.FindByID(ctx, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.Password != "" {
+		if request.Password == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Password is required"})
+			return
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password)); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect"})
+			return
+		}
+	}
+
+	// Queue the cleanup first; it also deletes the user, should that fail below
+	job, err := jobQueue.EnqueueAccountDeletion(ctx, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule account deletion"})
+		return
+	}
+	if _, err := revokeUserSessions(ctx, user.ID, "", "account deleted"); err != nil {
+		log.Printf("Failed to revoke sessions of deleted user %s: %v", user.ID, err)
+	}
+	if err := repos.Users.Delete(ctx, user.ID); err != nil && err != repository.ErrNotFound {
+		log.Printf("Failed to delete user %s, leaving it to job %s: %v", user.ID, job.ID, err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Account deleted; the rest of your data will be removed shortly",
+		"jobId":   job.ID,
+	})
+}
+
+// exportFile is a JSON document in the export archive
+type exportFile struct {
+	name string
+	data any
+}
+
+// exportMedia is an uploaded file copied into the export archive
+type exportMedia struct {
+	name string
+	url  string
+}
+
+// ExportAccount streams a ZIP archive of everything stored about the signed-in
+// user: their account, content and activity as JSON, plus the files they uploaded
+func ExportAccount(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := repos.Users.FindByID(ctx, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Read everything before the response starts, while an error can still be reported
+	files, media, err := collectAccountData(ctx, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to collect account data"})
+		return
+	}
+	store, err := utils.GetMediaStore()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Media storage is not configured"})
+		return
+	}
+
+	filename := fmt.Sprintf("%s-export-%s.zip", user.Username, time.Now().UTC().Format("2006-01-02"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	archive := zip.NewWriter(c.Writer)
+	for _, file := range files {
+		if err := writeExportJSON(archive, file.name, file.data); err != nil {
+			log.Printf("Failed to write %s to the export of user %s: %v", file.name, user.ID, err)
+			return
+		}
+	}
+
+	// Files that can't be read, like an avatar hosted by a sign-in provider,
+	// are listed instead of failing the whole export
+	missing := []gin.H{}
+	for _, item := range media {
+		err := copyExportMedia(ctx, store, archive, item)
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		missing = append(missing, gin.H{"file": item.name, "url": item.url, "error": err.Error()})
+	}
+	if len(missing) > 0 {
+		if err := writeExportJSON(archive, "media/missing.json", missing); err != nil {
+			log.Printf("Failed to write media/missing.json to the export of user %s: %v", user.ID, err)
+			return
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		log.Printf("Failed to finish the export of user %s: %v", user.ID, err)
+	}
+}
+
+// collectAccountData reads the user's data from every collection and lists
+// the uploaded files to include
+func collectAccountData(ctx context.Context, user *models.User) ([]exportFile, []exportMedia, error) {
+	videos, err := repos.Videos.List(ctx, repository.VideoQuery{OwnerID: user.ID, IncludeHidden: true, Now: time.Now()})
+	if err != nil {
+		return nil, nil, err
+	}
+	comments, err := repos.Comments.ListByOwner(ctx, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	likes, err := repos.Likes.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	subscriptions, err := repos.Subscriptions.ListBySubscriber(ctx, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	playlists, err := repos.Playlists.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	history := []models.VideoWatchEntry{}
+	for {
+		entries, err := repos.WatchHistory.ListByUser(ctx, user.ID, int64(len(history)), exportHistoryBatch)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, entry := range entries {
+			entry.Video = nil
+			history = append(history, entry)
+		}
+		if len(entries) < exportHistoryBatch {
+			break
+		}
+	}
+	sessions, err := repos.Sessions.ListActiveByUser(ctx, user.ID, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	identities, err := repos.Identities.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	apiKeys, err := repos.APIKeys.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var channel *models.Channel
+	if user.ChannelName.ID != "" {
+		channel, err = repos.Channels.FindByID(ctx, user.ChannelName.ID)
+		if err != nil && err != repository.ErrNotFound {
+			return nil, nil, err
+		}
+	}
+	twoFactor := gin.H{"enabled": false}
+	if settings, err := repos.TwoFactor.FindByUser(ctx, user.ID); err == nil && settings.Enabled() {
+		twoFactor = gin.H{"enabled": true, "enabledAt": settings.EnabledAt}
+	} else if err != nil && err != repository.ErrNotFound {
+		return nil, nil, err
+	}
+
+	files := []exportFile{
+		{"account.json", gin.H{"user": user, "twoFactor": twoFactor, "exportedAt": time.Now().UTC()}},
+		{"channel.json", channel},
+		{"videos.json", videos},
+		{"comments.json", comments},
+		{"likes.json", likes},
+		{"subscriptions.json", subscriptions},
+		{"playlists.json", playlists},
+		{"watch_history.json", history},
+		{"sessions.json", sessions},
+		{"identities.json", identities},
+		{"api_keys.json", apiKeys},
+	}
+
+	media := []exportMedia{}
+	addMedia := func(name string, fileURL string) {
+		if fileURL != "" {
+			media = append(media, exportMedia{name: name + mediaExtension(fileURL), url: fileURL})
+		}
+	}
+	addMedia("media/avatar", user.Avatar)
+	addMedia("media/cover", user.CoverImage)
+	if channel != nil {
+		addMedia("media/banner", channel.Banner)
+	}
+	for _, video := range videos {
+		addMedia("media/videos/"+video.ID, video.URL)
+		if video.CustomThumbnail {
+			addMedia("media/thumbnails/"+video.ID, video.Thumbnail)
+		}
+	}
+	return files, media, nil
+}
+
+func writeExportJSON(archive *zip.Writer, name string, data any) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+func copyExportMedia(ctx context.Context, store utils.MediaStore, archive *zip.Writer, item exportMedia) error {
+	src, err := store.Open(ctx, item.url)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	w, err := archive.Create(item.name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, src)
+	return err
+}
+
+// mediaExtension returns the extension of the file behind a media URL
+func mediaExtension(fileURL string) string {
+	parsed, err := url.Parse(fileURL)
+	if err != nil {
+		return ""
+	}
+	return path.Ext(parsed.Path)
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"yt_backend/models"
+	"yt_backend/repository"
+	"yt_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// minPasswordLength is the shortest password a reset accepts
+const minPasswordLength = 8
+
+// normalizeEmail lowercases a bare address like "name@example.com" and reports
+// whether it is valid; display names ("Name <name@example.com>") are rejected
+func normalizeEmail(value string) (string, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	address, err := mail.ParseAddress(value)
+	at := strings.LastIndex(value, "@")
+	if err != nil || address.Address != value || !strings.Contains(value[at+1:], ".") {
+		return "", false
+	}
+	return value, true
+}
+
+// VerifyEmail confirms the user's address with the token from the link we emailed
+func VerifyEmail(c *gin.Context) {
+	var request struct {
+		Token string `json:"token"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	token, err := repos.UserTokens.Consume(ctx, utils.HashOneTimeToken(request.Token), models.TokenPurposeVerifyEmail, time.Now())
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	// The link only proves ownership of the address it was sent to
+	err = repos.Users.MarkEmailVerified(ctx, token.UserID, token.Email, time.Now())
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// ResendVerificationEmail sends a new verification link; earlier links stop working
+func ResendVerificationEmail(c *gin.Context) {
+	user, err := repos.Users.FindByID(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.EmailVerified() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	if err := jobQueue.EnqueueAccountEmail(c.Request.Context(), user.ID, models.TokenPurposeVerifyEmail); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+}
+
+// ForgotPassword emails a password reset link. It answers the same whether or
+// not the address belongs to an account, so it can't be used to find accounts.
+func ForgotPassword(c *gin.Context) {
+	var request struct {
+		Email string `json:"email"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+	email, ok := normalizeEmail(request.Email)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid email is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := repos.Users.FindByEmail(ctx, email)
+	if err != nil && err != repository.ErrNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+	// Suspended accounts can't sign in, so there is no point resetting their password
+	if err == nil && !user.Suspended() {
+		if err := jobQueue.EnqueueAccountEmail(ctx, user.ID, models.TokenPurposeResetPassword); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If an account uses that email, a password reset link has been sent to it"})
+}
+
+// ResetPassword sets a new password with the token from a reset link and signs
+// the account out everywhere
+func ResetPassword(c *gin.Context) {
+	var request struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token and new password are required"})
+		return
+	}
+	if len(request.NewPassword) < minPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters"})
+		return
+	}
+
+	// Hash first so a failure here doesn't use up the token
+	hashedPassword, err := HashPass(request.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash new password"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	token, err := repos.UserTokens.Consume(ctx, utils.HashOneTimeToken(request.Token), models.TokenPurposeResetPassword, time.Now())
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	user, err := repos.Users.FindByID(ctx, token.UserID)
+	if err == repository.ErrNotFound || (err == nil && (user.Email != token.Email || user.Suspended())) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	if err := repos.Users.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+
+	// Following the link also proves the user owns the address
+	if !user.EmailVerified() {
+		if err := repos.Users.MarkEmailVerified(ctx, user.ID, token.Email, time.Now()); err != nil {
+			log.Printf("Failed to mark email of user %s verified: %v", user.ID, err)
+		}
+	}
+	// Whoever got hold of the old password may be signed in somewhere
+	if _, err := revokeUserSessions(ctx, user.ID, "", "password reset"); err != nil {
+		log.Printf("Failed to revoke sessions of user %s: %v", user.ID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset; please log in with your new password"})
+}
@@ -0,0 +1,371 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"yt_backend/models"
+	"yt_backend/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// moderationRequest carries the reason staff give for an action
+type moderationRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ListUsers lets staff browse accounts, filtered by username prefix, role or suspension
+func ListUsers(c *gin.Context) {
+	page, limit := pageParams(c)
+	query := repository.UserQuery{
+		Username:  c.Query("username"),
+		Role:      c.Query("role"),
+		Suspended: c.Query("suspended") == "true",
+		Skip:      int64((page - 1) * limit),
+		Limit:     int64(limit),
+	}
+	if query.Role != "" && !slices.Contains(models.Roles, query.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+		return
+	}
+
+	users, err := repos.Users.List(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+	for i := range users {
+		users[i].Password = ""
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users, "page": page, "limit": limit})
+}
+
+// SuspendUser blocks an account from signing in and ends all of its sessions.
+// Staff can only suspend accounts with a lower role than their own.
+func SuspendUser(c *gin.Context) {
+	var request moderationRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	target, ok := findManagedUser(c)
+	if !ok {
+		return
+	}
+	if target.Suspended() {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already suspended"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := repos.Users.Suspend(ctx, target.ID, time.Now(), request.Reason); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suspend user"})
+		return
+	}
+	revoked, err := revokeUserSessions(ctx, target.ID, "", "suspended")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end the user's sessions"})
+		return
+	}
+
+	recordAudit(c, models.AuditLog{
+		Action:     models.AuditUserSuspended,
+		TargetType: "user",
+		TargetID:   target.ID,
+		Reason:     request.Reason,
+		Details:    map[string]string{"username": target.Username},
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "User suspended", "sessionsRevoked": revoked})
+}
+
+// UnsuspendUser lets a suspended account sign in again
+func UnsuspendUser(c *gin.Context) {
+	target, ok := findManagedUser(c)
+	if !ok {
+		return
+	}
+	if !target.Suspended() {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is not suspended"})
+		return
+	}
+
+	if err := repos.Users.Unsuspend(c.Request.Context(), target.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsuspend user"})
+		return
+	}
+
+	recordAudit(c, models.AuditLog{
+		Action:     models.AuditUserUnsuspended,
+		TargetType: "user",
+		TargetID:   target.ID,
+		Details:    map[string]string{"username": target.Username},
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "User unsuspended"})
+}
+
+// SetUserRole changes an account's role. Admins can't change their own role, so
+// there is always someone left who can undo a mistake.
+func SetUserRole(c *gin.Context) {
+	var request struct {
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || !slices.Contains(models.Roles, request.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be one of user, creator, moderator or admin"})
+		return
+	}
+
+	userID := c.Param("userId")
+	if userID == c.GetString("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot change your own role"})
+		return
+	}
+	target, err := repos.Users.FindByID(c.Request.Context(), userID)
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	previous := target.Role
+	if previous == "" {
+		previous = models.RoleUser
+	}
+	if previous == request.Role {
+		c.JSON(http.StatusOK, gin.H{"message": "Role unchanged", "role": request.Role})
+		return
+	}
+
+	if err := repos.Users.SetRole(c.Request.Context(), target.ID, request.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	recordAudit(c, models.AuditLog{
+		Action:     models.AuditUserRoleChanged,
+		TargetType: "user",
+		TargetID:   target.ID,
+		Details:    map[string]string{"username": target.Username, "from": previous, "to": request.Role},
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated", "role": request.Role})
+}
+
+// TakeDownVideo hides a video from everyone but its owner without deleting it
+func TakeDownVideo(c *gin.Context) {
+	var request moderationRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	video, ok := findModeratedVideo(c)
+	if !ok {
+		return
+	}
+	if video.TakenDownAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Video is already taken down"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	now := time.Now()
+	if err := repos.Videos.SetTakedown(ctx, video.ID, &now, request.Reason); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to take down video"})
+		return
+	}
+	if err := searchIndex.Remove(ctx, video.ID); err != nil {
+		log.Printf("Failed to remove video %s from the search index: %v", video.ID, err)
+	}
+
+	recordAudit(c, models.AuditLog{
+		Action:     models.AuditVideoTakenDown,
+		TargetType: "video",
+		TargetID:   video.ID,
+		Reason:     request.Reason,
+		Details:    map[string]string{"title": video.Title, "ownerId": video.OwnerID},
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "Video taken down"})
+}
+
+// RestoreVideo reverses a takedown
+func RestoreVideo(c *gin.Context) {
+	video, ok := findModeratedVideo(c)
+	if !ok {
+		return
+	}
+	if video.TakenDownAt == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Video is not taken down"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := repos.Videos.SetTakedown(ctx, video.ID, nil, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore video"})
+		return
+	}
+	video.TakenDownAt = nil
+	video.TakedownReason = ""
+	syncSearchIndex(ctx, video)
+
+	recordAudit(c, models.AuditLog{
+		Action:     models.AuditVideoRestored,
+		TargetType: "video",
+		TargetID:   video.ID,
+		Details:    map[string]string{"title": video.Title, "ownerId": video.OwnerID},
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "Video restored"})
+}
+
+// RemoveComment deletes any comment; the reason is optional
+func RemoveComment(c *gin.Context) {
+	var request moderationRequest
+	// A DELETE usually has no body, so a missing one is fine
+	_ = c.ShouldBindJSON(&request)
+
+	comment, err := repos.Comments.FindByID(c.Request.Context(), c.Param("commentId"))
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comment"})
+		return
+	}
+
+	if err := repos.Comments.Delete(c.Request.Context(), comment.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+
+	recordAudit(c, commentDeletedAudit(comment, request.Reason))
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+// ListAuditLogs returns the audit log, newest first
+func ListAuditLogs(c *gin.Context) {
+	page, limit := pageParams(c)
+	entries, err := repos.AuditLogs.List(c.Request.Context(), repository.AuditLogQuery{
+		ActorID:    c.Query("actorId"),
+		Action:     c.Query("action"),
+		TargetType: c.Query("targetType"),
+		TargetID:   c.Query("targetId"),
+		Skip:       int64((page - 1) * limit),
+		Limit:      int64(limit),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"auditLogs": entries, "page": page, "limit": limit})
+}
+
+// findManagedUser loads the user named in the path, checking the current user
+// outranks them; it writes the error response when it returns false
+func findManagedUser(c *gin.Context) (*models.User, bool) {
+	userID := c.Param("userId")
+	if userID == c.GetString("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot moderate your own account"})
+		return nil, false
+	}
+
+	target, err := repos.Users.FindByID(c.Request.Context(), userID)
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return nil, false
+	}
+
+	if models.RoleRank(target.Role) >= models.RoleRank(c.GetString("user_role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot moderate a user with the same or a higher role"})
+		return nil, false
+	}
+	return target, true
+}
+
+// findModeratedVideo loads the video named in the path; it writes the error
+// response when it returns false
+func findModeratedVideo(c *gin.Context) (*models.Video, bool) {
+	video, err := repos.Videos.FindByID(c.Request.Context(), c.Param("videoId"))
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch video"})
+		return nil, false
+	}
+	return video, true
+}
+
+// actingAsStaff reports whether the current user holds at least role and isn't
+// suspended, for handlers outside /admin that let staff act on other people's
+// content. It remembers the role for the audit log. API keys only ever act as
+// their owner, never with staff powers.
+func actingAsStaff(c *gin.Context, role string) (bool, error) {
+	if c.GetString("api_key_id") != "" {
+		return false, nil
+	}
+	user, err := repos.Users.FindByID(c.Request.Context(), c.GetString("user_id"))
+	if err == repository.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if user.Suspended() || !user.HasRole(role) {
+		return false, nil
+	}
+	c.Set("user_role", user.Role)
+	return true, nil
+}
+
+func commentDeletedAudit(comment *models.VideoComment, reason string) models.AuditLog {
+	return models.AuditLog{
+		Action:     models.AuditCommentDeleted,
+		TargetType: "comment",
+		TargetID:   comment.ID,
+		Reason:     reason,
+		Details:    map[string]string{"videoId": comment.VideoID, "ownerId": comment.OwnerID, "content": comment.Content},
+	}
+}
+
+// recordAudit stores an audit entry for an action the current user just took.
+// The action has already happened, so a failure is logged rather than returned.
+func recordAudit(c *gin.Context, entry models.AuditLog) {
+	entry.ID = uuid.New().String()
+	entry.ActorID = c.GetString("user_id")
+	entry.ActorRole = c.GetString("user_role")
+	entry.CreatedAt = time.Now()
+	if err := repos.AuditLogs.Record(c.Request.Context(), &entry); err != nil {
+		log.Printf("Failed to record audit entry %s on %s %s: %v", entry.Action, entry.TargetType, entry.TargetID, err)
+	}
+}
+
+// pageParams reads the page (from 1) and limit (1 to 100, default 20) query parameters
+func pageParams(c *gin.Context) (int, int) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	return page, limit
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"yt_backend/models"
+	"yt_backend/repository"
+	"yt_backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// API key limits
+const (
+	maxAPIKeysPerUser   = 25
+	defaultAPIKeyDays   = 90
+	maxAPIKeyDays       = 365
+	maxAPIKeyNameLength = 100
+)
+
+// CreateAPIKey mints a named API key with the given scopes. The key is in the
+// response only; afterwards just its prefix is shown.
+func CreateAPIKey(c *gin.Context) {
+	var request struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expiresInDays"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" || len(request.Name) > maxAPIKeyNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required and must be at most 100 characters"})
+		return
+	}
+	if len(request.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one scope is required", "availableScopes": models.APIKeyScopes})
+		return
+	}
+	for _, scope := range request.Scopes {
+		if !slices.Contains(models.APIKeyScopes, scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope " + scope, "availableScopes": models.APIKeyScopes})
+			return
+		}
+	}
+	if request.ExpiresInDays == 0 {
+		request.ExpiresInDays = defaultAPIKeyDays
+	}
+	if request.ExpiresInDays < 1 || request.ExpiresInDays > maxAPIKeyDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expiresInDays must be between 1 and 365"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	existing, err := repos.APIKeys.ListByUser(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+	if len(existing) >= maxAPIKeysPerUser {
+		c.JSON(http.StatusConflict, gin.H{"error": "You have too many API keys; revoke one first"})
+		return
+	}
+
+	secret, prefix, hash, err := utils.NewAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+	scopes := slices.Clone(request.Scopes)
+	slices.Sort(scopes)
+	now := time.Now()
+	key := models.APIKey{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		Name:       request.Name,
+		Prefix:     prefix,
+		SecretHash: hash,
+		Scopes:     slices.Compact(scopes),
+		ExpiresAt:  now.AddDate(0, 0, request.ExpiresInDays),
+		CreatedAt:  now,
+	}
+	if err := repos.APIKeys.Create(ctx, &key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created; copy it now, it won't be shown again",
+		"key":     secret,
+		"apiKey":  key,
+	})
+}
+
+// ListAPIKeys returns the user's API keys, without their secrets
+func ListAPIKeys(c *gin.Context) {
+	keys, err := repos.APIKeys.ListByUser(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"apiKeys": keys, "availableScopes": models.APIKeyScopes})
+}
+
+// RevokeAPIKey deletes an API key; it stops working at once
+func RevokeAPIKey(c *gin.Context) {
+	err := repos.APIKeys.Delete(c.Request.Context(), c.Param("keyId"), c.GetString("user_id"))
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
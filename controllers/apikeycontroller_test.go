@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"yt_backend/middleware"
+	"yt_backend/models"
+	"yt_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyRouter has a route API keys with videos:write may use and one that is
+// for people only; both report who the request acts as
+func apiKeyRouter() *gin.Engine {
+	whoami := func(c *gin.Context) {
+		staff, err := actingAsStaff(c, models.RoleModerator)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"userId": c.GetString("user_id"), "apiKeyId": c.GetString("api_key_id"), "staff": staff})
+	}
+	router := gin.New()
+	router.GET("/videos", middleware.AuthMiddleware(models.ScopeVideosWrite), whoami)
+	router.GET("/account", middleware.AuthMiddleware(), whoami)
+	return router
+}
+
+// getWithToken sends a bearer token to the router and decodes the response
+func getWithToken(t *testing.T, router *gin.Engine, path string, token string) (int, map[string]interface{}) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+	}
+	return w.Code, response
+}
+
+// storeAPIKey saves a key for the user directly, for the states CreateAPIKey won't produce
+func storeAPIKey(t *testing.T, userID string, expiresAt time.Time, scopes ...string) string {
+	t.Helper()
+	secret, prefix, hash, err := utils.NewAPIKey()
+	if err != nil {
+		t.Fatalf("NewAPIKey: %v", err)
+	}
+	key := &models.APIKey{ID: prefix, UserID: userID, Name: "test", Prefix: prefix, SecretHash: hash, Scopes: scopes, ExpiresAt: expiresAt, CreatedAt: time.Now()}
+	if err := repos.APIKeys.Create(context.Background(), key); err != nil {
+		t.Fatalf("APIKeys.Create: %v", err)
+	}
+	return secret
+}
+
+func TestAPIKeyActsForItsOwner(t *testing.T) {
+	useMemoryRepositories(t)
+	createPasswordUser(t)
+	router := apiKeyRouter()
+
+	w, response := postJSONAs(t, "u1", CreateAPIKey, map[string]interface{}{"name": "ci", "scopes": []string{models.ScopeVideosWrite}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("CreateAPIKey = %d %v", w.Code, response)
+	}
+	key := response["key"].(string)
+
+	status, response := getWithToken(t, router, "/videos", key)
+	if status != http.StatusOK || response["userId"] != "u1" || response["apiKeyId"] == "" {
+		t.Fatalf("scoped route = %d %v, want it to act as u1 through the key", status, response)
+	}
+
+	// Routes without scopes are for people only
+	if status, response := getWithToken(t, router, "/account", key); status != http.StatusForbidden {
+		t.Errorf("unscoped route = %d %v, want 403", status, response)
+	}
+}
+
+func TestAPIKeyRejections(t *testing.T) {
+	useMemoryRepositories(t)
+	createPasswordUser(t)
+	suspendedAt := time.Now()
+	suspended := &models.User{ID: "u2", Username: "mallory", Email: "mallory@example.com", Role: models.RoleUser, SuspendedAt: &suspendedAt}
+	if err := repos.Users.Create(context.Background(), suspended); err != nil {
+		t.Fatalf("Users.Create: %v", err)
+	}
+	router := apiKeyRouter()
+	later := time.Now().Add(time.Hour)
+
+	valid := storeAPIKey(t, "u1", later, models.ScopeVideosWrite)
+	prefix, _, _ := utils.ParseAPIKey(valid)
+
+	tests := []struct {
+		name   string
+		key    string
+		status int
+		error  string
+	}{
+		{"wrong secret", "ytk_" + prefix + "_" + strings.Repeat("A", 43), http.StatusUnauthorized, "Invalid API key"},
+		{"unknown prefix", "ytk_0123456789abcdef_secret", http.StatusUnauthorized, "Invalid API key"},
+		{"malformed", "ytk_short", http.StatusUnauthorized, "Invalid API key"},
+		{"expired", storeAPIKey(t, "u1", time.Now().Add(-time.Minute), models.ScopeVideosWrite), http.StatusUnauthorized, "API key has expired"},
+		{"missing scope", storeAPIKey(t, "u1", later, models.ScopeVideosRead, models.ScopeLikesWrite), http.StatusForbidden, "API key is missing a required scope"},
+		{"suspended owner", storeAPIKey(t, "u2", later, models.ScopeVideosWrite), http.StatusUnauthorized, "Invalid API key"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, response := getWithToken(t, router, "/videos", test.key)
+			if status != test.status || response["error"] != test.error {
+				t.Errorf("response = %d %v, want %d %q", status, response, test.status, test.error)
+			}
+			if response["userId"] != nil {
+				t.Errorf("request went through as %v", response["userId"])
+			}
+		})
+	}
+
+	// The valid key still works after all that
+	if status, _ := getWithToken(t, router, "/videos", valid); status != http.StatusOK {
+		t.Errorf("valid key = %d, want 200", status)
+	}
+}
+
+func TestAPIKeyNeverActsAsStaff(t *testing.T) {
+	useMemoryRepositories(t)
+	admin := &models.User{ID: "u1", Username: "root", Email: "root@example.com", Role: models.RoleAdmin}
+	if err := repos.Users.Create(context.Background(), admin); err != nil {
+		t.Fatalf("Users.Create: %v", err)
+	}
+	router := apiKeyRouter()
+
+	key := storeAPIKey(t, admin.ID, time.Now().Add(time.Hour), models.ScopeVideosWrite)
+	if status, response := getWithToken(t, router, "/videos", key); status != http.StatusOK || response["staff"] != false {
+		t.Errorf("admin's API key = %d %v, want it to act without staff powers", status, response)
+	}
+
+	// The same admin signed in with an access token is staff
+	c, _ := newTestContext()
+	tokens, err := startSession(c, admin.ID, nil)
+	if err != nil {
+		t.Fatalf("startSession: %v", err)
+	}
+	if status, response := getWithToken(t, router, "/videos", tokens["token"].(string)); status != http.StatusOK || response["staff"] != true {
+		t.Errorf("admin's access token = %d %v, want staff", status, response)
+	}
+}
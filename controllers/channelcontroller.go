@@ -0,0 +1,435 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"yt_backend/models"
+	"yt_backend/repository"
+	"yt_backend/search"
+	"yt_backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Limits on what owners can put on their channel
+const (
+	maxChannelNameLength        = 100
+	maxChannelDescriptionLength = 1000
+	maxBannerSize               = 6 << 20 // 6 MB
+)
+
+// handlePattern is what a channel handle may look like once lowercased, without the "@"
+var handlePattern = regexp.MustCompile(`^[a-z0-9._-]{3,30}$`)
+
+var handleUnsafe = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// normalizeHandle lowercases a handle and drops a leading "@", reporting false if it isn't valid
+func normalizeHandle(handle string) (string, bool) {
+	handle = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+	return handle, handlePattern.MatchString(handle)
+}
+
+// CreateChannel creates the signed-in user's channel and makes them a creator.
+// Each user has at most one channel. The handle is made from the channel name
+// unless one is given.
+func CreateChannel(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := repos.Users.FindByID(ctx, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.ChannelName.ID != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have a channel"})
+		return
+	}
+
+	// Get channel name from form data
+	channelName := strings.TrimSpace(c.PostForm("channelName"))
+	if channelName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Channel name is required"})
+		return
+	}
+	if utf8.RuneCountInString(channelName) > maxChannelNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Channel name must be 100 characters or fewer"})
+		return
+	}
+	description := strings.TrimSpace(c.PostForm("description"))
+	if utf8.RuneCountInString(description) > maxChannelDescriptionLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Description must be 1000 characters or fewer"})
+		return
+	}
+	handle, chosen := "", c.PostForm("handle") != ""
+	if chosen {
+		var ok bool
+		if handle, ok = normalizeHandle(c.PostForm("handle")); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Handle must be 3 to 30 letters, digits, dots, dashes or underscores"})
+			return
+		}
+	}
+
+	// Create a new Channel instance
+	channel := models.Channel{
+		ID:          uuid.New().String(),
+		ChannelName: channelName,
+		OwnerID:     user.ID,
+		Description: description,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	// Insert channel into channels collection. A made-up handle that is taken
+	// gets a random suffix; a duplicate can also mean the user just created a
+	// channel in another request.
+	for attempt := 0; attempt < 5; attempt++ {
+		channel.Handle = handle
+		if !chosen {
+			channel.Handle = handleFromName(channelName, attempt)
+		}
+		err = repos.Channels.Create(ctx, &channel)
+		if err != repository.ErrDuplicate {
+			break
+		}
+		if _, err := repos.Channels.FindByHandle(ctx, channel.Handle); err != nil || chosen {
+			break
+		}
+	}
+	if err == repository.ErrDuplicate {
+		if _, err := repos.Channels.FindByHandle(ctx, channel.Handle); err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Handle is already taken"})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have a channel"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create channel"})
+		return
+	}
+
+	// Update the user's channel reference
+	err = repos.Users.SetChannel(ctx, user.ID, channel)
+	if err != nil {
+		// If user update fails, we should clean up the channel
+		_ = repos.Channels.Delete(ctx, channel.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user channel"})
+		return
+	}
+
+	// Get the updated user
+	updatedUser, err := repos.Users.FindByID(ctx, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated user"})
+		return
+	}
+
+	// Owning a channel makes a plain user a creator; staff keep their role
+	if !updatedUser.HasRole(models.RoleCreator) {
+		if err := repos.Users.SetRole(ctx, updatedUser.ID, models.RoleCreator); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user role"})
+			return
+		}
+		updatedUser.Role = models.RoleCreator
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Channel created successfully",
+		"channel": channel,
+		"user":    updatedUser,
+	})
+}
+
+// handleFromName makes a handle from a channel name; later attempts add a random number
+func handleFromName(name string, attempt int) string {
+	base := strings.Trim(handleUnsafe.ReplaceAllString(strings.ToLower(name), "-"), "-._")
+	if base == "" {
+		base = "channel"
+	}
+	if len(base) > 25 {
+		base = base[:25]
+	}
+	for len(base) < 3 {
+		base += "0"
+	}
+	if attempt == 0 {
+		return base
+	}
+	return fmt.Sprintf("%s-%04d", base, rand.IntN(10000))
+}
+
+// GetChannel returns a channel by its ID
+func GetChannel(c *gin.Context) {
+	channel, err := repos.Channels.FindByID(c.Request.Context(), c.Param("channelId"))
+	respondWithChannel(c, channel, err)
+}
+
+// GetChannelByHandle returns a channel by its handle, with or without the "@"
+func GetChannelByHandle(c *gin.Context) {
+	handle, ok := normalizeHandle(c.Param("handle"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+	channel, err := repos.Channels.FindByHandle(c.Request.Context(), handle)
+	respondWithChannel(c, channel, err)
+}
+
+func respondWithChannel(c *gin.Context, channel *models.Channel, err error) {
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch channel"})
+		return
+	}
+
+	count, err := repos.Subscriptions.CountByChannel(c.Request.Context(), channel.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count subscribers"})
+		return
+	}
+	response := gin.H{"channel": channel, "subscriberCount": count}
+	if owner, err := repos.Users.FindByID(c.Request.Context(), channel.OwnerID); err == nil {
+		response["owner"] = owner.Summary()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateChannel changes the name, handle, description or links of the
+// signed-in user's channel; fields left out of the request are kept
+func UpdateChannel(c *gin.Context) {
+	channel, ok := ownedChannel(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		ChannelName *string               `json:"channelName"`
+		Handle      *string               `json:"handle"`
+		Description *string               `json:"description"`
+		Links       *[]models.ProfileLink `json:"links"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+	if request.ChannelName == nil && request.Handle == nil && request.Description == nil && request.Links == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	var update repository.ChannelUpdate
+	if request.ChannelName != nil {
+		name := strings.TrimSpace(*request.ChannelName)
+		if name == "" || utf8.RuneCountInString(name) > maxChannelNameLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Channel name must be 1 to 100 characters"})
+			return
+		}
+		update.ChannelName = &name
+	}
+	if request.Handle != nil {
+		handle, ok := normalizeHandle(*request.Handle)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Handle must be 3 to 30 letters, digits, dots, dashes or underscores"})
+			return
+		}
+		update.Handle = &handle
+	}
+	if request.Description != nil {
+		description := strings.TrimSpace(*request.Description)
+		if utf8.RuneCountInString(description) > maxChannelDescriptionLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Description must be 1000 characters or fewer"})
+			return
+		}
+		update.Description = &description
+	}
+	if request.Links != nil {
+		links, message := normalizeProfileLinks(*request.Links)
+		if message != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return
+		}
+		update.Links = &links
+	}
+
+	ctx := c.Request.Context()
+	updated, err := repos.Channels.Update(ctx, channel.ID, update)
+	if err == repository.ErrDuplicate {
+		c.JSON(http.StatusConflict, gin.H{"error": "Handle is already taken"})
+		return
+	}
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update channel"})
+		return
+	}
+	syncOwnerChannel(ctx, updated)
+
+	// Search matches videos by their channel's name
+	if updated.ChannelName != channel.ChannelName {
+		reindexChannelVideos(ctx, updated.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Channel updated successfully",
+		"channel": updated,
+	})
+}
+
+// UpdateChannelBanner replaces the banner of the signed-in user's channel
+func UpdateChannelBanner(c *gin.Context) {
+	channel, ok := ownedChannel(c)
+	if !ok {
+		return
+	}
+
+	file, err := c.FormFile("banner")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Banner file is required"})
+		return
+	}
+	if file.Size > maxBannerSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Banner must be 6 MB or smaller"})
+		return
+	}
+	if !isImageFile(file) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Banner must be a JPEG, PNG or WebP image"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	bannerURL, err := utils.HandleImageUpload(ctx, file, "banner")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload banner"})
+		return
+	}
+	updated, err := repos.Channels.SetBanner(ctx, channel.ID, bannerURL)
+	if err != nil {
+		utils.DeleteMedia(ctx, bannerURL)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update banner"})
+		return
+	}
+	syncOwnerChannel(ctx, updated)
+	deleteProfileImage(ctx, channel.Banner, bannerURL)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Banner updated successfully",
+		"banner":  bannerURL,
+	})
+}
+
+// DeleteChannelBanner removes the banner of the signed-in user's channel
+func DeleteChannelBanner(c *gin.Context) {
+	channel, ok := ownedChannel(c)
+	if !ok {
+		return
+	}
+	if channel.Banner == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Banner not set"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	updated, err := repos.Channels.SetBanner(ctx, channel.ID, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove banner"})
+		return
+	}
+	syncOwnerChannel(ctx, updated)
+	deleteProfileImage(ctx, channel.Banner, "")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Banner removed successfully"})
+}
+
+// DeleteChannel deletes the signed-in user's channel and its subscriptions.
+// Its videos have to be deleted first. A creator without a channel becomes a
+// plain user again.
+func DeleteChannel(c *gin.Context) {
+	channel, ok := ownedChannel(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	videos, err := repos.Videos.List(ctx, repository.VideoQuery{ChannelID: channel.ID, IncludeHidden: true, Now: time.Now(), Limit: 1})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check channel videos"})
+		return
+	}
+	if len(videos) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Delete the channel's videos first"})
+		return
+	}
+
+	if err := repos.Channels.Delete(ctx, channel.ID); err != nil && err != repository.ErrNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete channel"})
+		return
+	}
+	if err := repos.Subscriptions.DeleteByChannel(ctx, channel.ID); err != nil {
+		log.Printf("Failed to delete subscriptions to channel %s: %v", channel.ID, err)
+	}
+	if err := repos.Users.SetChannel(ctx, channel.OwnerID, models.Channel{}); err != nil {
+		log.Printf("Failed to clear channel of user %s: %v", channel.OwnerID, err)
+	}
+	if user, err := repos.Users.FindByID(ctx, channel.OwnerID); err == nil && user.Role == models.RoleCreator {
+		if err := repos.Users.SetRole(ctx, user.ID, models.RoleUser); err != nil {
+			log.Printf("Failed to update role of user %s: %v", user.ID, err)
+		}
+	}
+	deleteProfileImage(ctx, channel.Banner, "")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Channel deleted successfully"})
+}
+
+// ownedChannel loads the channel in the URL, answering 404 or 403 and
+// reporting false unless it belongs to the signed-in user
+func ownedChannel(c *gin.Context) (*models.Channel, bool) {
+	channel, err := repos.Channels.FindByID(c.Request.Context(), c.Param("channelId"))
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch channel"})
+		return nil, false
+	}
+	if channel.OwnerID == "" || channel.OwnerID != c.GetString("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to manage this channel"})
+		return nil, false
+	}
+	return channel, true
+}
+
+// syncOwnerChannel refreshes the copy of the channel kept on its owner's account
+func syncOwnerChannel(ctx context.Context, channel *models.Channel) {
+	if err := repos.Users.SetChannel(ctx, channel.OwnerID, *channel); err != nil {
+		log.Printf("Failed to update channel copy of user %s: %v", channel.OwnerID, err)
+	}
+}
+
+// reindexChannelVideos brings the search documents of a channel's videos up to date
+func reindexChannelVideos(ctx context.Context, channelID string) {
+	videos, err := repos.Videos.List(ctx, repository.VideoQuery{ChannelID: channelID, IncludeHidden: true, Now: time.Now()})
+	if err != nil {
+		log.Printf("Failed to list videos of channel %s for the search index: %v", channelID, err)
+		return
+	}
+	for i := range videos {
+		if err := search.Sync(ctx, searchIndex, &videos[i]); err != nil {
+			log.Printf("Failed to reindex video %s: %v", videos[i].ID, err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"context"
+	"log"
+
+	"yt_backend/jobs"
+	"yt_backend/models"
+	"yt_backend/repository"
+	"yt_backend/search"
+)
+
+// repos holds the repositories used by every handler
+var repos *repository.Repositories
+
+// jobQueue runs background work such as video processing
+var jobQueue *jobs.Queue
+
+// searchIndex answers video searches
+var searchIndex search.Index
+
+// SetRepositories injects the repositories used by the handlers
+func SetRepositories(r *repository.Repositories) {
+	repos = r
+}
+
+// SetJobQueue injects the background job queue
+func SetJobQueue(q *jobs.Queue) {
+	jobQueue = q
+}
+
+// SetSearchIndex injects the video search index
+func SetSearchIndex(index search.Index) {
+	searchIndex = index
+}
+
+// syncSearchIndex updates a video's search document. Search is allowed to lag,
+// so failures are logged rather than failing the request.
+func syncSearchIndex(ctx context.Context, video *models.Video) {
+	if err := search.Sync(ctx, searchIndex, video); err != nil {
+		log.Printf("Failed to update search index for video %s: %v", video.ID, err)
+	}
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"yt_backend/middleware"
+	"yt_backend/models"
+	"yt_backend/repository"
+	"yt_backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestMain loads a throwaway signing key so handlers can issue access tokens
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	dir, err := os.MkdirTemp("", "controllers")
+	if err != nil {
+		panic(err)
+	}
+	key, err := utils.GenerateSigningKey("ed25519")
+	if err != nil {
+		panic(err)
+	}
+	path := filepath.Join(dir, "signing.pem")
+	if err := os.WriteFile(path, key, 0600); err != nil {
+		panic(err)
+	}
+	os.Setenv("JWT_SIGNING_KEY", path)
+	if err := utils.LoadSigningKeys(); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// useMemoryRepositories gives the handlers and the auth middleware empty in-memory repositories
+func useMemoryRepositories(t *testing.T) *repository.Repositories {
+	t.Helper()
+	r := repository.NewMemoryRepositories()
+	SetRepositories(r)
+	middleware.SetRepositories(r)
+	return r
+}
+
+// createPasswordUser stores alice, who logs in with the password "correct horse"
+func createPasswordUser(t *testing.T) *models.User {
+	t.Helper()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	user := &models.User{ID: "u1", Username: "alice", Email: "alice@example.com", Password: string(hash), Role: models.RoleUser}
+	if err := repos.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("Users.Create: %v", err)
+	}
+	return user
+}
+
+// newTestContext is a context for calling helpers that need a request
+func newTestContext() (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	return c, w
+}
+
+// postJSON calls the handler with body as its JSON request and decodes the response
+func postJSON(t *testing.T, handler gin.HandlerFunc, body interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	return postJSONAs(t, "", handler, body)
+}
+
+// postJSONAs is postJSON for a request the auth middleware let through as userID
+func postJSONAs(t *testing.T, userID string, handler gin.HandlerFunc, body interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("encoding request: %v", err)
+	}
+	c, w := newTestContext()
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+	c.Request.Header.Set("Content-Type", "application/json")
+	if userID != "" {
+		c.Set("user_id", userID)
+	}
+	handler(c)
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+	}
+	return w, response
+}
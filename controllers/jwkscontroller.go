@@ -0,0 +1,16 @@
+package controllers
+
+import (
+	"net/http"
+
+	"yt_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS publishes the public keys access tokens are signed with so other services can verify them
+func JWKS(c *gin.Context) {
+	// Verifiers refetch on an unknown kid, so a short cache is enough to pick up rotations
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.JWKS())
+}
@@ -1,17 +1,14 @@
 package controllers
 
 import (
-	"context"
-	"fmt"
+	"log"
 	"net/http"
 	"time"
-	"yt_backend/db"
 	"yt_backend/models"
+	"yt_backend/repository"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func LikeVideo(c *gin.Context) {
@@ -27,58 +24,53 @@ func LikeVideo(c *gin.Context) {
 		return
 	}
 
-	likeCollection := db.GetCollection("likes")
-
-	// Check if like already exists
-	filter := bson.M{
-		"owner._id": userID,
-		"vlike._id": videoID,
-	}
-
-	userCollection := db.GetCollection("users")
-	var user models.User
-	err := userCollection.FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user)
+	user, err := repos.Users.FindByID(c.Request.Context(), userID.(string))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
 		return
 	}
 
-	videoCollection := db.GetCollection("videos")
-	var video models.Video
-	err = videoCollection.FindOne(context.TODO(), bson.M{"_id": videoID}).Decode(&video)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+	video, ok := findViewableVideo(c, videoID)
+	if !ok {
 		return
 	}
 
-	var existingLike models.Like
-	err = likeCollection.FindOne(context.TODO(), filter).Decode(&existingLike)
-
-	fmt.Println(err)
-
-	if err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "You already liked this video"})
-		return
-	} else if err != mongo.ErrNoDocuments {
+	// Check if like already exists
+	liked, err := repos.Likes.Exists(c.Request.Context(), user.ID, video.ID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking existing like"})
 		return
 	}
+	if liked {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already liked this video"})
+		return
+	}
 
 	// Like does not exist, create one
 	newLike := models.Like{
 		ID:        uuid.New().String(),
-		Owner:     user,
-		VLike:     video,
+		UserID:    user.ID,
+		VideoID:   video.ID,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	_, err = likeCollection.InsertOne(context.TODO(), newLike)
+	err = repos.Likes.Create(c.Request.Context(), &newLike)
+	if err == repository.ErrDuplicate {
+		// A concurrent request liked it first
+		c.JSON(http.StatusConflict, gin.H{"error": "You already liked this video"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to like video"})
 		return
 	}
 
+	// Keep the counter on the video in step for sorting by likes
+	if err := repos.Videos.AdjustLikes(c.Request.Context(), video.ID, 1); err != nil && err != repository.ErrNotFound {
+		log.Printf("Failed to update like count of video %s: %v", video.ID, err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Video liked successfully"})
 }
 
@@ -95,35 +87,19 @@ func RemoveLike(c *gin.Context) {
 		return
 	}
 
-	likeCollection := db.GetCollection("likes")
-
-	// Check if like exists before attempting to remove
-	filter := bson.M{
-		"owner._id": userID,
-		"vlike._id": videoID,
-	}
-
-	var existingLike models.Like
-	err := likeCollection.FindOne(context.TODO(), filter).Decode(&existingLike)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			c.JSON(http.StatusNotFound, gin.H{"error": "You haven't liked this video"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking like status"})
-		}
+	// Remove the like
+	err := repos.Likes.Delete(c.Request.Context(), userID.(string), videoID)
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You haven't liked this video"})
 		return
 	}
-
-	// Remove the like
-	result, err := likeCollection.DeleteOne(context.TODO(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove like"})
 		return
 	}
 
-	if result.DeletedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Like not found"})
-		return
+	if err := repos.Videos.AdjustLikes(c.Request.Context(), videoID, -1); err != nil && err != repository.ErrNotFound {
+		log.Printf("Failed to update like count of video %s: %v", videoID, err)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Like removed successfully"})
@@ -136,45 +112,12 @@ func CountVideoLikes(c *gin.Context) {
 		return
 	}
 
-	likeCollection := db.GetCollection("likes")
-
-	// Create aggregation pipeline to count likes
-	pipeline := []bson.M{
-		{
-			"$match": bson.M{
-				"vlike._id": videoID,
-			},
-		},
-		{
-			"$group": bson.M{
-				"_id": "$vlike._id",
-				"likeCount": bson.M{
-					"$sum": 1,
-				},
-			},
-		},
-	}
-
-	// Execute aggregation
-	cursor, err := likeCollection.Aggregate(context.TODO(), pipeline)
+	// Count likes for the video
+	count, err := repos.Likes.CountByVideo(c.Request.Context(), videoID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count likes"})
 		return
 	}
-	defer cursor.Close(context.TODO())
-
-	// Get the result
-	var result []bson.M
-	if err = cursor.All(context.TODO(), &result); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process like count"})
-		return
-	}
-
-	// If no likes found, return 0
-	count := 0
-	if len(result) > 0 {
-		count = int(result[0]["likeCount"].(int32))
-	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"videoId":   videoID,
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"yt_backend/models"
+	"yt_backend/repository"
+	"yt_backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// invalidCredentials is the one answer to a failed login, whether the account
+// exists or not, so logins can't be used to find out which accounts exist
+const invalidCredentials = "Invalid username/email or password"
+
+// dummyPasswordHash is compared against when no account matches, so a failed
+// login takes as long whether or not the account exists
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// loginThrottle identifies the failed login counter for an account or IP address
+type loginThrottle struct {
+	key    string
+	policy utils.LoginPolicy
+}
+
+// accountThrottle is the counter for the account with the given ID or, when
+// no account matches a login, for the name it tried; unknown names lock out
+// just like real accounts
+func accountThrottle(userID string, name string) loginThrottle {
+	key := "name:" + name
+	if userID != "" {
+		key = "user:" + userID
+	}
+	return loginThrottle{key: key, policy: utils.AccountLoginPolicy()}
+}
+
+// mfaThrottle is the counter for wrong two-factor codes. It is kept apart from
+// the account's so logging in with a known password doesn't reset it.
+func mfaThrottle(userID string) loginThrottle {
+	return loginThrottle{key: "mfa:" + userID, policy: utils.AccountLoginPolicy()}
+}
+
+func ipThrottle(c *gin.Context) loginThrottle {
+	return loginThrottle{key: "ip:" + c.ClientIP(), policy: utils.IPLoginPolicy()}
+}
+
+// loginLocked answers 429 with Retry-After and reports true if any of the
+// counters is locked out. If the counters can't be read the login goes ahead.
+func loginLocked(c *gin.Context, throttles ...loginThrottle) bool {
+	var retryAfter time.Duration
+	for _, throttle := range throttles {
+		counter, err := repos.LoginThrottles.Find(c.Request.Context(), throttle.key, time.Now())
+		if err != nil {
+			if err != repository.ErrNotFound {
+				log.Printf("Failed to read login throttle %s: %v", throttle.key, err)
+			}
+			continue
+		}
+		retryAfter = max(retryAfter, counter.RetryAfter(time.Now()))
+	}
+	if retryAfter == 0 {
+		return false
+	}
+
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":      "Too many failed login attempts; try again later",
+		"retryAfter": seconds,
+	})
+	return true
+}
+
+// recordLoginFailure counts a failed login against each counter, locking the
+// ones that reach their limit and recording the lockout in the audit log
+func recordLoginFailure(c *gin.Context, userID string, throttles ...loginThrottle) {
+	ctx := c.Request.Context()
+	now := time.Now()
+	for _, throttle := range throttles {
+		counter, err := repos.LoginThrottles.RecordFailure(ctx, throttle.key, now, utils.LoginFailureMemory())
+		if err != nil {
+			log.Printf("Failed to record failed login for %s: %v", throttle.key, err)
+			continue
+		}
+		lockout := throttle.policy.LockoutAfter(counter.Failures)
+		if lockout == 0 {
+			continue
+		}
+		lockedUntil := now.Add(lockout)
+		if err := repos.LoginThrottles.Lock(ctx, throttle.key, lockedUntil); err != nil {
+			log.Printf("Failed to lock logins for %s: %v", throttle.key, err)
+			continue
+		}
+
+		entry := models.AuditLog{
+			ID:         uuid.New().String(),
+			ActorRole:  "system",
+			Action:     models.AuditLoginLocked,
+			TargetType: "login",
+			TargetID:   throttle.key,
+			Details: map[string]string{
+				"ip":          c.ClientIP(),
+				"failures":    strconv.Itoa(counter.Failures),
+				"lockedUntil": lockedUntil.UTC().Format(time.RFC3339),
+			},
+			CreatedAt: now,
+		}
+		if userID != "" && (throttle.key == "user:"+userID || throttle.key == "mfa:"+userID) {
+			entry.TargetType, entry.TargetID = "user", userID
+		}
+		if err := repos.AuditLogs.Record(ctx, &entry); err != nil {
+			log.Printf("Failed to record audit entry %s on %s: %v", entry.Action, throttle.key, err)
+		}
+	}
+}
+
+// clearLoginFailures forgets failed attempts once the user gets in
+func clearLoginFailures(c *gin.Context, throttle loginThrottle) {
+	if err := repos.LoginThrottles.Delete(c.Request.Context(), throttle.key); err != nil {
+		log.Printf("Failed to clear login throttle %s: %v", throttle.key, err)
+	}
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"yt_backend/repository"
+)
+
+// login posts the credentials and returns the status and the Retry-After seconds
+func login(t *testing.T, username string, password string) (int, int) {
+	t.Helper()
+	w, _ := postJSON(t, Login, map[string]string{"username": username, "password": password})
+	retryAfter, _ := strconv.Atoi(w.Header().Get("Retry-After"))
+	return w.Code, retryAfter
+}
+
+// unlock ends a lockout early, as if its time had passed
+func unlock(t *testing.T, key string) {
+	t.Helper()
+	if err := repos.LoginThrottles.Lock(context.Background(), key, time.Now()); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+}
+
+func TestLoginLockoutBacksOff(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "3")
+	t.Setenv("LOGIN_LOCKOUT", "1m")
+	t.Setenv("LOGIN_MAX_LOCKOUT", "10m")
+	r := useMemoryRepositories(t)
+	createPasswordUser(t)
+
+	for i := 0; i < 3; i++ {
+		if status, _ := login(t, "alice", "wrong"); status != http.StatusUnauthorized {
+			t.Fatalf("failure %d = %d, want 401", i+1, status)
+		}
+	}
+	// Locked, even with the right password
+	if status, retryAfter := login(t, "alice", "correct horse"); status != http.StatusTooManyRequests || retryAfter != 60 {
+		t.Fatalf("login while locked = %d, Retry-After %d; want 429 after 60s", status, retryAfter)
+	}
+
+	// Each failure after the lockout ends doubles the next one
+	for _, want := range []int{120, 240} {
+		unlock(t, "user:u1")
+		if status, _ := login(t, "alice", "wrong"); status != http.StatusUnauthorized {
+			t.Fatalf("failure after lockout = %d, want 401", status)
+		}
+		if status, retryAfter := login(t, "alice", "correct horse"); status != http.StatusTooManyRequests || retryAfter != want {
+			t.Fatalf("login while locked = %d, Retry-After %d; want 429 after %ds", status, retryAfter, want)
+		}
+	}
+
+	// Getting in forgets the failures
+	unlock(t, "user:u1")
+	if status, _ := login(t, "alice", "correct horse"); status != http.StatusOK {
+		t.Fatalf("login after lockout = %d, want 200", status)
+	}
+	if _, err := r.LoginThrottles.Find(context.Background(), "user:u1", time.Now()); err != repository.ErrNotFound {
+		t.Errorf("counter after a successful login = %v, want ErrNotFound", err)
+	}
+	if status, _ := login(t, "alice", "wrong"); status != http.StatusUnauthorized {
+		t.Errorf("first failure after success = %d, want 401", status)
+	}
+}
+
+func TestLoginLockoutCoversUnknownAccounts(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "3")
+	useMemoryRepositories(t)
+
+	// Unknown names answer like real accounts, lockout included
+	for i := 0; i < 3; i++ {
+		if status, _ := login(t, "nobody", "wrong"); status != http.StatusUnauthorized {
+			t.Fatalf("failure %d = %d, want 401", i+1, status)
+		}
+	}
+	if status, _ := login(t, "nobody", "wrong"); status != http.StatusTooManyRequests {
+		t.Errorf("login while locked = %d, want 429", status)
+	}
+}
+
+func TestLoginLockoutPerIP(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "3")
+	t.Setenv("LOGIN_MAX_FAILURES_PER_IP", "5")
+	useMemoryRepositories(t)
+	createPasswordUser(t)
+
+	// Spread over many names, failures still add up for the address
+	for i := 0; i < 5; i++ {
+		if status, _ := login(t, "user"+strconv.Itoa(i), "wrong"); status != http.StatusUnauthorized {
+			t.Fatalf("failure %d = %d, want 401", i+1, status)
+		}
+	}
+	if status, _ := login(t, "alice", "correct horse"); status != http.StatusTooManyRequests {
+		t.Errorf("login from a locked address = %d, want 429", status)
+	}
+}
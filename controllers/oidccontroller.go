@@ -0,0 +1,321 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"yt_backend/models"
+	"yt_backend/oidc"
+	"yt_backend/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// oidcLoginTTL is how long a user has to finish signing in at the provider
+const oidcLoginTTL = 10 * time.Minute
+
+// oidcProviders holds the configured "sign in with" providers by name
+var oidcProviders = map[string]*oidc.Provider{}
+
+// SetOIDCProviders injects the configured OpenID providers
+func SetOIDCProviders(providers map[string]*oidc.Provider) {
+	oidcProviders = providers
+}
+
+// ListOIDCProviders names the providers users can sign in with
+func ListOIDCProviders(c *gin.Context) {
+	names := make([]string, 0, len(oidcProviders))
+	for name := range oidcProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	c.JSON(http.StatusOK, gin.H{"providers": names})
+}
+
+// StartOIDCLogin begins signing in with a provider. The web app sends the user
+// to authorizationUrl, keeps state, and once the provider redirects back checks
+// the state it gets matches before posting it with the code to FinishOIDCLogin.
+func StartOIDCLogin(c *gin.Context) {
+	startOIDC(c, "")
+}
+
+// LinkOIDCIdentity begins linking a provider account to the signed-in user; it
+// is finished through FinishOIDCLogin like a login
+func LinkOIDCIdentity(c *gin.Context) {
+	startOIDC(c, c.GetString("user_id"))
+}
+
+func startOIDC(c *gin.Context, linkUserID string) {
+	provider, ok := oidcProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown sign-in provider"})
+		return
+	}
+
+	login := models.OIDCLogin{
+		Provider:   provider.Name,
+		LinkUserID: linkUserID,
+		CreatedAt:  time.Now(),
+		ExpiresAt:  time.Now().Add(oidcLoginTTL),
+	}
+	var err error
+	for _, value := range []*string{&login.ID, &login.Nonce, &login.CodeVerifier} {
+		if *value, err = oidc.RandomString(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+			return
+		}
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), login.ID, login.Nonce, oidc.CodeChallenge(login.CodeVerifier))
+	if err != nil {
+		log.Printf("Failed to start sign-in with %s: %v", provider.Name, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Sign-in provider is unavailable"})
+		return
+	}
+	if err := repos.OIDCLogins.Create(c.Request.Context(), &login); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"authorizationUrl": authURL, "state": login.ID})
+}
+
+// FinishOIDCLogin exchanges the code the provider sent back. A login signs in
+// the linked user, links the provider account to an existing user with the
+// same verified email, or creates a new user; a link attaches it to the user
+// who started the link. Logins then continue as a password login would.
+func FinishOIDCLogin(c *gin.Context) {
+	var request struct {
+		Code  string `json:"code"`
+		State string `json:"state"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Code == "" || request.State == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code and state are required"})
+		return
+	}
+	provider, ok := oidcProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown sign-in provider"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	login, err := repos.OIDCLogins.Consume(ctx, request.State, provider.Name, time.Now())
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sign-in expired or was already finished; please start again"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to finish sign-in"})
+		return
+	}
+
+	claims, err := provider.Exchange(ctx, request.Code, login.CodeVerifier, login.Nonce)
+	if errors.Is(err, oidc.ErrExchangeFailed) || errors.Is(err, oidc.ErrInvalidIDToken) {
+		log.Printf("Sign-in with %s rejected: %v", provider.Name, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign-in with the provider failed"})
+		return
+	}
+	if err != nil {
+		log.Printf("Sign-in with %s failed: %v", provider.Name, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Sign-in provider is unavailable"})
+		return
+	}
+
+	if login.LinkUserID != "" {
+		finishOIDCLink(c, provider.Name, login.LinkUserID, claims)
+		return
+	}
+
+	user, status, message := oidcUser(ctx, provider.Name, claims)
+	if user == nil {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+	if user.Suspended() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended"})
+		return
+	}
+	// The provider is only a replacement for the password
+	signIn(c, user)
+}
+
+// oidcUser finds or creates the user a provider account signs in as. On
+// failure it returns a nil user with the status and message to respond with.
+func oidcUser(ctx context.Context, provider string, claims *oidc.Claims) (*models.User, int, string) {
+	identity, err := repos.Identities.FindByProviderSubject(ctx, provider, claims.Subject)
+	if err == nil {
+		if err := repos.Identities.TouchLogin(ctx, identity.ID, time.Now()); err != nil {
+			log.Printf("Failed to record login of identity %s: %v", identity.ID, err)
+		}
+		user, err := repos.Users.FindByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, http.StatusInternalServerError, "Failed to fetch user"
+		}
+		return user, 0, ""
+	}
+	if err != repository.ErrNotFound {
+		return nil, http.StatusInternalServerError, "Failed to finish sign-in"
+	}
+
+	email, ok := normalizeEmail(claims.Email)
+	if !ok {
+		return nil, http.StatusBadRequest, "The provider did not share a valid email address"
+	}
+
+	user, err := repos.Users.FindByEmail(ctx, email)
+	switch {
+	case err == nil:
+		// Only join accounts when both sides have proven they own the address;
+		// otherwise whoever registered it first could be taken over
+		if !claims.EmailVerified || !user.EmailVerified() {
+			return nil, http.StatusConflict, "An account already uses this email; log in with your password and link the provider from your account"
+		}
+	case err == repository.ErrNotFound:
+		if user, err = createOIDCUser(ctx, email, claims); err != nil {
+			log.Printf("Failed to create user for %s sign-in: %v", provider, err)
+			return nil, http.StatusInternalServerError, "Failed to create user"
+		}
+	default:
+		return nil, http.StatusInternalServerError, "Failed to finish sign-in"
+	}
+
+	err = repos.Identities.Create(ctx, newIdentity(user.ID, provider, claims, email))
+	if err == repository.ErrDuplicate {
+		// Another sign-in with the same provider account won the race; use what it linked
+		return oidcUser(ctx, provider, claims)
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Failed to link identity"
+	}
+	return user, 0, ""
+}
+
+// finishOIDCLink attaches the provider account to the user who started the link
+func finishOIDCLink(c *gin.Context, provider string, userID string, claims *oidc.Claims) {
+	ctx := c.Request.Context()
+	identity := newIdentity(userID, provider, claims, strings.ToLower(claims.Email))
+	err := repos.Identities.Create(ctx, identity)
+	if err == repository.ErrDuplicate {
+		existing, err := repos.Identities.FindByProviderSubject(ctx, provider, claims.Subject)
+		if err == nil && existing.UserID == userID {
+			c.JSON(http.StatusOK, gin.H{"message": "Identity is already linked", "identity": existing})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "This provider account is linked to another user"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link identity"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Identity linked", "identity": identity})
+}
+
+func newIdentity(userID string, provider string, claims *oidc.Claims, email string) *models.Identity {
+	return &models.Identity{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Provider:    provider,
+		Subject:     claims.Subject,
+		Email:       email,
+		CreatedAt:   time.Now(),
+		LastLoginAt: time.Now(),
+	}
+}
+
+var usernameUnsafe = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// createOIDCUser signs up a user from the provider's profile. They have no
+// password; one can be set through the forgot password flow.
+func createOIDCUser(ctx context.Context, email string, claims *oidc.Claims) (*models.User, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	base = strings.Trim(usernameUnsafe.ReplaceAllString(strings.ToLower(base), "_"), "_")
+	if len(base) > 15 {
+		base = base[:15]
+	}
+	for len(base) < 3 {
+		base += "_"
+	}
+
+	now := time.Now()
+	user := models.User{
+		ID:        uuid.New().String(),
+		Email:     email,
+		Avatar:    claims.Picture,
+		Role:      models.RoleUser,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if claims.EmailVerified {
+		user.EmailVerifiedAt = &now
+	}
+
+	// The email was free a moment ago, so a duplicate means the username is taken
+	for attempt := 0; attempt < 5; attempt++ {
+		user.Username = base
+		if attempt > 0 {
+			user.Username = fmt.Sprintf("%s_%04d", base, rand.IntN(10000))
+		}
+		err := repos.Users.Create(ctx, &user)
+		if err != repository.ErrDuplicate {
+			return &user, err
+		}
+	}
+	return nil, errors.New("no free username found")
+}
+
+// ListIdentities returns the provider accounts linked to the signed-in user
+func ListIdentities(c *gin.Context) {
+	identities, err := repos.Identities.ListByUser(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch identities"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+// UnlinkIdentity removes a linked provider account, unless it is the only way
+// left to sign in
+func UnlinkIdentity(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+
+	user, err := repos.Users.FindByID(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	identities, err := repos.Identities.ListByUser(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch identities"})
+		return
+	}
+	if user.Password == "" && len(identities) <= 1 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Set a password before unlinking your only sign-in provider"})
+		return
+	}
+
+	err = repos.Identities.Delete(ctx, c.Param("identityId"), userID)
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink identity"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Identity unlinked"})
+}
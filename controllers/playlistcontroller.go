@@ -1,16 +1,12 @@
 package controllers
 
 import (
-	"context"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
-	"go.mongodb.org/mongo-driver/bson"
-
-	"yt_backend/db"
 	"yt_backend/models"
 )
 
@@ -50,8 +46,7 @@ func CreatePlaylist(c *gin.Context) {
 	}
 
 	// Insert into database
-	collection := db.GetCollection("playlists")
-	_, err := collection.InsertOne(context.Background(), playlist)
+	err := repos.Playlists.Create(c.Request.Context(), playlist)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create playlist"})
 		return
@@ -77,30 +72,31 @@ func AddToPlaylist(c *gin.Context) {
 	}
 
 	// Get playlist from database
-	collection := db.GetCollection("playlists")
-	var playlist models.Playlist
-	if err := collection.FindOne(context.Background(), bson.M{"_id": playlistID}).Decode(&playlist); err != nil {
+	playlist, err := repos.Playlists.FindByID(c.Request.Context(), playlistID)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
 		return
 	}
 
+	// Check if user owns the playlist
+	if playlist.UserID != c.GetString("user_id") {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authorized to modify playlist"})
+		return
+	}
+
+	// Only videos the user can watch may be added
+	if _, ok := findViewableVideo(c, input.VideoID); !ok {
+		return
+	}
+
 	// Check if video already exists
 	if playlist.HasVideo(input.VideoID) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Video already exists in playlist"})
 		return
 	}
 
-	// Update playlist in database using $push operator
-	update := bson.M{
-		"$push": bson.M{
-			"videoIds": input.VideoID,
-		},
-		"$set": bson.M{
-			"updatedAt": time.Now(),
-		},
-	}
-
-	_, err := collection.UpdateOne(context.Background(), bson.M{"_id": playlistID}, update)
+	// Update playlist in database
+	err = repos.Playlists.AddVideo(c.Request.Context(), playlistID, input.VideoID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add video to playlist"})
 		return
@@ -124,9 +120,8 @@ func DeletePlaylist(c *gin.Context) {
 	}
 
 	// Get playlist from database
-	collection := db.GetCollection("playlists")
-	var playlist models.Playlist
-	if err := collection.FindOne(context.Background(), bson.M{"_id": playlistID}).Decode(&playlist); err != nil {
+	playlist, err := repos.Playlists.FindByID(c.Request.Context(), playlistID)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
 		return
 	}
@@ -138,7 +133,7 @@ func DeletePlaylist(c *gin.Context) {
 	}
 
 	// Delete playlist from database
-	_, err := collection.DeleteOne(context.Background(), bson.M{"_id": playlistID})
+	err = repos.Playlists.Delete(c.Request.Context(), playlistID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete playlist"})
 		return
@@ -164,30 +159,26 @@ func RemoveFromPlaylist(c *gin.Context) {
 	}
 
 	// Get playlist from database
-	collection := db.GetCollection("playlists")
-	var playlist models.Playlist
-	if err := collection.FindOne(context.Background(), bson.M{"_id": playlistID}).Decode(&playlist); err != nil {
+	playlist, err := repos.Playlists.FindByID(c.Request.Context(), playlistID)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
 		return
 	}
 
+	// Check if user owns the playlist
+	if playlist.UserID != c.GetString("user_id") {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authorized to modify playlist"})
+		return
+	}
+
 	// Check if video exists
 	if !playlist.HasVideo(input.VideoID) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Video not found in playlist"})
 		return
 	}
 
-	// Update playlist in database using $pull operator
-	update := bson.M{
-		"$pull": bson.M{
-			"videoIds": input.VideoID,
-		},
-		"$set": bson.M{
-			"updatedAt": time.Now(),
-		},
-	}
-
-	_, err := collection.UpdateOne(context.Background(), bson.M{"_id": playlistID}, update)
+	// Update playlist in database
+	err = repos.Playlists.RemoveVideo(c.Request.Context(), playlistID, input.VideoID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove video from playlist"})
 		return
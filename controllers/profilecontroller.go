@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"yt_backend/models"
+	"yt_backend/repository"
+	"yt_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits on what users can put on their profile
+const (
+	maxBioLength        = 500
+	maxProfileLinks     = 5
+	maxLinkTitleLength  = 50
+	maxLinkURLLength    = 2048
+	maxProfileImageSize = 5 << 20 // 5 MB
+)
+
+// usernamePattern is what a username may look like, both at sign-up and when it is changed
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]{3,20}$`)
+
+const invalidUsernameMessage = "Username must be 3 to 20 letters, digits or underscores"
+
+// GetProfile returns the public profile of any user
+func GetProfile(c *gin.Context) {
+	user, err := repos.Users.FindByID(c.Request.Context(), c.Param("userId"))
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"profile": user.Profile()})
+}
+
+// GetMe returns the signed-in user's own account, including their email and role
+func GetMe(c *gin.Context) {
+	user, err := repos.Users.FindByID(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+// UpdateProfile changes the signed-in user's username, bio or links; fields
+// left out of the request are kept
+func UpdateProfile(c *gin.Context) {
+	var request struct {
+		Username *string               `json:"username"`
+		Bio      *string               `json:"bio"`
+		Links    *[]models.ProfileLink `json:"links"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+	if request.Username == nil && request.Bio == nil && request.Links == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	update := repository.ProfileUpdate{Username: request.Username}
+	if request.Username != nil && !usernamePattern.MatchString(*request.Username) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidUsernameMessage})
+		return
+	}
+	if request.Bio != nil {
+		bio := strings.TrimSpace(*request.Bio)
+		if utf8.RuneCountInString(bio) > maxBioLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bio must be 500 characters or fewer"})
+			return
+		}
+		update.Bio = &bio
+	}
+	if request.Links != nil {
+		links, message := normalizeProfileLinks(*request.Links)
+		if message != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return
+		}
+		update.Links = &links
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	err := repos.Users.UpdateProfile(ctx, userID, update)
+	if err == repository.ErrDuplicate {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+		return
+	}
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		return
+	}
+
+	user, err := repos.Users.FindByID(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated user"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile updated successfully",
+		"user":    user,
+	})
+}
+
+// normalizeProfileLinks trims the links and checks they are absolute http(s)
+// URLs, returning an error message if any is not
+func normalizeProfileLinks(links []models.ProfileLink) ([]models.ProfileLink, string) {
+	if len(links) > maxProfileLinks {
+		return nil, "A profile can have at most 5 links"
+	}
+	normalized := make([]models.ProfileLink, 0, len(links))
+	for _, link := range links {
+		link.Title = strings.TrimSpace(link.Title)
+		link.URL = strings.TrimSpace(link.URL)
+		if utf8.RuneCountInString(link.Title) > maxLinkTitleLength {
+			return nil, "Link titles must be 50 characters or fewer"
+		}
+		parsed, err := url.Parse(link.URL)
+		if err != nil || len(link.URL) > maxLinkURLLength || parsed.Host == "" ||
+			(parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return nil, "Links must be http or https URLs"
+		}
+		if link.Title == "" {
+			link.Title = parsed.Host
+		}
+		normalized = append(normalized, link)
+	}
+	return normalized, ""
+}
+
+// profileImage describes one of the images on a user's profile
+type profileImage struct {
+	field  string // multipart form field
+	folder string // media store folder
+	name   string // for messages
+	get    func(user *models.User) string
+	set    func(ctx context.Context, userID string, imageURL string) error
+}
+
+var (
+	avatarImage = profileImage{
+		field:  "avatar",
+		folder: "avatar",
+		name:   "Avatar",
+		get:    func(user *models.User) string { return user.Avatar },
+		set: func(ctx context.Context, userID string, imageURL string) error {
+			return repos.Users.SetAvatar(ctx, userID, imageURL)
+		},
+	}
+	coverImage = profileImage{
+		field:  "coverImage",
+		folder: "cover",
+		name:   "Cover image",
+		get:    func(user *models.User) string { return user.CoverImage },
+		set: func(ctx context.Context, userID string, imageURL string) error {
+			return repos.Users.SetCoverImage(ctx, userID, imageURL)
+		},
+	}
+)
+
+// UpdateAvatar replaces the signed-in user's avatar
+func UpdateAvatar(c *gin.Context) { replaceProfileImage(c, avatarImage) }
+
+// DeleteAvatar removes the signed-in user's avatar
+func DeleteAvatar(c *gin.Context) { removeProfileImage(c, avatarImage) }
+
+// UpdateCoverImage replaces the signed-in user's cover image
+func UpdateCoverImage(c *gin.Context) { replaceProfileImage(c, coverImage) }
+
+// DeleteCoverImage removes the signed-in user's cover image
+func DeleteCoverImage(c *gin.Context) { removeProfileImage(c, coverImage) }
+
+// replaceProfileImage uploads the new image, points the user at it and only
+// then deletes the old one, so a failure never leaves the profile without one
+func replaceProfileImage(c *gin.Context, image profileImage) {
+	ctx := c.Request.Context()
+	user, err := repos.Users.FindByID(ctx, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	file, err := c.FormFile(image.field)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": image.name + " file is required"})
+		return
+	}
+	if file.Size > maxProfileImageSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": image.name + " must be 5 MB or smaller"})
+		return
+	}
+	if !isImageFile(file) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": image.name + " must be a JPEG, PNG or WebP image"})
+		return
+	}
+
+	imageURL, err := utils.HandleImageUpload(ctx, file, image.folder)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload " + strings.ToLower(image.name)})
+		return
+	}
+	if err := image.set(ctx, user.ID, imageURL); err != nil {
+		utils.DeleteMedia(ctx, imageURL)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update " + strings.ToLower(image.name)})
+		return
+	}
+	deleteProfileImage(ctx, image.get(user), imageURL)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   image.name + " updated successfully",
+		image.field: imageURL,
+	})
+}
+
+// removeProfileImage clears the image from the user's profile and deletes it
+func removeProfileImage(c *gin.Context, image profileImage) {
+	ctx := c.Request.Context()
+	user, err := repos.Users.FindByID(ctx, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if image.get(user) == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": image.name + " not set"})
+		return
+	}
+
+	if err := image.set(ctx, user.ID, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove " + strings.ToLower(image.name)})
+		return
+	}
+	deleteProfileImage(ctx, image.get(user), "")
+
+	c.JSON(http.StatusOK, gin.H{"message": image.name + " removed successfully"})
+}
+
+// deleteProfileImage deletes an image the profile no longer references. The
+// media store refuses URLs it didn't issue, like a sign-in provider's avatar,
+// so those are left alone.
+func deleteProfileImage(ctx context.Context, oldURL string, newURL string) {
+	if oldURL == "" || oldURL == newURL {
+		return
+	}
+	if err := utils.DeleteMedia(ctx, oldURL); err != nil && err != utils.ErrMediaNotFound {
+		log.Printf("Failed to delete old profile image %s: %v", oldURL, err)
+	}
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"yt_backend/models"
+	"yt_backend/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = 50
+	maxSearchQueryLength  = 200
+	maxSuggestions        = 10
+)
+
+// durationBuckets maps the duration filter to a [min, max) range in seconds
+var durationBuckets = map[string][2]float64{
+	"short":  {0, 4 * 60},
+	"medium": {4 * 60, 20 * 60},
+	"long":   {20 * 60, 0},
+}
+
+// uploadedWithin maps the upload date filter to how far back to look
+var uploadedWithin = map[string]time.Duration{
+	"hour":  time.Hour,
+	"today": 24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"year":  365 * 24 * time.Hour,
+}
+
+// SearchVideos finds public videos by title, description, tags and channel name
+func SearchVideos(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+	if len(text) > maxSearchQueryLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is too long"})
+		return
+	}
+
+	query := search.Query{
+		Text:      text,
+		ChannelID: c.Query("channel"),
+		Now:       time.Now(),
+		Limit:     defaultSearchPageSize,
+	}
+
+	if value := c.Query("duration"); value != "" {
+		bucket, ok := durationBuckets[value]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duration must be short, medium or long"})
+			return
+		}
+		query.MinDuration, query.MaxDuration = bucket[0], bucket[1]
+	}
+
+	if value := c.Query("uploaded"); value != "" {
+		within, ok := uploadedWithin[value]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "uploaded must be hour, today, week, month or year"})
+			return
+		}
+		after := query.Now.Add(-within)
+		query.UploadedAfter = &after
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 1 || limit > maxSearchPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 50"})
+			return
+		}
+		query.Limit = limit
+	}
+	page := int64(1)
+	if value := c.Query("page"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive number"})
+			return
+		}
+		page = parsed
+	}
+	query.Offset = (page - 1) * query.Limit
+
+	result, err := searchIndex.Search(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search videos"})
+		return
+	}
+
+	// Load the videos and keep the ranking order
+	ids := make([]string, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.ID)
+	}
+	found, err := repos.Videos.FindByIDs(c.Request.Context(), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch videos"})
+		return
+	}
+	byID := make(map[string]models.Video, len(found))
+	for _, video := range found {
+		byID[video.ID] = video
+	}
+
+	// The index can briefly lag behind edits, so re-check visibility on the way out
+	viewerID := c.GetString("user_id")
+	videos := make([]models.Video, 0, len(ids))
+	for _, id := range ids {
+		if video, ok := byID[id]; ok && video.VisibleTo(viewerID, query.Now) {
+			videos = append(videos, video)
+		}
+	}
+
+	response := gin.H{
+		"query":  text,
+		"total":  result.Total,
+		"page":   page,
+		"videos": videos,
+	}
+	if result.CorrectedQuery != "" {
+		response["correctedQuery"] = result.CorrectedQuery
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// SearchSuggestions completes a partially typed search query
+func SearchSuggestions(c *gin.Context) {
+	prefix := c.Query("q")
+	if len(prefix) > maxSearchQueryLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is too long"})
+		return
+	}
+
+	suggestions, err := searchIndex.Suggest(c.Request.Context(), prefix, maxSuggestions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch suggestions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
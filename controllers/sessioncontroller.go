@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"yt_backend/middleware"
+	"yt_backend/models"
+	"yt_backend/repository"
+	"yt_backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// startSession records a new signed-in device and returns its tokens;
+// mfaVerifiedAt is set when the login included the second factor
+func startSession(c *gin.Context, userID string, mfaVerifiedAt *time.Time) (gin.H, error) {
+	now := time.Now()
+	session := models.Session{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(utils.RefreshTokenTTL()),
+
+		MFAVerifiedAt: mfaVerifiedAt,
+	}
+
+	refreshToken, secretHash, err := utils.NewRefreshToken(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	session.SecretHash = secretHash
+
+	if err := repos.Sessions.Create(c.Request.Context(), &session); err != nil {
+		return nil, err
+	}
+	return sessionTokens(c, &session, refreshToken)
+}
+
+// sessionTokens issues an access token for the session alongside its refresh token
+func sessionTokens(c *gin.Context, session *models.Session, refreshToken string) (gin.H, error) {
+	token, err := utils.GenerateToken(session.UserID, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"token":        token,
+		"expiresIn":    int(utils.AccessTokenTTL().Seconds()),
+		"refreshToken": refreshToken,
+		"sessionId":    session.ID,
+	}, nil
+}
+
+// RefreshToken trades a refresh token for a new access token and a new refresh
+// token. Each refresh token works once; presenting one that was already used
+// means it was copied, so the whole session is revoked.
+func RefreshToken(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := c.BindJSON(&input); err != nil || input.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token is required"})
+		return
+	}
+
+	sessionID, secret, ok := utils.ParseRefreshToken(input.RefreshToken)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := repos.Sessions.FindByID(ctx, sessionID)
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load session"})
+		return
+	}
+
+	now := time.Now()
+	if !session.Active(now) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has expired or been revoked"})
+		return
+	}
+
+	secretHash := utils.HashRefreshSecret(secret)
+	if secretHash != session.SecretHash {
+		if slices.Contains(session.PreviousHashes, secretHash) {
+			revokeReusedSession(c, session)
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	refreshToken, newHash, err := utils.NewRefreshToken(session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	session, err = repos.Sessions.Rotate(ctx, session.ID, secretHash, newHash, now.Add(utils.RefreshTokenTTL()))
+	if err == repository.ErrNotFound {
+		// Another request rotated the same token first, which is reuse as well
+		revokeReusedSession(c, &models.Session{ID: sessionID})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
+	tokens, err := sessionTokens(c, session, refreshToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// revokeReusedSession ends a session whose refresh token was presented twice
+func revokeReusedSession(c *gin.Context, session *models.Session) {
+	if err := repos.Sessions.Revoke(c.Request.Context(), session.ID, "refresh token reused"); err != nil && err != repository.ErrNotFound {
+		log.Printf("Failed to revoke session %s after token reuse: %v", session.ID, err)
+	}
+	middleware.NoteRevokedSession(session.ID)
+	log.Printf("Refresh token reuse detected, revoked session %s", session.ID)
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token was already used; the session has been revoked"})
+}
+
+// ListSessions lists the devices the user is signed in on
+func ListSessions(c *gin.Context) {
+	userID := c.GetString("user_id")
+	sessions, err := repos.Sessions.ListActiveByUser(c.Request.Context(), userID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	current := c.GetString("session_id")
+	result := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, gin.H{
+			"id":         session.ID,
+			"userAgent":  session.UserAgent,
+			"ip":         session.IP,
+			"createdAt":  session.CreatedAt,
+			"lastUsedAt": session.LastUsedAt,
+			"expiresAt":  session.ExpiresAt,
+			"current":    session.ID == current,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": result})
+}
+
+// RevokeSession signs the user out on one device
+func RevokeSession(c *gin.Context) {
+	userID := c.GetString("user_id")
+	session, err := repos.Sessions.FindByID(c.Request.Context(), c.Param("sessionId"))
+	if err == repository.ErrNotFound || (err == nil && session.UserID != userID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load session"})
+		return
+	}
+
+	err = repos.Sessions.Revoke(c.Request.Context(), session.ID, "revoked by user")
+	if err != nil && err != repository.ErrNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	middleware.NoteRevokedSession(session.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// RevokeOtherSessions signs the user out everywhere except the current device
+func RevokeOtherSessions(c *gin.Context) {
+	userID := c.GetString("user_id")
+	revoked, err := revokeUserSessions(c.Request.Context(), userID, c.GetString("session_id"), "revoked by user")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Other sessions revoked", "revoked": revoked})
+}
+
+// revokeUserSessions revokes every session of the user except exceptID and drops
+// them from this process's revocation cache right away
+func revokeUserSessions(ctx context.Context, userID string, exceptID string, reason string) (int64, error) {
+	sessions, err := repos.Sessions.ListActiveByUser(ctx, userID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	revoked, err := repos.Sessions.RevokeAllForUser(ctx, userID, exceptID, reason)
+	if err != nil {
+		return 0, err
+	}
+	for _, session := range sessions {
+		if session.ID != exceptID {
+			middleware.NoteRevokedSession(session.ID)
+		}
+	}
+	return revoked, nil
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"yt_backend/utils"
+)
+
+// refresh trades the refresh token and returns the status and the new refresh token
+func refresh(t *testing.T, refreshToken string) (int, string) {
+	t.Helper()
+	w, response := postJSON(t, RefreshToken, map[string]string{"refreshToken": refreshToken})
+	next, _ := response["refreshToken"].(string)
+	return w.Code, next
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	useMemoryRepositories(t)
+	c, _ := newTestContext()
+	tokens, err := startSession(c, "u1", nil)
+	if err != nil {
+		t.Fatalf("startSession: %v", err)
+	}
+	sessionID := tokens["sessionId"].(string)
+	current := tokens["refreshToken"].(string)
+
+	for i := 0; i < 3; i++ {
+		w, response := postJSON(t, RefreshToken, map[string]string{"refreshToken": current})
+		if w.Code != http.StatusOK {
+			t.Fatalf("refresh %d = %d %v", i, w.Code, response)
+		}
+		next := response["refreshToken"].(string)
+		if next == current || !strings.HasPrefix(next, sessionID+".") {
+			t.Fatalf("refresh %d returned %q, want a new token for the same session", i, next)
+		}
+		claims, err := utils.VerifyToken(response["token"].(string))
+		if err != nil || claims.UserID != "u1" || claims.SessionID != sessionID {
+			t.Fatalf("access token = %+v, %v; want u1 in session %s", claims, err, sessionID)
+		}
+		current = next
+	}
+}
+
+func TestRefreshTokenReuseRevokesTheSession(t *testing.T) {
+	r := useMemoryRepositories(t)
+	c, _ := newTestContext()
+	tokens, err := startSession(c, "u1", nil)
+	if err != nil {
+		t.Fatalf("startSession: %v", err)
+	}
+	sessionID := tokens["sessionId"].(string)
+	stolen := tokens["refreshToken"].(string)
+
+	status, current := refresh(t, stolen)
+	if status != http.StatusOK {
+		t.Fatalf("first refresh = %d", status)
+	}
+
+	// Presenting the rotated token again means someone else holds a copy
+	if status, _ := refresh(t, stolen); status != http.StatusUnauthorized {
+		t.Fatalf("reused refresh token = %d, want 401", status)
+	}
+	session, err := r.Sessions.FindByID(context.Background(), sessionID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if session.RevokedAt == nil || session.RevokedReason != "refresh token reused" {
+		t.Fatalf("session revoked at %v for %q, want revoked for reuse", session.RevokedAt, session.RevokedReason)
+	}
+
+	// and the legitimate holder is signed out too
+	if status, _ := refresh(t, current); status != http.StatusUnauthorized {
+		t.Errorf("refreshing a revoked session = %d, want 401", status)
+	}
+}
+
+func TestRefreshTokenRejectsUnknownSecrets(t *testing.T) {
+	r := useMemoryRepositories(t)
+	c, _ := newTestContext()
+	tokens, err := startSession(c, "u1", nil)
+	if err != nil {
+		t.Fatalf("startSession: %v", err)
+	}
+	sessionID := tokens["sessionId"].(string)
+
+	for _, token := range []string{"garbage", sessionID + ".not-a-secret-we-issued", "missing.secret"} {
+		if status, _ := refresh(t, token); status != http.StatusUnauthorized {
+			t.Errorf("refresh(%q) = %d, want 401", token, status)
+		}
+	}
+
+	// A guess is not reuse, so the session survives it
+	session, _ := r.Sessions.FindByID(context.Background(), sessionID)
+	if session.RevokedAt != nil {
+		t.Errorf("session revoked after a wrong secret")
+	}
+	if status, _ := refresh(t, tokens["refreshToken"].(string)); status != http.StatusOK {
+		t.Errorf("refresh after wrong guesses = %d, want 200", status)
+	}
+}
@@ -1,114 +1,180 @@
 package controllers
 
 import (
-	"context"
 	"net/http"
 	"time"
-	"yt_backend/db"
 	"yt_backend/models"
+	"yt_backend/repository"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // i have to do subscriber already exist or not
 func Subscribe(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	videoID := c.Param("videoId")
+	if videoID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Video ID is required"})
 		return
 	}
-	userCollection := db.GetCollection("users")
-	var user models.User
-	err := userCollection.FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+
+	video, ok := findViewableVideo(c, videoID)
+	if !ok {
+		return
+	}
+	channel, ok := findVideoChannel(c, video)
+	if !ok {
 		return
 	}
+	subscribe(c, channel.ID)
+}
 
+func Unsubscribe(c *gin.Context) {
 	videoID := c.Param("videoId")
 	if videoID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Video ID is required"})
 		return
 	}
 
-	videoCollection := db.GetCollection("videos")
-
-	var video models.Video
-	err = videoCollection.FindOne(context.TODO(), bson.M{"_id": videoID}).Decode(&video)
+	video, err := repos.Videos.FindByID(c.Request.Context(), videoID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
 		return
 	}
+	channel, ok := findVideoChannel(c, video)
+	if !ok {
+		return
+	}
+	unsubscribe(c, channel.ID)
+}
+
+// findVideoChannel loads the channel a video was uploaded to, answering 404
+// if there is none, as for videos uploaded before their owner made a channel
+func findVideoChannel(c *gin.Context, video *models.Video) (*models.Channel, bool) {
+	if video.ChannelID == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video does not belong to a channel"})
+		return nil, false
+	}
+	return findChannel(c, video.ChannelID)
+}
 
-	subscriptionCollection := db.GetCollection("subscriptions")
+// findChannel loads a channel, writing the error response if it can't
+func findChannel(c *gin.Context, channelID string) (*models.Channel, bool) {
+	channel, err := repos.Channels.FindByID(c.Request.Context(), channelID)
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch channel"})
+		return nil, false
+	}
+	return channel, true
+}
 
-	// Check if subscription already exists
-	filter := bson.M{
-		"subscribers._id": userID,
-		"channelName._id": video.ChannelName.ID,
+// SubscribeToChannel subscribes the signed-in user to the channel in the URL
+func SubscribeToChannel(c *gin.Context) {
+	channel, ok := findChannel(c, c.Param("channelId"))
+	if !ok {
+		return
 	}
+	subscribe(c, channel.ID)
+}
 
-	var existingSubscription models.Subscription
-	err = subscriptionCollection.FindOne(context.TODO(), filter).Decode(&existingSubscription)
-	if err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "You are already subscribed to this channel"})
+// UnsubscribeFromChannel ends the signed-in user's subscription to the channel in the URL
+func UnsubscribeFromChannel(c *gin.Context) {
+	channel, ok := findChannel(c, c.Param("channelId"))
+	if !ok {
+		return
+	}
+	unsubscribe(c, channel.ID)
+}
+
+func subscribe(c *gin.Context, channelID string) {
+	user, err := repos.Users.FindByID(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
-	} else if err != mongo.ErrNoDocuments {
+	}
+
+	// Check if subscription already exists
+	subscribed, err := repos.Subscriptions.Exists(c.Request.Context(), user.ID, channelID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking existing subscription"})
 		return
 	}
+	if subscribed {
+		c.JSON(http.StatusConflict, gin.H{"error": "You are already subscribed to this channel"})
+		return
+	}
 
 	subscription := models.Subscription{
-		ID:          uuid.New().String(),
-		ChannelName: video.ChannelName,
-		Subscribers: user,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ID:           uuid.New().String(),
+		ChannelID:    channelID,
+		SubscriberID: user.ID,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
 	}
 
-	_, err = subscriptionCollection.InsertOne(context.TODO(), subscription)
+	err = repos.Subscriptions.Create(c.Request.Context(), &subscription)
+	if err == repository.ErrDuplicate {
+		// A concurrent request subscribed first
+		c.JSON(http.StatusConflict, gin.H{"error": "You are already subscribed to this channel"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save subscription"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Subscribed successfully"})
+	respondWithSubscriberCount(c, channelID, "Subscribed successfully")
 }
 
-func Unsubscribe(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+func unsubscribe(c *gin.Context, channelID string) {
+	err := repos.Subscriptions.Delete(c.Request.Context(), c.GetString("user_id"), channelID)
+	if err != nil && err != repository.ErrNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
 		return
 	}
 
-	videoID := c.Param("videoId")
-	if videoID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Video ID is required"})
-		return
-	}
+	respondWithSubscriberCount(c, channelID, "Unsubscribed successfully")
+}
 
-	videoCollection := db.GetCollection("videos")
+// respondWithSubscriberCount reports the channel's subscriber count after a
+// change; the change itself already succeeded, so a failed count is left out
+func respondWithSubscriberCount(c *gin.Context, channelID string, message string) {
+	response := gin.H{"message": message, "channelId": channelID}
+	if count, err := repos.Subscriptions.CountByChannel(c.Request.Context(), channelID); err == nil {
+		response["subscriberCount"] = count
+	}
+	c.JSON(http.StatusOK, response)
+}
 
-	var video models.Video
-	err := videoCollection.FindOne(context.TODO(), bson.M{"_id": videoID}).Decode(&video)
+// SubscriptionFeed lists the newest videos from the channels the signed-in
+// user is subscribed to, a page at a time
+func SubscriptionFeed(c *gin.Context) {
+	query, err := videoQueryFromRequest(c)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	subscriptionCollection := db.GetCollection("subscriptions")
-
-	_, err = subscriptionCollection.DeleteOne(context.TODO(), bson.M{"subscribers._id": userID, "channelName._id": video.ChannelName.ID})
+	channelIDs, err := repos.Subscriptions.ListChannelIDs(c.Request.Context(), c.GetString("user_id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subscriptions"})
+		return
+	}
+	// An empty channel list would match every video
+	if len(channelIDs) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"videos":     []models.Video{},
+			"nextCursor": "",
+		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Unsubscribed successfully"})
+	query.ChannelIDs = channelIDs
+	listVideos(c, query)
 }
 
 func CountSubscribers(c *gin.Context) {
@@ -118,45 +184,12 @@ func CountSubscribers(c *gin.Context) {
 		return
 	}
 
-	subscriptionCollection := db.GetCollection("subscriptions")
-
-	// Create aggregation pipeline
-	pipeline := []bson.M{
-		{
-			"$match": bson.M{
-				"channelName._id": channelID,
-			},
-		},
-		{
-			"$group": bson.M{
-				"_id": "$channelName._id",
-				"subscriberCount": bson.M{
-					"$sum": 1,
-				},
-			},
-		},
-	}
-
-	// Execute aggregation
-	cursor, err := subscriptionCollection.Aggregate(context.TODO(), pipeline)
+	// Count subscriptions to the channel
+	count, err := repos.Subscriptions.CountByChannel(c.Request.Context(), channelID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count subscribers"})
 		return
 	}
-	defer cursor.Close(context.TODO())
-
-	// Get the result
-	var result []bson.M
-	if err = cursor.All(context.TODO(), &result); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process subscriber count"})
-		return
-	}
-
-	// If no subscribers found, return 0
-	count := 0
-	if len(result) > 0 {
-		count = int(result[0]["subscriberCount"].(int32))
-	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"channelId":       channelID,
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"yt_backend/models"
+	"yt_backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// brokenChannels fails every channel lookup, as an unreachable database would
+type brokenChannels struct {
+	repository.ChannelRepo
+}
+
+func (brokenChannels) FindByID(ctx context.Context, id string) (*models.Channel, error) {
+	return nil, errors.New("connection refused")
+}
+
+// callWithParam calls the handler as u1 with one URL parameter
+func callWithParam(handler gin.HandlerFunc, key string, value string) int {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	c.Params = gin.Params{{Key: key, Value: value}}
+	c.Set("user_id", "u1")
+	handler(c)
+	return w.Code
+}
+
+func TestSubscribeToMissingChannels(t *testing.T) {
+	r := useMemoryRepositories(t)
+	createPasswordUser(t)
+	video := &models.Video{ID: "v1", Title: "Old upload", Visibility: models.VisibilityPublic, Status: models.VideoStatusReady}
+	if err := r.Videos.Create(context.Background(), video); err != nil {
+		t.Fatalf("Videos.Create: %v", err)
+	}
+
+	for name, handler := range map[string]gin.HandlerFunc{"Subscribe": Subscribe, "Unsubscribe": Unsubscribe} {
+		if status := callWithParam(handler, "videoId", "v1"); status != http.StatusNotFound {
+			t.Errorf("%s to a video without a channel = %d, want 404", name, status)
+		}
+	}
+	for name, handler := range map[string]gin.HandlerFunc{"SubscribeToChannel": SubscribeToChannel, "UnsubscribeFromChannel": UnsubscribeFromChannel} {
+		if status := callWithParam(handler, "channelId", "missing"); status != http.StatusNotFound {
+			t.Errorf("%s to a missing channel = %d, want 404", name, status)
+		}
+	}
+
+	// A failing database is not a missing channel
+	r.Channels = brokenChannels{r.Channels}
+	for name, handler := range map[string]gin.HandlerFunc{"SubscribeToChannel": SubscribeToChannel, "UnsubscribeFromChannel": UnsubscribeFromChannel} {
+		if status := callWithParam(handler, "channelId", "c1"); status != http.StatusInternalServerError {
+			t.Errorf("%s with a failing database = %d, want 500", name, status)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"yt_backend/models"
+	"yt_backend/repository"
+	"yt_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxMFAFailures is how many wrong codes a login challenge takes before it is discarded
+const maxMFAFailures = 5
+
+// signIn finishes a login once the user has proven who they are. Users with
+// two-factor enabled get a challenge token to send back with a code to
+// CompleteMFALogin; everyone else gets a session right away.
+func signIn(c *gin.Context, user *models.User) {
+	ctx := c.Request.Context()
+	twoFactor, err := repos.TwoFactor.FindByUser(ctx, user.ID)
+	if err != nil && err != repository.ErrNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+	if err == repository.ErrNotFound || !twoFactor.Enabled() {
+		respondWithSession(c, user, nil)
+		return
+	}
+
+	challenge, hash, err := utils.NewOneTimeToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+	ttl := utils.MFAChallengeTTL()
+	err = repos.UserTokens.Create(ctx, &models.UserToken{
+		ID:        hash,
+		UserID:    user.ID,
+		Purpose:   models.TokenPurposeMFAChallenge,
+		ExpiresAt: time.Now().Add(ttl),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Enter the code from your authenticator app to finish logging in",
+		"mfaRequired":    true,
+		"challengeToken": challenge,
+		"expiresIn":      int(ttl.Seconds()),
+	})
+}
+
+// respondWithSession starts a session for the user and returns its tokens
+func respondWithSession(c *gin.Context, user *models.User, mfaVerifiedAt *time.Time) {
+	tokens, err := startSession(c, user.ID, mfaVerifiedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	tokens["message"] = "Login successful"
+	tokens["user"] = user
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CompleteMFALogin finishes a login with the challenge token from the first
+// step and a code from the user's authenticator app or a recovery code
+func CompleteMFALogin(c *gin.Context) {
+	var request struct {
+		ChallengeToken string `json:"challengeToken"`
+		Code           string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.ChallengeToken == "" || request.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Challenge token and code are required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	hash := utils.HashOneTimeToken(request.ChallengeToken)
+	challenge, err := repos.UserTokens.Find(ctx, hash, models.TokenPurposeMFAChallenge, time.Now())
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired; please log in again"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+
+	mfa, ip := mfaThrottle(challenge.UserID), ipThrottle(c)
+	if loginLocked(c, mfa, ip) {
+		return
+	}
+
+	twoFactor, err := repos.TwoFactor.FindByUser(ctx, challenge.UserID)
+	if err == repository.ErrNotFound || (err == nil && !twoFactor.Enabled()) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired; please log in again"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+
+	ok, err := checkSecondFactor(ctx, twoFactor, request.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+	if !ok {
+		recordLoginFailure(c, challenge.UserID, mfa, ip)
+		if err := repos.UserTokens.RecordFailure(ctx, hash, maxMFAFailures); err != nil && err != repository.ErrNotFound {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+	clearLoginFailures(c, mfa)
+
+	// Consuming fails if the challenge ran out of attempts or was used meanwhile
+	if _, err := repos.UserTokens.Consume(ctx, hash, models.TokenPurposeMFAChallenge, time.Now()); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired; please log in again"})
+		return
+	}
+
+	user, err := repos.Users.FindByID(ctx, challenge.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+	if user.Suspended() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended"})
+		return
+	}
+
+	now := time.Now()
+	respondWithSession(c, user, &now)
+}
+
+// checkSecondFactor accepts a current authenticator code or an unused recovery
+// code. Either works only once.
+func checkSecondFactor(ctx context.Context, twoFactor *models.TwoFactor, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	var err error
+	if step, ok := utils.ValidateTOTP(twoFactor.Secret, code, time.Now()); ok {
+		err = repos.TwoFactor.UseStep(ctx, twoFactor.UserID, step)
+	} else {
+		err = repos.TwoFactor.UseRecoveryCode(ctx, twoFactor.UserID, utils.HashRecoveryCode(code))
+	}
+	if err == repository.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// TwoFactorStatus tells the user whether two-factor is enabled and how many recovery codes are left
+func TwoFactorStatus(c *gin.Context) {
+	twoFactor, err := repos.TwoFactor.FindByUser(c.Request.Context(), c.GetString("user_id"))
+	if err == repository.ErrNotFound || (err == nil && !twoFactor.Enabled()) {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch two-factor status"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":           true,
+		"enabledAt":         twoFactor.EnabledAt,
+		"recoveryCodesLeft": len(twoFactor.RecoveryCodes),
+	})
+}
+
+// SetupTwoFactor creates a new authenticator secret. Two-factor stays off
+// until the user confirms a code from it with EnableTwoFactor.
+func SetupTwoFactor(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := repos.Users.FindByID(ctx, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set up two-factor authentication"})
+		return
+	}
+	err = repos.TwoFactor.Begin(ctx, &models.TwoFactor{UserID: user.ID, Secret: secret, CreatedAt: time.Now()})
+	if err == repository.ErrDuplicate {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set up two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":          secret,
+		"provisioningUri": utils.TOTPProvisioningURI(secret, user.Username),
+	})
+}
+
+// EnableTwoFactor turns two-factor on once the user enters a code from the new
+// secret, and returns their recovery codes. They are shown only this once.
+func EnableTwoFactor(c *gin.Context) {
+	var request struct {
+		Code string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	twoFactor, err := repos.TwoFactor.FindByUser(ctx, userID)
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set up two-factor authentication first"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	if twoFactor.Enabled() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	step, ok := utils.ValidateTOTP(twoFactor.Secret, strings.TrimSpace(request.Code), time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+	codes, hashes, err := utils.NewRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	err = repos.TwoFactor.Enable(ctx, userID, time.Now(), step, hashes)
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	// The user just entered a code on this device
+	markSessionMFAVerified(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Two-factor authentication enabled; store these recovery codes somewhere safe",
+		"recoveryCodes": codes,
+	})
+}
+
+// VerifyTwoFactor confirms the second factor again on the current session, so
+// operations guarded by middleware.RequireFreshMFA are allowed for a while
+func VerifyTwoFactor(c *gin.Context) {
+	var request struct {
+		Code string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	mfa := mfaThrottle(userID)
+	if loginLocked(c, mfa) {
+		return
+	}
+
+	twoFactor, err := repos.TwoFactor.FindByUser(ctx, userID)
+	if err == repository.ErrNotFound || (err == nil && !twoFactor.Enabled()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+
+	ok, err := checkSecondFactor(ctx, twoFactor, request.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !ok {
+		recordLoginFailure(c, userID, mfa)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+	clearLoginFailures(c, mfa)
+	if !markSessionMFAVerified(c) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Code verified",
+		"expiresIn": int(utils.FreshMFAWindow().Seconds()),
+	})
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes with new ones
+func RegenerateRecoveryCodes(c *gin.Context) {
+	codes, hashes, err := utils.NewRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+	err = repos.TwoFactor.ReplaceRecoveryCodes(c.Request.Context(), c.GetString("user_id"), hashes)
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "New recovery codes generated; the old ones no longer work",
+		"recoveryCodes": codes,
+	})
+}
+
+// DisableTwoFactor turns two-factor off, or cancels a setup that wasn't finished
+func DisableTwoFactor(c *gin.Context) {
+	err := repos.TwoFactor.Delete(c.Request.Context(), c.GetString("user_id"))
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// markSessionMFAVerified records that the second factor was just entered on the current session
+func markSessionMFAVerified(c *gin.Context) bool {
+	err := repos.Sessions.MarkMFAVerified(c.Request.Context(), c.GetString("session_id"), time.Now())
+	return err == nil
+}
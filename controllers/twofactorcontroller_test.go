@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"yt_backend/models"
+	"yt_backend/utils"
+)
+
+// totpAt computes the code an authenticator app shows at the given time
+func totpAt(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decoding secret: %v", err)
+	}
+	mac := hmac.New(sha1.New, key)
+	binary.Write(mac, binary.BigEndian, at.Unix()/30)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:])&0x7fffffff)%1000000)
+}
+
+// createMFAUser stores alice with two-factor enabled and returns her secret
+func createMFAUser(t *testing.T) string {
+	t.Helper()
+	ctx := context.Background()
+	user := createPasswordUser(t)
+
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		t.Fatalf("NewTOTPSecret: %v", err)
+	}
+	if err := repos.TwoFactor.Begin(ctx, &models.TwoFactor{UserID: user.ID, Secret: secret, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("TwoFactor.Begin: %v", err)
+	}
+	if err := repos.TwoFactor.Enable(ctx, user.ID, time.Now(), 0, nil); err != nil {
+		t.Fatalf("TwoFactor.Enable: %v", err)
+	}
+	return secret
+}
+
+// loginWithCode logs alice in with her password and then the code
+func loginWithCode(t *testing.T, code string) (int, map[string]interface{}) {
+	t.Helper()
+	w, response := postJSON(t, Login, map[string]string{"username": "alice", "password": "correct horse"})
+	if w.Code != http.StatusOK || response["mfaRequired"] != true {
+		t.Fatalf("Login = %d %v, want a two-factor challenge", w.Code, response)
+	}
+	w, response = postJSON(t, CompleteMFALogin, map[string]string{
+		"challengeToken": response["challengeToken"].(string),
+		"code":           code,
+	})
+	return w.Code, response
+}
+
+func TestTOTPCodeWorksOnce(t *testing.T) {
+	useMemoryRepositories(t)
+	secret := createMFAUser(t)
+	now := time.Now()
+
+	code := totpAt(t, secret, now)
+	if status, response := loginWithCode(t, code); status != http.StatusOK || response["token"] == nil {
+		t.Fatalf("first use = %d %v, want a session", status, response)
+	}
+
+	// Someone who saw the code can't log in with it while it is still current
+	if status, response := loginWithCode(t, code); status != http.StatusUnauthorized {
+		t.Errorf("replayed code = %d %v, want 401", status, response)
+	}
+	// nor with the code from the period before, which is still within the allowed drift
+	if status, response := loginWithCode(t, totpAt(t, secret, now.Add(-30*time.Second))); status != http.StatusUnauthorized {
+		t.Errorf("older code = %d %v, want 401", status, response)
+	}
+}
+
+func TestRecoveryCodeWorksOnce(t *testing.T) {
+	useMemoryRepositories(t)
+	createMFAUser(t)
+	codes, hashes, err := utils.NewRecoveryCodes()
+	if err != nil {
+		t.Fatalf("NewRecoveryCodes: %v", err)
+	}
+	if err := repos.TwoFactor.ReplaceRecoveryCodes(context.Background(), "u1", hashes); err != nil {
+		t.Fatalf("ReplaceRecoveryCodes: %v", err)
+	}
+
+	if status, response := loginWithCode(t, codes[0]); status != http.StatusOK {
+		t.Fatalf("recovery code = %d %v, want a session", status, response)
+	}
+	if status, _ := loginWithCode(t, codes[0]); status != http.StatusUnauthorized {
+		t.Errorf("reused recovery code = %d, want 401", status)
+	}
+}
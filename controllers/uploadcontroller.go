@@ -0,0 +1,467 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"yt_backend/models"
+	"yt_backend/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultMaxUploadSize = 5 << 30 // 5 GB
+	uploadSessionTTL     = 24 * time.Hour
+	uploadSweepInterval  = time.Hour
+	uploadSweepBatch     = 100
+)
+
+// uploadLocks serializes writes to the same upload session
+var uploadLocks sync.Map
+
+func lockUpload(uploadID string) func() {
+	value, _ := uploadLocks.LoadOrStore(uploadID, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// uploadDir is where partial uploads are kept until they are finalized
+func uploadDir() string {
+	if dir := os.Getenv("UPLOAD_TMP_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "yt_uploads")
+}
+
+func uploadPartPath(uploadID string) string {
+	return filepath.Join(uploadDir(), uploadID+".part")
+}
+
+func maxUploadSize() int64 {
+	if value, err := strconv.ParseInt(os.Getenv("UPLOAD_MAX_SIZE"), 10, 64); err == nil && value > 0 {
+		return value
+	}
+	return defaultMaxUploadSize
+}
+
+// setUploadHeaders reports the session progress using the tus header names
+func setUploadHeaders(c *gin.Context, session *models.UploadSession) {
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.Length, 10))
+	c.Header("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "no-store")
+}
+
+// findUploadSession loads a session owned by the current user, writing the error response if it can't
+func findUploadSession(c *gin.Context) (*models.UploadSession, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	uploadID := c.Param("uploadId")
+	session, err := repos.UploadSessions.FindByID(c.Request.Context(), uploadID)
+	if err == repository.ErrNotFound || (err == nil && session.UserID != userID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch upload"})
+		return nil, false
+	}
+
+	if session.Status == models.UploadStatusActive && time.Now().After(session.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "Upload has expired"})
+		return nil, false
+	}
+	return session, true
+}
+
+// CreateUpload starts a resumable upload session
+func CreateUpload(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var input struct {
+		Title       string   `json:"title" binding:"required"`
+		Filename    string   `json:"filename" binding:"required"`
+		Size        int64    `json:"size" binding:"required"`
+		Description string   `json:"description"`
+		Tags        []string `json:"tags"`
+		Category    string   `json:"category"`
+		Language    string   `json:"language"`
+		Visibility  string   `json:"visibility"`
+		PublishAt   string   `json:"publishAt"` // RFC 3339
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title, filename and size are required"})
+		return
+	}
+
+	if input.Size <= 0 || input.Size > maxUploadSize() {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Upload size must be between 1 and %d bytes", maxUploadSize())})
+		return
+	}
+
+	// Check the metadata now rather than after the whole file has been sent
+	metadata := &videoMetadata{
+		Title:       input.Title,
+		Description: input.Description,
+		Tags:        input.Tags,
+		Category:    input.Category,
+		Language:    input.Language,
+		Visibility:  input.Visibility,
+	}
+	var err error
+	if metadata.PublishAt, err = parsePublishAt(input.PublishAt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := metadata.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session := models.UploadSession{
+		ID:        uuid.New().String(),
+		UserID:    userID.(string),
+		Filename:  filepath.Base(input.Filename),
+		Title:     metadata.Title,
+		Length:    input.Size,
+		Offset:    0,
+		Status:    models.UploadStatusActive,
+		ExpiresAt: time.Now().Add(uploadSessionTTL),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+
+		Description: metadata.Description,
+		Tags:        metadata.Tags,
+		Category:    metadata.Category,
+		Language:    metadata.Language,
+		Visibility:  metadata.Visibility,
+		PublishAt:   metadata.PublishAt,
+	}
+
+	// Create the empty part file that chunks are appended to
+	if err := os.MkdirAll(uploadDir(), 0o755); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+	partFile, err := os.Create(uploadPartPath(session.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+	partFile.Close()
+
+	if err := repos.UploadSessions.Create(c.Request.Context(), &session); err != nil {
+		os.Remove(uploadPartPath(session.ID))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+
+	setUploadHeaders(c, &session)
+	c.Header("Location", "/uploads/"+session.ID)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Upload created successfully",
+		"upload":  session,
+	})
+}
+
+// requestOffset reads the chunk offset from Upload-Offset or, for PUT, from Content-Range
+func requestOffset(c *gin.Context) (int64, error) {
+	if value := c.GetHeader("Upload-Offset"); value != "" {
+		return strconv.ParseInt(value, 10, 64)
+	}
+
+	// Content-Range: bytes <start>-<end>/<total>
+	contentRange := c.GetHeader("Content-Range")
+	if rangeSpec, ok := strings.CutPrefix(contentRange, "bytes "); ok {
+		start, _, found := strings.Cut(rangeSpec, "-")
+		if found {
+			return strconv.ParseInt(start, 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("Upload-Offset or Content-Range header is required")
+}
+
+// UploadChunk appends a chunk of bytes at the given offset
+func UploadChunk(c *gin.Context) {
+	unlock := lockUpload(c.Param("uploadId"))
+	defer unlock()
+
+	session, ok := findUploadSession(c)
+	if !ok {
+		return
+	}
+
+	if session.Status != models.UploadStatusActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload has already been finalized"})
+		return
+	}
+
+	offset, err := requestOffset(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Chunks must be sent in order, so the offset has to match what we already have
+	if offset != session.Offset {
+		setUploadHeaders(c, session)
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload offset does not match", "offset": session.Offset})
+		return
+	}
+
+	partFile, err := os.OpenFile(uploadPartPath(session.ID), os.O_WRONLY, 0o644)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open upload"})
+		return
+	}
+	defer partFile.Close()
+
+	// Drop anything written after the last recorded offset (e.g. an interrupted chunk)
+	if err := partFile.Truncate(session.Offset); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write upload"})
+		return
+	}
+	if _, err := partFile.Seek(session.Offset, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write upload"})
+		return
+	}
+
+	// Never accept more bytes than were announced
+	remaining := session.Length - session.Offset
+	written, copyErr := io.Copy(partFile, io.LimitReader(c.Request.Body, remaining+1))
+	if written > remaining {
+		partFile.Truncate(session.Offset)
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Chunk exceeds the declared upload size"})
+		return
+	}
+
+	// Keep whatever arrived before a dropped connection so the client can resume from there
+	newOffset := session.Offset + written
+	if err := repos.UploadSessions.UpdateOffset(c.Request.Context(), session.ID, newOffset); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save upload progress"})
+		return
+	}
+	session.Offset = newOffset
+
+	if copyErr != nil {
+		setUploadHeaders(c, session)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read chunk", "offset": session.Offset})
+		return
+	}
+
+	setUploadHeaders(c, session)
+	c.Status(http.StatusNoContent)
+}
+
+// GetUploadStatus reports how many bytes have been received
+func GetUploadStatus(c *gin.Context) {
+	session, ok := findUploadSession(c)
+	if !ok {
+		return
+	}
+
+	setUploadHeaders(c, session)
+	if c.Request.Method == http.MethodHead {
+		c.Status(http.StatusOK)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"upload": session})
+}
+
+// FinalizeUpload creates the video from the assembled file and queues it for processing
+func FinalizeUpload(c *gin.Context) {
+	unlock := lockUpload(c.Param("uploadId"))
+	defer unlock()
+
+	session, ok := findUploadSession(c)
+	if !ok {
+		return
+	}
+
+	if session.Status == models.UploadStatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload has already been finalized", "videoId": session.VideoID})
+		return
+	}
+
+	if session.Offset != session.Length {
+		setUploadHeaders(c, session)
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload is incomplete", "offset": session.Offset, "length": session.Length})
+		return
+	}
+
+	// Validated when the session was created; this fills in the defaults for older sessions
+	metadata := uploadMetadata(session)
+	if err := metadata.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := repos.Users.FindByID(c.Request.Context(), session.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Hand the assembled file to the processing worker
+	videoID := uuid.New().String()
+	sourcePath := processingSourcePath(videoID, session.Filename)
+	if err := os.MkdirAll(filepath.Dir(sourcePath), 0o755); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save video"})
+		return
+	}
+	if err := os.Rename(uploadPartPath(session.ID), sourcePath); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save video"})
+		return
+	}
+
+	// Create the video and queue it for processing
+	video, job, err := startVideoProcessing(c.Request.Context(), videoID, user, metadata, sourcePath)
+	if err != nil {
+		os.Rename(sourcePath, uploadPartPath(session.ID))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save video"})
+		return
+	}
+
+	// The video and its job exist from here on, so the upload has succeeded
+	// even if the session can't be marked
+	if err := repos.UploadSessions.MarkCompleted(c.Request.Context(), session.ID, video.ID); err != nil {
+		log.Printf("Failed to mark upload %s completed for video %s: %v", session.ID, video.ID, err)
+	}
+	uploadLocks.Delete(session.ID)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Video uploaded successfully and is being processed",
+		"video":   video,
+		"jobId":   job.ID,
+	})
+}
+
+// uploadMetadata is the video metadata the session was created with. Sessions
+// started before it was stored only have a title, and are published publicly as before.
+func uploadMetadata(session *models.UploadSession) *videoMetadata {
+	return &videoMetadata{
+		Title:       session.Title,
+		Description: session.Description,
+		Tags:        session.Tags,
+		Category:    session.Category,
+		Language:    session.Language,
+		Visibility:  session.Visibility,
+		PublishAt:   session.PublishAt,
+	}
+}
+
+// CancelUpload aborts an upload session and discards the received bytes
+func CancelUpload(c *gin.Context) {
+	unlock := lockUpload(c.Param("uploadId"))
+	defer unlock()
+
+	session, ok := findUploadSession(c)
+	if !ok {
+		return
+	}
+
+	if session.Status != models.UploadStatusActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload has already been finalized"})
+		return
+	}
+
+	if err := repos.UploadSessions.Delete(c.Request.Context(), session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel upload"})
+		return
+	}
+	os.Remove(uploadPartPath(session.ID))
+	uploadLocks.Delete(session.ID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Upload cancelled"})
+}
+
+// StartUploadSweeper removes expired upload sessions and their part files
+// every hour until ctx is cancelled
+func StartUploadSweeper(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(uploadSweepInterval)
+		defer ticker.Stop()
+		for {
+			if err := sweepUploads(ctx, time.Now()); err != nil {
+				log.Printf("Failed to sweep expired uploads: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// sweepUploads deletes sessions that expired before now with their part
+// files, part files left behind by sessions that are already gone, and the
+// locks of sessions that can no longer be written to
+func sweepUploads(ctx context.Context, now time.Time) error {
+	for {
+		sessions, err := repos.UploadSessions.ListExpired(ctx, now, uploadSweepBatch)
+		if err != nil {
+			return err
+		}
+		for _, session := range sessions {
+			unlock := lockUpload(session.ID)
+			os.Remove(uploadPartPath(session.ID))
+			err := repos.UploadSessions.Delete(ctx, session.ID)
+			uploadLocks.Delete(session.ID)
+			unlock()
+			if err != nil && err != repository.ErrNotFound {
+				return err
+			}
+		}
+		if len(sessions) < uploadSweepBatch {
+			break
+		}
+	}
+
+	// Sessions expire a fixed time after they start, so a part file untouched
+	// for that long can't belong to a live session, whichever instance created it
+	parts, err := filepath.Glob(filepath.Join(uploadDir(), "*.part"))
+	if err != nil {
+		return err
+	}
+	for _, part := range parts {
+		if info, err := os.Stat(part); err == nil && now.Sub(info.ModTime()) > uploadSessionTTL {
+			os.Remove(part)
+		}
+	}
+
+	// Requests lock an upload before looking it up, so unknown IDs leave locks behind too
+	uploadLocks.Range(func(key, value any) bool {
+		mu := value.(*sync.Mutex)
+		if !mu.TryLock() {
+			return true
+		}
+		defer mu.Unlock()
+		session, err := repos.UploadSessions.FindByID(ctx, key.(string))
+		if err == repository.ErrNotFound || (err == nil && session.Status == models.UploadStatusActive && now.After(session.ExpiresAt)) {
+			uploadLocks.Delete(key)
+		}
+		return true
+	})
+	return nil
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"yt_backend/jobs"
+	"yt_backend/models"
+	"yt_backend/search"
+	"yt_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateUploadValidatesTheTitle(t *testing.T) {
+	t.Setenv("UPLOAD_TMP_DIR", t.TempDir())
+	r := useMemoryRepositories(t)
+	createPasswordUser(t)
+
+	for _, title := range []string{"   ", strings.Repeat("a", maxTitleLength+1)} {
+		w, response := postJSONAs(t, "u1", CreateUpload, map[string]interface{}{"title": title, "filename": "clip.mp4", "size": 1024})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("title of %d characters = %d %v, want 400", len(title), w.Code, response)
+		}
+	}
+
+	w, response := postJSONAs(t, "u1", CreateUpload, map[string]interface{}{"title": "  Holiday  ", "filename": "clip.mp4", "size": 1024})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("CreateUpload = %d %v", w.Code, response)
+	}
+	id := response["upload"].(map[string]interface{})["id"].(string)
+	session, err := r.UploadSessions.FindByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if session.Title != "Holiday" {
+		t.Errorf("stored title = %q, want it trimmed", session.Title)
+	}
+}
+
+func TestFinalizeUploadKeepsTheSessionMetadata(t *testing.T) {
+	t.Setenv("UPLOAD_TMP_DIR", t.TempDir())
+	r := useMemoryRepositories(t)
+	createPasswordUser(t)
+	store, err := utils.NewLocalStore(t.TempDir(), "http://media.test")
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	utils.SetMediaStore(store)
+	SetJobQueue(jobs.NewQueue(r, search.NewMemoryIndex(), nil))
+
+	publishAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	w, response := postJSONAs(t, "u1", CreateUpload, map[string]interface{}{
+		"title":       "Holiday",
+		"filename":    "clip.mp4",
+		"size":        4,
+		"description": "Two weeks by the sea",
+		"tags":        []string{"travel", "sea"},
+		"category":    "travel",
+		"language":    "en",
+		"visibility":  models.VisibilityUnlisted,
+		"publishAt":   publishAt.Format(time.RFC3339),
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("CreateUpload = %d %v", w.Code, response)
+	}
+	id := response["upload"].(map[string]interface{})["id"].(string)
+
+	// Stand in for the chunk requests
+	if err := os.WriteFile(filepath.Join(uploadDir(), id+".part"), []byte("data"), 0o644); err != nil {
+		t.Fatalf("writing part file: %v", err)
+	}
+	if err := r.UploadSessions.UpdateOffset(context.Background(), id, 4); err != nil {
+		t.Fatalf("UpdateOffset: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/uploads/"+id+"/finalize", nil)
+	c.Params = gin.Params{{Key: "uploadId", Value: id}}
+	c.Set("user_id", "u1")
+	FinalizeUpload(c)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("FinalizeUpload = %d %s", rec.Code, rec.Body)
+	}
+
+	var finalized struct {
+		Video models.Video `json:"video"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &finalized); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	video, err := r.Videos.FindByID(context.Background(), finalized.Video.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if video.Title != "Holiday" || video.Description != "Two weeks by the sea" || video.Category != "travel" || video.Language != "en" {
+		t.Errorf("video = %q, %q, %q, %q; want the metadata sent with the upload", video.Title, video.Description, video.Category, video.Language)
+	}
+	if video.Visibility != models.VisibilityUnlisted || !slices.Equal(video.Tags, []string{"travel", "sea"}) {
+		t.Errorf("video visibility %q with tags %v, want unlisted with travel and sea", video.Visibility, video.Tags)
+	}
+	if video.PublishAt == nil || !video.PublishAt.Equal(publishAt) {
+		t.Errorf("PublishAt = %v, want %v", video.PublishAt, publishAt)
+	}
+}
+
+func TestCreateUploadRejectsInvalidMetadata(t *testing.T) {
+	t.Setenv("UPLOAD_TMP_DIR", t.TempDir())
+	useMemoryRepositories(t)
+	createPasswordUser(t)
+
+	for field, value := range map[string]interface{}{
+		"visibility": "friends",
+		"category":   "not a category",
+		"language":   "english!",
+		"publishAt":  "tomorrow",
+	} {
+		body := map[string]interface{}{"title": "Holiday", "filename": "clip.mp4", "size": 1024, field: value}
+		if w, response := postJSONAs(t, "u1", CreateUpload, body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s %q = %d %v, want 400", field, value, w.Code, response)
+		}
+	}
+}
@@ -1,18 +1,18 @@
 package controllers
 
 import (
-	"context"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 	"time"
-	"yt_backend/db"
+	"yt_backend/middleware"
 	"yt_backend/models"
+	"yt_backend/repository"
 	"yt_backend/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -38,6 +38,7 @@ func SignUp(c *gin.Context) {
 		Username: c.PostForm("username"),
 		Email:    c.PostForm("email"),
 		Password: c.PostForm("password"),
+		Role:     models.RoleUser,
 	}
 
 	// Validate required fields
@@ -45,32 +46,32 @@ func SignUp(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Username, email, and password are required"})
 		return
 	}
-
-	// Check if user already exists
-	collection := db.GetCollection("users")
-	filter := bson.M{
-		"$or": []bson.M{
-			{"Username": user.Username},
-			{"email": user.Email},
-		},
+	if !usernamePattern.MatchString(user.Username) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidUsernameMessage})
+		return
 	}
-
-	err = collection.FindOne(context.TODO(), filter).Err()
-	fmt.Println(err)
-
-	if err == nil {
-		// Found a user – duplicate
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Username or Email already exists"})
+	email, ok := normalizeEmail(user.Email)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is not a valid address"})
 		return
 	}
+	user.Email = email
 
-	if err != mongo.ErrNoDocuments {
+	// Check if user already exists
+	exists, err := repos.Users.ExistsByUsernameOrEmail(c.Request.Context(), user.Username, user.Email)
+	if err != nil {
 		// Real database error
 		fmt.Println("Database error:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
 		return
 	}
 
+	if exists {
+		// Found a user – duplicate
+		c.JSON(http.StatusConflict, gin.H{"error": "Username or Email already exists"})
+		return
+	}
+
 	// Hash password
 	hashedPassword, err := HashPass(user.Password)
 	if err != nil {
@@ -109,13 +110,28 @@ func SignUp(c *gin.Context) {
 		user.CoverImage = ""
 	}
 
-	// Save user to MongoDB
-	_, err = collection.InsertOne(context.Background(), user)
+	// Save user to the database
+	err = repos.Users.Create(c.Request.Context(), &user)
+	if err == repository.ErrDuplicate {
+		// Someone signed up with the same username or email since the check above
+		for _, upload := range []string{user.Avatar, user.CoverImage} {
+			if upload != "" {
+				utils.DeleteMedia(c.Request.Context(), upload)
+			}
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "Username or Email already exists"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save user to database"})
 		return
 	}
 
+	// The account works right away; the emailed link confirms the address
+	if err := jobQueue.EnqueueAccountEmail(c.Request.Context(), user.ID, models.TokenPurposeVerifyEmail); err != nil {
+		log.Printf("Failed to queue verification email for user %s: %v", user.ID, err)
+	}
+
 	// Return success response
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "User created successfully",
@@ -140,41 +156,57 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Create filter based on provided credentials
-	var filter bson.M
-	if loginData.Username != "" {
-		filter = bson.M{"username": loginData.Username}
-	} else {
-		filter = bson.M{"email": loginData.Email}
+	// Too many failures from this address; refuse before looking at any account
+	ip := ipThrottle(c)
+	if loginLocked(c, ip) {
+		return
 	}
 
-	// Find user in database
-	collection := db.GetCollection("users")
-	var user models.User
-	err := collection.FindOne(context.TODO(), filter).Decode(&user)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+	// Find user by the provided credentials
+	var user *models.User
+	var err error
+	name := loginData.Username
+	if name != "" {
+		user, err = repos.Users.FindByUsername(c.Request.Context(), name)
+	} else {
+		// Addresses are stored lowercased
+		name = strings.ToLower(strings.TrimSpace(loginData.Email))
+		user, err = repos.Users.FindByEmail(c.Request.Context(), name)
+	}
+	if err != nil && err != repository.ErrNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+	userID := ""
+	if user != nil {
+		userID = user.ID
+	}
+	account := accountThrottle(userID, name)
+	if loginLocked(c, account) {
 		return
 	}
 
-	// Verify password
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginData.Password))
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username/email or password"})
+	// Verify password; compare against a dummy hash when there is no password
+	// to check, so the response time doesn't reveal whether the account exists
+	hash := dummyPasswordHash()
+	if user != nil && user.Password != "" {
+		hash = []byte(user.Password)
+	}
+	err = bcrypt.CompareHashAndPassword(hash, []byte(loginData.Password))
+	if err != nil || user == nil || user.Password == "" {
+		recordLoginFailure(c, userID, account, ip)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": invalidCredentials})
 		return
 	}
+	clearLoginFailures(c, account)
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+	if user.Suspended() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   token,
-		"user":    user,
-	})
+
+	// Start a session for this device, or ask for the second factor first
+	signIn(c, user)
 }
 
 func ChangePassword(c *gin.Context) {
@@ -202,9 +234,7 @@ func ChangePassword(c *gin.Context) {
 	}
 
 	// Find user in database
-	collection := db.GetCollection("users")
-	var user models.User
-	err := collection.FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user)
+	user, err := repos.Users.FindByID(c.Request.Context(), userID.(string))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
@@ -225,87 +255,19 @@ func ChangePassword(c *gin.Context) {
 	}
 
 	// Update password in database
-	update := bson.M{
-		"$set": bson.M{
-			"password":  hashedPassword,
-			"updatedAt": time.Now(),
-		},
-	}
-
-	_, err = collection.UpdateOne(context.TODO(), bson.M{"_id": userID}, update)
+	err = repos.Users.UpdatePassword(c.Request.Context(), user.ID, hashedPassword)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Password updated successfully",
-	})
-}
-
-func CreateChannel(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	// Get channel name from form data
-	channelName := c.PostForm("channelName")
-	if channelName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Channel name is required"})
-		return
-	}
-
-	// Create a new Channel instance
-	channel := models.Channel{
-		ID:          uuid.New().String(),
-		ChannelName: channelName,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	}
-
-	// Insert channel into channels collection
-	channelCollection := db.GetCollection("channels")
-	_, err := channelCollection.InsertOne(context.TODO(), channel)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create channel"})
-		return
-	}
-
-	// Update the user's channel reference
-	userCollection := db.GetCollection("users")
-	update := bson.M{
-		"$set": bson.M{
-			"channelName": channel,
-			"updatedAt":   time.Now(),
-		},
-	}
-
-	// Update the user document
-	_, err = userCollection.UpdateOne(
-		context.TODO(),
-		bson.M{"_id": userID},
-		update,
-	)
-	if err != nil {
-		// If user update fails, we should clean up the channel
-		_, _ = channelCollection.DeleteOne(context.TODO(), bson.M{"_id": channel.ID})
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user channel"})
-		return
-	}
-
-	// Get the updated user
-	var updatedUser models.User
-	err = userCollection.FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&updatedUser)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated user"})
-		return
+	// Sign out every other device; whoever knew the old password may be using one
+	if _, err := revokeUserSessions(c.Request.Context(), user.ID, c.GetString("session_id"), "password changed"); err != nil {
+		log.Printf("Failed to revoke sessions of user %s: %v", user.ID, err)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Channel created successfully",
-		"user":    updatedUser,
+		"message": "Password updated successfully",
 	})
 }
 
@@ -316,56 +278,11 @@ func SubscribedToChannel(c *gin.Context) {
 		return
 	}
 
-	subscriptionCollection := db.GetCollection("subscriptions")
-
-	// Create pipeline to get subscribed channels
-	pipeline := []bson.M{
-		{
-			"$match": bson.M{
-				"subscribers._id": userID,
-			},
-		},
-		{
-			"$lookup": bson.M{ // Join with channels collection
-				"from":         "channels",
-				"localField":   "channelName._id",
-				"foreignField": "_id",
-				"as":           "channelDetails",
-			},
-		},
-		{
-			"$unwind": "$channelDetails", // Flatten the channelDetails array
-		},
-		{
-			"$project": bson.M{ // Shape the output
-				"channelId":    "$channelDetails._id",
-				"channelName":  "$channelDetails.channelName",
-				"createdAt":    "$channelDetails.createdAt",
-				"subscribedAt": "$createdAt", // When user subscribed
-				"subscriberCount": bson.M{
-					"$size": "$channelDetails.subscribers", // Count of subscribers
-				},
-			},
-		},
-		{
-			"$sort": bson.M{ // Sort by subscription date
-				"subscribedAt": -1,
-			},
-		},
-	}
-
-	cursor, err := subscriptionCollection.Aggregate(context.TODO(), pipeline)
+	subscribedChannels, err := repos.Subscriptions.ListSubscribedChannels(c.Request.Context(), userID.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subscribed channels"})
 		return
 	}
-	defer cursor.Close(context.TODO())
-
-	var subscribedChannels []bson.M
-	if err := cursor.All(context.TODO(), &subscribedChannels); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process subscribed channels"})
-		return
-	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":  "Subscribed channels retrieved successfully",
@@ -382,18 +299,32 @@ func Logout(c *gin.Context) {
 		return
 	}
 
-	// Add token to blacklist
+	// Revoke this access token by its jti until it would have expired
+	expiresAt := c.GetTime("token_expires_at")
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(utils.AccessTokenTTL())
+	}
 	blacklistEntry := models.TokenBlacklist{
-		Token:     c.GetHeader("Authorization"),
+		TokenID:   c.GetString("token_id"),
+		ExpiresAt: expiresAt,
 		CreatedAt: time.Now(),
 	}
 
-	blacklistCollection := db.GetCollection("token_blacklist")
-	_, err := blacklistCollection.InsertOne(context.TODO(), blacklistEntry)
+	err := repos.TokenBlacklist.Add(c.Request.Context(), &blacklistEntry)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invalidate token"})
 		return
 	}
+	middleware.NoteRevokedToken(blacklistEntry.TokenID, blacklistEntry.ExpiresAt)
+
+	// End the session so its refresh token stops working too
+	if sessionID := c.GetString("session_id"); sessionID != "" {
+		if err := repos.Sessions.Revoke(c.Request.Context(), sessionID, "logged out"); err != nil && err != repository.ErrNotFound {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end session"})
+			return
+		}
+		middleware.NoteRevokedSession(sessionID)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Logout successful",
@@ -1,18 +1,12 @@
 package controllers
 
 import (
-	"context"
 	"net/http"
 	"time"
-	"yt_backend/db"
 	"yt_backend/models"
 
-	// "yt_backend/utils"
-
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"go.mongodb.org/mongo-driver/bson"
-	// "go.mongodb.org/mongo-driver/mongo"
 )
 
 func PostComment(c *gin.Context) {
@@ -35,37 +29,32 @@ func PostComment(c *gin.Context) {
 	}
 
 	// Get user details
-	userCollection := db.GetCollection("users")
-	var user models.User
-	err := userCollection.FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user)
+	user, err := repos.Users.FindByID(c.Request.Context(), userID.(string))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	videoCollection := db.GetCollection("videos")
-	var video models.Video
-	err = videoCollection.FindOne(context.TODO(), bson.M{"_id": videoID}).Decode(&video)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+	video, ok := findViewableVideo(c, videoID)
+	if !ok {
 		return
 	}
 
 	comment := models.VideoComment{
 		ID:        uuid.New().String(),
 		Content:   content,
-		Owner:     user,
-		VComment:  video,
+		OwnerID:   user.ID,
+		VideoID:   video.ID,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	commentCollection := db.GetCollection("videocomments")
-	_, err = commentCollection.InsertOne(context.TODO(), comment)
+	err = repos.Comments.Create(c.Request.Context(), &comment)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload comment"})
 		return
 	}
+	comment.Owner = user.Summary()
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Comment uploaded successfully",
@@ -80,32 +69,42 @@ func DeleteComment(c *gin.Context) {
 		return
 	}
 
-	commentID := c.Param("commentID")
+	commentID := c.Param("commentId")
 	if commentID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment ID is required"})
 		return
 	}
 
-	commentCollection := db.GetCollection("videocomments")
-
-	var comment models.VideoComment
-	err := commentCollection.FindOne(context.TODO(), bson.M{"_id": commentID}).Decode(&comment)
+	comment, err := repos.Comments.FindByID(c.Request.Context(), commentID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
 		return
 	}
 
-	if comment.Owner.ID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to delete this comment"})
-		return
+	// Owners can delete their comments, and moderators anyone's
+	byStaff := comment.OwnerID != userID
+	if byStaff {
+		allowed, err := actingAsStaff(c, models.RoleModerator)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to delete this comment"})
+			return
+		}
 	}
 
-	_, err = commentCollection.DeleteOne(context.TODO(), bson.M{"_id": commentID})
+	err = repos.Comments.Delete(c.Request.Context(), commentID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
 		return
 	}
 
+	if byStaff {
+		recordAudit(c, commentDeletedAudit(comment, ""))
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Comment deleted successfully",
 	})
@@ -119,7 +118,7 @@ func EditComment(c *gin.Context) {
 		return
 	}
 
-	commentID := c.Param("commentID")
+	commentID := c.Param("commentId")
 	if commentID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment ID is required"})
 		return
@@ -131,42 +130,26 @@ func EditComment(c *gin.Context) {
 		return
 	}
 
-	commentCollection := db.GetCollection("videocomments")
-
-	var comment models.VideoComment
-	err := commentCollection.FindOne(context.TODO(), bson.M{"_id": commentID}).Decode(&comment)
+	comment, err := repos.Comments.FindByID(c.Request.Context(), commentID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
 		return
 	}
 
-	if comment.Owner.ID != userID {
+	if comment.OwnerID != userID {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to delete this comment"})
 		return
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"content":   content,
-			"updatedAt": time.Now(),
-		},
-	}
-
-	_, err = commentCollection.UpdateOne(
-		context.TODO(),
-		bson.M{"_id": commentID},
-		update,
-	)
-
+	err = repos.Comments.UpdateContent(c.Request.Context(), commentID, content)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
 		return
 	}
 
-	var editedComment models.User
-	err = commentCollection.FindOne(context.TODO(), bson.M{"_id": commentID}).Decode(&editedComment)
+	editedComment, err := repos.Comments.FindByID(c.Request.Context(), commentID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated user"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated comment"})
 		return
 	}
 
@@ -176,71 +159,3 @@ func EditComment(c *gin.Context) {
 	})
 
 }
-
-
-// func EditComment(c *gin.Context) {
-// 	userID, exists := c.Get("user_id")
-// 	if !exists {
-// 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-// 		return
-// 	}
-
-// 	commentID := c.Param("commentID")
-// 	if commentID == "" {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment ID is required"})
-// 		return
-// 	}
-
-// 	var input struct {
-// 		Content string `json:"content" binding:"required"`
-// 	}
-
-// 	if err := c.ShouldBindJSON(&input); err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment cannot be empty"})
-// 		return
-// 	}
-
-// 	commentCollection := db.GetCollection("videocomments")
-
-// 	var comment models.VideoComment
-// 	err := commentCollection.FindOne(context.TODO(), bson.M{"_id": commentID}).Decode(&comment)
-// 	if err != nil {
-// 		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
-// 		return
-// 	}
-
-// 	if comment.Owner.ID != userID {
-// 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to edit this comment"})
-// 		return
-// 	}
-
-// 	update := bson.M{
-// 		"$set": bson.M{
-// 			"content":   input.Content,
-// 			"updatedAt": time.Now(),
-// 		},
-// 	}
-
-// 	_, err = commentCollection.UpdateOne(
-// 		context.TODO(),
-// 		bson.M{"_id": commentID},
-// 		update,
-// 	)
-
-// 	if err != nil {
-// 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
-// 		return
-// 	}
-
-// 	var editedComment models.VideoComment
-// 	err = commentCollection.FindOne(context.TODO(), bson.M{"_id": commentID}).Decode(&editedComment)
-// 	if err != nil {
-// 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated comment"})
-// 		return
-// 	}
-
-// 	c.JSON(http.StatusOK, gin.H{
-// 		"message": "Comment edited successfully",
-// 		"comment": editedComment,
-// 	})
-// }
\ No newline at end of file
@@ -2,16 +2,29 @@ package controllers
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
-	"yt_backend/db"
+	"unicode/utf8"
+	"yt_backend/jobs"
 	"yt_backend/models"
+	"yt_backend/repository"
 	"yt_backend/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"go.mongodb.org/mongo-driver/bson"
-	// "go.mongodb.org/mongo-driver/mongo"
 )
 
 func UploadVideo(c *gin.Context) {
@@ -35,63 +48,184 @@ func UploadVideo(c *gin.Context) {
 		return
 	}
 
-	// Get title from form
-	title := c.PostForm("title")
-	if title == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+	// Get title and the optional metadata from form
+	metadata, err := videoMetadataFromForm(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Get user details
-	userCollection := db.GetCollection("users")
-	var user models.User
-	err = userCollection.FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user)
+	user, err := repos.Users.FindByID(c.Request.Context(), userID.(string))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	// Upload video to Cloudinary
-	videoURL, err := utils.HandleVideoUpload(c.Request.Context(), videoFile, "videos")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload video to Cloudinary"})
+	// Stage the file for the processing worker
+	videoID := uuid.New().String()
+	sourcePath := processingSourcePath(videoID, videoFile.Filename)
+	if err := c.SaveUploadedFile(videoFile, sourcePath); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save video"})
 		return
 	}
 
-	// Get video duration
-	duration, err := utils.GetVideoDuration(videoFile)
+	// Create the video and queue it for processing
+	video, job, err := startVideoProcessing(c.Request.Context(), videoID, user, metadata, sourcePath)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video duration"})
+		os.Remove(sourcePath)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save video"})
 		return
 	}
 
-	// Create video document
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Video uploaded successfully and is being processed",
+		"video":   video,
+		"jobId":   job.ID,
+	})
+}
+
+// processingSourcePath is where an uploaded file is staged before it is handed to the media store
+func processingSourcePath(videoID string, filename string) string {
+	return filepath.Join(uploadDir(), "processing", videoID+strings.ToLower(filepath.Ext(filename)))
+}
+
+// startVideoProcessing moves the staged source to the media store, creates the
+// video in the processing state and queues the job that makes it playable. The
+// staged file is only removed once everything succeeded.
+func startVideoProcessing(ctx context.Context, videoID string, user *models.User, metadata *videoMetadata, sourcePath string) (*models.Video, *models.Job, error) {
+	sourceURL, err := utils.PutFile(ctx, utils.SourceKey(videoID, filepath.Ext(sourcePath)), sourcePath, utils.PutOptions{ResourceType: "video"})
+	if err != nil {
+		return nil, nil, err
+	}
+
 	video := models.Video{
-		ID:          uuid.New().String(),
-		Title:       title,
-		URL:         videoURL,
-		Owner:       user,
-		ChannelName: user.ChannelName,
-		Duration:    duration,
+		ID:          videoID,
+		Title:       metadata.Title,
+		Description: metadata.Description,
+		Tags:        metadata.Tags,
+		Category:    metadata.Category,
+		Language:    metadata.Language,
+		Visibility:  metadata.Visibility,
+		PublishAt:   metadata.PublishAt,
+		OwnerID:     user.ID,
+		ChannelID:   user.ChannelName.ID,
+		Status:      models.VideoStatusProcessing,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
 	// Save video to database
-	videoCollection := db.GetCollection("videos")
-	_, err = videoCollection.InsertOne(context.TODO(), video)
+	if err := repos.Videos.Create(ctx, &video); err != nil {
+		utils.DeleteMedia(ctx, sourceURL)
+		return nil, nil, err
+	}
+
+	job, err := jobQueue.Enqueue(ctx, jobs.TypeProcessVideo, video.ID, map[string]string{"sourceUrl": sourceURL})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save video"})
+		repos.Videos.Delete(ctx, video.ID)
+		utils.DeleteMedia(ctx, sourceURL)
+		return nil, nil, err
+	}
+	os.Remove(sourcePath)
+
+	// Fill in what a read would join in, for the response
+	video.Owner = user.Summary()
+	if channel, err := repos.Channels.FindByID(ctx, video.ChannelID); err == nil {
+		video.Channel = channel
+	}
+	return &video, job, nil
+}
+
+func DeleteVideo(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	videoID := c.Param("videoId")
+	if videoID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Video ID is required"})
+		return
+	}
+
+	// Find the video and verify ownership
+	video, err := repos.Videos.FindByID(c.Request.Context(), videoID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+
+	// Owners can delete their videos, and moderators anyone's
+	byStaff := video.OwnerID != userID
+	if byStaff {
+		allowed, err := actingAsStaff(c, models.RoleModerator)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to delete this video"})
+			return
+		}
+	}
+
+	// Delete the video and its thumbnail from media storage
+	for _, fileURL := range []string{video.URL, video.Thumbnail} {
+		if fileURL == "" {
+			continue
+		}
+		err = utils.DeleteMedia(c.Request.Context(), fileURL)
+		if err != nil && err != utils.ErrMediaNotFound {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete video from storage"})
+			return
+		}
+	}
+
+	// Delete the HLS playlists and segments, and the preview sprite and track
+	if video.PreviewSprite != "" {
+		err = utils.DeleteMediaPrefix(c.Request.Context(), utils.PreviewKeyPrefix(video.ID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete video from storage"})
+			return
+		}
+	}
+	if video.HLSURL != "" {
+		err = utils.DeleteMediaPrefix(c.Request.Context(), utils.HLSKeyPrefix(video.ID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete video from storage"})
+			return
+		}
+	}
+
+	// Delete the video from database
+	err = repos.Videos.Delete(c.Request.Context(), videoID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete video"})
 		return
 	}
 
+	if err := searchIndex.Remove(c.Request.Context(), videoID); err != nil {
+		log.Printf("Failed to remove video %s from the search index: %v", videoID, err)
+	}
+
+	if byStaff {
+		recordAudit(c, models.AuditLog{
+			Action:     models.AuditVideoDeleted,
+			TargetType: "video",
+			TargetID:   video.ID,
+			Details:    map[string]string{"title": video.Title, "ownerId": video.OwnerID},
+		})
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Video uploaded successfully",
-		"video":   video,
+		"message": "Video deleted successfully",
 	})
 }
 
-func DeleteVideo(c *gin.Context) {
+// UpdateVideo edits the metadata of a video; only fields present in the body change
+func UpdateVideo(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
@@ -104,42 +238,261 @@ func DeleteVideo(c *gin.Context) {
 		return
 	}
 
-	// Get video collection
-	videoCollection := db.GetCollection("videos")
+	var input struct {
+		Title       *string   `json:"title"`
+		Description *string   `json:"description"`
+		Tags        *[]string `json:"tags"`
+		Category    *string   `json:"category"`
+		Language    *string   `json:"language"`
+		Visibility  *string   `json:"visibility"`
+		PublishAt   *string   `json:"publishAt"` // RFC 3339; an empty string clears the schedule
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
 
 	// Find the video and verify ownership
-	var video models.Video
-	err := videoCollection.FindOne(context.TODO(), bson.M{"_id": videoID}).Decode(&video)
+	video, err := repos.Videos.FindByID(c.Request.Context(), videoID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
 		return
 	}
 
 	// Check if the user is the owner of the video
-	if video.Owner.ID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to delete this video"})
+	if video.OwnerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to edit this video"})
+		return
+	}
+
+	metadata := videoMetadataOf(video)
+	if input.Title != nil {
+		metadata.Title = *input.Title
+	}
+	if input.Description != nil {
+		metadata.Description = *input.Description
+	}
+	if input.Tags != nil {
+		metadata.Tags = *input.Tags
+	}
+	if input.Category != nil {
+		metadata.Category = *input.Category
+	}
+	if input.Language != nil {
+		metadata.Language = *input.Language
+	}
+	if input.Visibility != nil {
+		metadata.Visibility = *input.Visibility
+	}
+	if input.PublishAt != nil {
+		metadata.PublishAt, err = parsePublishAt(*input.PublishAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := metadata.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	metadata.applyTo(video)
+	err = repos.Videos.UpdateMetadata(c.Request.Context(), video)
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update video"})
+		return
+	}
+
+	syncSearchIndex(c.Request.Context(), video)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Video updated successfully",
+		"video":   video,
+	})
+}
+
+// GetVideo returns a single video
+func GetVideo(c *gin.Context) {
+	video, ok := findViewableVideo(c, c.Param("videoId"))
+	if !ok {
 		return
 	}
 
-	// Delete the video from Cloudinary
-	err = utils.DeleteFromCloudinary(c.Request.Context(), video.URL)
+	c.JSON(http.StatusOK, gin.H{"video": video})
+}
+
+// ListVideos lists public videos, optionally filtered by owner, tag and upload date.
+// Owners filtering on themselves also see their hidden videos.
+func ListVideos(c *gin.Context) {
+	query, err := videoQueryFromRequest(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete video from Cloudinary"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Delete the video from database
-	_, err = videoCollection.DeleteOne(context.TODO(), bson.M{"_id": videoID})
+	query.OwnerID = c.Query("owner")
+	query.IncludeHidden = query.OwnerID != "" && query.OwnerID == c.GetString("user_id")
+	listVideos(c, query)
+}
+
+// ListChannelVideos lists the videos of a channel
+func ListChannelVideos(c *gin.Context) {
+	query, err := videoQueryFromRequest(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete video"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query.ChannelID = c.Param("channelId")
+	if _, err := repos.Channels.FindByID(c.Request.Context(), query.ChannelID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+
+	// The channel's owner also sees their hidden videos
+	if userID := c.GetString("user_id"); userID != "" {
+		if user, err := repos.Users.FindByID(c.Request.Context(), userID); err == nil {
+			query.IncludeHidden = user.ChannelName.ID == query.ChannelID
+		}
+	}
+	listVideos(c, query)
+}
+
+const (
+	defaultVideoPageSize = 20
+	maxVideoPageSize     = 50
+)
+
+// videoCursor is what the opaque cursor handed to clients decodes to
+type videoCursor struct {
+	Sort  string    `json:"s"`
+	Time  time.Time `json:"t"`
+	Count int64     `json:"c"`
+	ID    string    `json:"i"`
+}
+
+func encodeVideoCursor(sort string, video *models.Video) string {
+	cursor := videoCursor{Sort: sort, Time: video.CreatedAt, ID: video.ID}
+	switch sort {
+	case repository.VideoSortViews:
+		cursor.Count = int64(video.Views)
+	case repository.VideoSortLikes:
+		cursor.Count = int64(video.Likes)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeVideoCursor(value string, sort string) (*repository.VideoCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid cursor")
+	}
+	var cursor videoCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, fmt.Errorf("Invalid cursor")
+	}
+	// A cursor only makes sense for the order it was issued for
+	if cursor.Sort != sort {
+		return nil, fmt.Errorf("Cursor does not match the requested sort")
+	}
+	return &repository.VideoCursor{Time: cursor.Time, Count: cursor.Count, ID: cursor.ID}, nil
+}
+
+// videoQueryFromRequest reads the tag, from, to, sort, limit and cursor query parameters
+func videoQueryFromRequest(c *gin.Context) (*repository.VideoQuery, error) {
+	query := &repository.VideoQuery{
+		Tag:   strings.TrimSpace(c.Query("tag")),
+		Sort:  c.DefaultQuery("sort", repository.VideoSortNewest),
+		Now:   time.Now(),
+		Limit: defaultVideoPageSize,
+	}
+
+	switch query.Sort {
+	case repository.VideoSortNewest, repository.VideoSortViews, repository.VideoSortLikes:
+	default:
+		return nil, fmt.Errorf("sort must be newest, views or likes")
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 1 || limit > maxVideoPageSize {
+			return nil, fmt.Errorf("limit must be between 1 and %d", maxVideoPageSize)
+		}
+		query.Limit = limit
+	}
+
+	var err error
+	if query.From, err = parseDateParam(c.Query("from")); err != nil {
+		return nil, fmt.Errorf("from %v", err)
+	}
+	if query.To, err = parseDateParam(c.Query("to")); err != nil {
+		return nil, fmt.Errorf("to %v", err)
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		if query.After, err = decodeVideoCursor(value, query.Sort); err != nil {
+			return nil, err
+		}
+	}
+	return query, nil
+}
+
+// parseDateParam accepts an RFC 3339 timestamp or a YYYY-MM-DD date (midnight UTC)
+func parseDateParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return &parsed, nil
+		}
+	}
+	return nil, fmt.Errorf("must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+}
+
+// listVideos runs the query and responds with one page plus the cursor for the next
+func listVideos(c *gin.Context, query *repository.VideoQuery) {
+	pageSize := query.Limit
+	query.Limit = pageSize + 1 // one extra row tells us whether there is a next page
+
+	videos, err := repos.Videos.List(c.Request.Context(), *query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch videos"})
 		return
 	}
 
+	nextCursor := ""
+	if int64(len(videos)) > pageSize {
+		videos = videos[:pageSize]
+		nextCursor = encodeVideoCursor(query.Sort, &videos[len(videos)-1])
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Video deleted successfully",
+		"videos":     videos,
+		"nextCursor": nextCursor,
 	})
 }
 
+// findViewableVideo loads a video the current (possibly anonymous) user may watch.
+// Hidden videos get the same 404 as missing ones so their existence isn't revealed.
+func findViewableVideo(c *gin.Context, videoID string) (*models.Video, bool) {
+	video, err := repos.Videos.FindByID(c.Request.Context(), videoID)
+	if err != nil && err != repository.ErrNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch video"})
+		return nil, false
+	}
+	if err == repository.ErrNotFound || !video.VisibleTo(c.GetString("user_id"), time.Now()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return nil, false
+	}
+	return video, true
+}
+
 func IncrementViews(c *gin.Context) {
 	videoID := c.Param("videoId")
 	if videoID == "" {
@@ -147,16 +500,15 @@ func IncrementViews(c *gin.Context) {
 		return
 	}
 
-	videoCollection := db.GetCollection("videos")
+	if _, ok := findViewableVideo(c, videoID); !ok {
+		return
+	}
 
-	var video models.Video
-	err := videoCollection.FindOne(context.TODO(), bson.M{"_id": videoID}).Decode(&video)
-	if err != nil {
+	err := repos.Videos.IncrementViews(c.Request.Context(), videoID)
+	if err == repository.ErrNotFound {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
 		return
 	}
-
-	_, err = videoCollection.UpdateOne(context.TODO(), bson.M{"_id": videoID}, bson.M{"$inc": bson.M{"views": 1}})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to increment views"})
 		return
@@ -166,3 +518,347 @@ func IncrementViews(c *gin.Context) {
 		"message": "Views incremented successfully",
 	})
 }
+
+func GetVideoStatus(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	videoID := c.Param("videoId")
+	if videoID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Video ID is required"})
+		return
+	}
+
+	video, err := repos.Videos.FindByID(c.Request.Context(), videoID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+
+	// Processing details, including failure reasons, are only shown to the owner
+	if video.OwnerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to view this video's status"})
+		return
+	}
+
+	// Videos uploaded before processing existed have no status and are playable
+	status := video.Status
+	if status == "" {
+		status = models.VideoStatusReady
+	}
+
+	response := gin.H{
+		"videoId": video.ID,
+		"status":  status,
+	}
+
+	job, err := repos.Jobs.FindLatestByVideo(c.Request.Context(), video.ID)
+	if err != nil && err != repository.ErrNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch processing job"})
+		return
+	}
+	if job != nil {
+		response["job"] = job
+		response["progress"] = job.Progress
+		if job.Error != "" {
+			response["error"] = job.Error
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// maxThumbnailSize is the largest custom thumbnail accepted
+const maxThumbnailSize = 2 << 20 // 2 MB
+
+// UploadThumbnail replaces a video's thumbnail with an image uploaded by the owner
+func UploadThumbnail(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	videoID := c.Param("videoId")
+	video, err := repos.Videos.FindByID(c.Request.Context(), videoID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+
+	// Check if the user is the owner of the video
+	if video.OwnerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to change this video's thumbnail"})
+		return
+	}
+
+	// Get thumbnail file
+	thumbnailFile, err := c.FormFile("thumbnail")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Thumbnail file is required"})
+		return
+	}
+	if thumbnailFile.Size > maxThumbnailSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Thumbnail must be 2 MB or smaller"})
+		return
+	}
+	if !isImageFile(thumbnailFile) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Thumbnail must be a JPEG, PNG or WebP image"})
+		return
+	}
+
+	thumbnailURL, err := utils.HandleImageUpload(c.Request.Context(), thumbnailFile, "thumbnails")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload thumbnail"})
+		return
+	}
+
+	err = repos.Videos.SetCustomThumbnail(c.Request.Context(), video.ID, thumbnailURL)
+	if err != nil {
+		utils.DeleteMedia(c.Request.Context(), thumbnailURL)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update thumbnail"})
+		return
+	}
+
+	// The previous thumbnail is no longer referenced
+	if video.Thumbnail != "" && video.Thumbnail != thumbnailURL {
+		if err := utils.DeleteMedia(c.Request.Context(), video.Thumbnail); err != nil && err != utils.ErrMediaNotFound {
+			log.Printf("Failed to delete old thumbnail %s: %v", video.Thumbnail, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Thumbnail updated successfully",
+		"thumbnail": thumbnailURL,
+	})
+}
+
+// isImageFile sniffs the first bytes of an upload rather than trusting its Content-Type header
+func isImageFile(file *multipart.FileHeader) bool {
+	src, err := file.Open()
+	if err != nil {
+		return false
+	}
+	defer src.Close()
+
+	header := make([]byte, 512)
+	n, _ := io.ReadFull(src, header)
+	switch http.DetectContentType(header[:n]) {
+	case "image/jpeg", "image/png", "image/webp":
+		return true
+	}
+	return false
+}
+
+// hlsSegmentURLExpiry is how long the signed segment URLs handed to players stay valid
+const hlsSegmentURLExpiry = time.Hour
+
+// StreamVideo serves a video's HLS output. Playlists are read from media storage
+// and returned directly so players resolve the relative segment paths against this
+// endpoint; segments redirect to a signed media storage URL.
+func StreamVideo(c *gin.Context) {
+	videoID := c.Param("videoId")
+	file := strings.TrimPrefix(path.Clean("/"+c.Param("file")), "/")
+
+	ext := path.Ext(file)
+	if ext != ".m3u8" && ext != ".ts" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stream not found"})
+		return
+	}
+
+	video, ok := findViewableVideo(c, videoID)
+	if !ok {
+		return
+	}
+	if video.Status != models.VideoStatusReady || !strings.HasSuffix(video.HLSURL, "/"+utils.HLSMasterPlaylist) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stream not available"})
+		return
+	}
+
+	store, err := utils.GetMediaStore()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Media storage is not configured"})
+		return
+	}
+
+	// Every HLS file sits next to the master playlist
+	fileURL := strings.TrimSuffix(video.HLSURL, utils.HLSMasterPlaylist) + file
+
+	if ext == ".ts" {
+		signedURL, err := store.SignedURL(c.Request.Context(), fileURL, hlsSegmentURLExpiry)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign segment URL"})
+			return
+		}
+		c.Redirect(http.StatusFound, signedURL)
+		return
+	}
+
+	playlist, err := store.Open(c.Request.Context(), fileURL)
+	if err == utils.ErrMediaNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stream not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read playlist"})
+		return
+	}
+	defer playlist.Close()
+
+	c.DataFromReader(http.StatusOK, -1, utils.HLSContentType(file), playlist, map[string]string{
+		"Cache-Control": "public, max-age=300",
+	})
+}
+
+const (
+	maxTitleLength       = 100
+	maxDescriptionLength = 5000
+	maxTags              = 30
+	maxTagLength         = 30
+	maxTagsLength        = 500
+)
+
+// languagePattern accepts BCP 47 style codes such as "en", "pt-BR" or "zh-Hant"
+var languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// videoMetadata holds the owner editable fields of a video
+type videoMetadata struct {
+	Title       string
+	Description string
+	Tags        []string
+	Category    string
+	Language    string
+	Visibility  string
+	PublishAt   *time.Time
+}
+
+// videoMetadataFromForm reads the metadata sent alongside an upload. Tags may be
+// comma separated, repeated, or both.
+func videoMetadataFromForm(c *gin.Context) (*videoMetadata, error) {
+	metadata := &videoMetadata{
+		Title:       c.PostForm("title"),
+		Description: c.PostForm("description"),
+		Tags:        c.PostFormArray("tags"),
+		Category:    c.PostForm("category"),
+		Language:    c.PostForm("language"),
+		Visibility:  c.DefaultPostForm("visibility", models.VisibilityPublic),
+	}
+
+	var err error
+	if metadata.PublishAt, err = parsePublishAt(c.PostForm("publishAt")); err != nil {
+		return nil, err
+	}
+	if err := metadata.validate(); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+func videoMetadataOf(video *models.Video) *videoMetadata {
+	return &videoMetadata{
+		Title:       video.Title,
+		Description: video.Description,
+		Tags:        video.Tags,
+		Category:    video.Category,
+		Language:    video.Language,
+		Visibility:  video.Visibility,
+		PublishAt:   video.PublishAt,
+	}
+}
+
+func (m *videoMetadata) applyTo(video *models.Video) {
+	video.Title = m.Title
+	video.Description = m.Description
+	video.Tags = m.Tags
+	video.Category = m.Category
+	video.Language = m.Language
+	video.Visibility = m.Visibility
+	video.PublishAt = m.PublishAt
+}
+
+// validate normalizes the metadata and reports the first problem found
+func (m *videoMetadata) validate() error {
+	m.Title = strings.TrimSpace(m.Title)
+	if m.Title == "" {
+		return fmt.Errorf("Title is required")
+	}
+	if utf8.RuneCountInString(m.Title) > maxTitleLength {
+		return fmt.Errorf("Title must be at most %d characters", maxTitleLength)
+	}
+
+	m.Description = strings.TrimSpace(m.Description)
+	if utf8.RuneCountInString(m.Description) > maxDescriptionLength {
+		return fmt.Errorf("Description must be at most %d characters", maxDescriptionLength)
+	}
+
+	m.Tags = normalizeTags(m.Tags)
+	if len(m.Tags) > maxTags {
+		return fmt.Errorf("A video can have at most %d tags", maxTags)
+	}
+	total := 0
+	for _, tag := range m.Tags {
+		length := utf8.RuneCountInString(tag)
+		if length > maxTagLength {
+			return fmt.Errorf("Tags must be at most %d characters", maxTagLength)
+		}
+		total += length
+	}
+	if total > maxTagsLength {
+		return fmt.Errorf("Tags must be at most %d characters in total", maxTagsLength)
+	}
+
+	m.Category = strings.ToLower(strings.TrimSpace(m.Category))
+	if m.Category != "" && !slices.Contains(models.VideoCategories, m.Category) {
+		return fmt.Errorf("Category must be one of: %s", strings.Join(models.VideoCategories, ", "))
+	}
+
+	m.Language = strings.TrimSpace(m.Language)
+	if m.Language != "" && !languagePattern.MatchString(m.Language) {
+		return fmt.Errorf("Language must be a language code such as \"en\" or \"pt-BR\"")
+	}
+
+	// Videos created before visibility existed are public
+	if m.Visibility == "" {
+		m.Visibility = models.VisibilityPublic
+	}
+	switch m.Visibility {
+	case models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityPrivate:
+	default:
+		return fmt.Errorf("Visibility must be public, unlisted or private")
+	}
+	return nil
+}
+
+// normalizeTags splits comma separated values, trims them and drops empty and duplicate tags
+func normalizeTags(values []string) []string {
+	tags := []string{}
+	seen := make(map[string]bool)
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimSpace(tag)
+			key := strings.ToLower(tag)
+			if tag == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// parsePublishAt parses an RFC 3339 publish time; an empty value means "publish immediately"
+func parsePublishAt(value string) (*time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	publishAt, err := time.Parse(time.RFC3339, strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("publishAt must be an RFC 3339 timestamp")
+	}
+	publishAt = publishAt.UTC()
+	return &publishAt, nil
+}
@@ -1,16 +1,14 @@
 package controllers
 
 import (
-	"context"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"github.com/google/uuid"
-	"yt_backend/db"
 	"yt_backend/models"
+	"yt_backend/repository"
 )
 
 func AddVideoToWatchHistory(c *gin.Context) {
@@ -36,14 +34,13 @@ func AddVideoToWatchHistory(c *gin.Context) {
 		WatchedAt: time.Now(),
 	}
 
-	watchHistoryCollection := db.GetCollection("video_watches")
-	result, err := watchHistoryCollection.InsertOne(context.TODO(), watchEntry)
+	err := repos.WatchHistory.Add(c.Request.Context(), &watchEntry)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Video added to watch history", "id": result.InsertedID})
+	c.JSON(http.StatusOK, gin.H{"message": "Video added to watch history", "id": watchEntry.ID})
 }
 
 func GetWatchHistory(c *gin.Context) {
@@ -65,35 +62,12 @@ func GetWatchHistory(c *gin.Context) {
 
 	skip := (page - 1) * limit
 
-	watchHistoryCollection := db.GetCollection("video_watches")
-	
-	pipeline := []bson.M{
-			{
-				"$match": bson.M{"user_id": userID},
-			},
-			{
-				"$sort": bson.M{"watched_at": -1},
-			},
-			{
-				"$skip": int64(skip),
-			},
-			{
-				"$limit": int64(limit),
-			},
-		}
-
-	cursor, err := watchHistoryCollection.Aggregate(context.TODO(), pipeline)
+	entries, err := repos.WatchHistory.ListByUser(c.Request.Context(), userID.(string), int64(skip), int64(limit))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	var entries []models.VideoWatchEntry
-	if err := cursor.All(context.TODO(), &entries); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
 	c.JSON(http.StatusOK, gin.H{"watch_history": entries, "page": page, "limit": limit})
 }
 
@@ -106,18 +80,13 @@ func DeleteVideoFromWatchHistory(c *gin.Context) {
 
 	videoID := c.Param("video_id")
 
-	watchHistoryCollection := db.GetCollection("video_watches")
-	result, err := watchHistoryCollection.DeleteOne(context.TODO(),
-		bson.M{"user_id": userID, "video_id": videoID},
-	)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	err := repos.WatchHistory.Delete(c.Request.Context(), userID.(string), videoID)
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found in watch history"})
 		return
 	}
-
-	if result.DeletedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found in watch history"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -6,7 +6,6 @@ import (
 	"log"
 	"os"
 
-	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -14,11 +13,6 @@ import (
 var Client *mongo.Client
 
 func ConnectDB() {
-	// Load environment variables from .env file
-	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file")
-	}
-
 	// Get MongoDB URI from environment variable
 	uri := os.Getenv("MONGODB_URI")
 	if uri == "" {
@@ -43,6 +37,15 @@ func ConnectDB() {
 	fmt.Println("Successfully connected to MongoDB Atlas!")
 }
 
+// Database returns the application database, named by DB_NAME (default "yt_backend")
+func Database() *mongo.Database {
+	name := os.Getenv("DB_NAME")
+	if name == "" {
+		name = "yt_backend"
+	}
+	return Client.Database(name)
+}
+
 func GetCollection(collectionName string) *mongo.Collection {
-	return Client.Database("yt_backend").Collection(collectionName)
+	return Database().Collection(collectionName)
 }
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Indexes declares the indexes every collection needs. The unique ones are what
+// actually stop duplicate likes, subscriptions and accounts; the checks in the
+// controllers only give a friendlier error in the common case.
+var Indexes = map[string][]mongo.IndexModel{
+	"users": {
+		{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetName("username_unique").SetUnique(true)},
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetName("email_unique").SetUnique(true)},
+		{Keys: bson.D{{Key: "role", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetName("role_created")},
+	},
+	"likes": {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "videoId", Value: 1}}, Options: options.Index().SetName("user_video_unique").SetUnique(true)},
+		{Keys: bson.D{{Key: "videoId", Value: 1}}, Options: options.Index().SetName("video")},
+	},
+	"subscriptions": {
+		{Keys: bson.D{{Key: "subscriberId", Value: 1}, {Key: "channelId", Value: 1}}, Options: options.Index().SetName("subscriber_channel_unique").SetUnique(true)},
+		{Keys: bson.D{{Key: "channelId", Value: 1}}, Options: options.Index().SetName("channel")},
+	},
+	"video_watches": {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "watchedAt", Value: -1}}, Options: options.Index().SetName("user_watched")},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "videoId", Value: 1}}, Options: options.Index().SetName("user_video")},
+		{Keys: bson.D{{Key: "videoId", Value: 1}}, Options: options.Index().SetName("video")},
+	},
+	"videocomments": {
+		{Keys: bson.D{{Key: "videoId", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetName("video_created")},
+		{Keys: bson.D{{Key: "ownerId", Value: 1}}, Options: options.Index().SetName("owner")},
+	},
+	"videos": {
+		{Keys: bson.D{{Key: "ownerId", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetName("owner_created")},
+		{Keys: bson.D{{Key: "channelId", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetName("channel_created")},
+	},
+	"sessions": {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "lastUsedAt", Value: -1}}, Options: options.Index().SetName("user_last_used")},
+		// Expired sessions are removed by MongoDB once they can no longer be refreshed
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetName("expires_ttl").SetExpireAfterSeconds(0)},
+	},
+	"token_blacklist": {
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetName("expires_ttl").SetExpireAfterSeconds(0)},
+	},
+	"audit_logs": {
+		{Keys: bson.D{{Key: "createdAt", Value: -1}}, Options: options.Index().SetName("created")},
+		{Keys: bson.D{{Key: "actorId", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetName("actor_created")},
+		{Keys: bson.D{{Key: "targetType", Value: 1}, {Key: "targetId", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetName("target_created")},
+	},
+	"user_tokens": {
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "purpose", Value: 1}}, Options: options.Index().SetName("user_purpose")},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetName("expires_ttl").SetExpireAfterSeconds(0)},
+	},
+	"api_keys": {
+		{Keys: bson.D{{Key: "prefix", Value: 1}}, Options: options.Index().SetName("prefix_unique").SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetName("user_created")},
+	},
+	"login_throttles": {
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetName("expires_ttl").SetExpireAfterSeconds(0)},
+	},
+	"identities": {
+		{Keys: bson.D{{Key: "provider", Value: 1}, {Key: "subject", Value: 1}}, Options: options.Index().SetName("provider_subject_unique").SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}}, Options: options.Index().SetName("user")},
+	},
+	"oidc_logins": {
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetName("expires_ttl").SetExpireAfterSeconds(0)},
+	},
+	"channels": {
+		// Channels from before owners and handles existed have neither, so only set ones must be unique
+		{Keys: bson.D{{Key: "ownerId", Value: 1}}, Options: options.Index().SetName("owner_unique").SetUnique(true).
+			SetPartialFilterExpression(bson.M{"ownerId": bson.M{"$type": "string"}})},
+		{Keys: bson.D{{Key: "handle", Value: 1}}, Options: options.Index().SetName("handle_unique").SetUnique(true).
+			SetPartialFilterExpression(bson.M{"handle": bson.M{"$type": "string"}})},
+	},
+	"playlists": {
+		{Keys: bson.D{{Key: "userId", Value: 1}}, Options: options.Index().SetName("user")},
+	},
+	"jobs": {
+		// One index per branch of the ClaimNext $or, so workers polling an idle queue stay cheap
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "runAt", Value: 1}}, Options: options.Index().SetName("status_run_at")},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "lockedUntil", Value: 1}}, Options: options.Index().SetName("status_locked_until")},
+		{Keys: bson.D{{Key: "videoId", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetName("video_created")},
+	},
+	"upload_sessions": {
+		// Not a TTL index: the upload sweep has to delete each session's part file along with it
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetName("expires")},
+	},
+}
+
+// EnsureIndexes creates any declared index that is missing. Creating an index
+// that already exists with the same definition is a no-op, so this runs on
+// every start. It has to run after migrations, which may rename indexed fields.
+func EnsureIndexes(ctx context.Context, database *mongo.Database) error {
+	for collection, indexes := range Indexes {
+		if _, err := database.Collection(collection).Indexes().CreateMany(ctx, indexes); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return fmt.Errorf("failed to create indexes on %s: existing documents break a unique index; run \"migrate up\" and remove any remaining duplicates: %v", collection, err)
+			}
+			return fmt.Errorf("failed to create indexes on %s: %v", collection, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"yt_backend/models"
+	"yt_backend/repository"
+	"yt_backend/utils"
+)
+
+// TypeDeleteAccount removes everything a deleted user left behind: their
+// videos with the likes, comments and views on them, their own likes,
+// comments, subscriptions, playlists and watch history, their channel and its
+// banner, their sign-in data and their images. Every step can be repeated, so
+// a failed attempt is simply retried.
+const TypeDeleteAccount = "delete_account"
+
+// EnqueueAccountDeletion queues the deletion of the user's data. The payload
+// keeps what is needed once the user document itself is gone.
+func (q *Queue) EnqueueAccountDeletion(ctx context.Context, user *models.User) (*models.Job, error) {
+	return q.Enqueue(ctx, TypeDeleteAccount, "", map[string]string{
+		"userId":     user.ID,
+		"channelId":  user.ChannelName.ID,
+		"avatar":     user.Avatar,
+		"coverImage": user.CoverImage,
+	})
+}
+
+func (q *Queue) deleteAccount(ctx context.Context, job *models.Job, report func(step string, progress int)) error {
+	userID := job.Payload["userId"]
+
+	report("videos", 10)
+	videos, err := q.repos.Videos.List(ctx, repository.VideoQuery{OwnerID: userID, IncludeHidden: true, Now: time.Now()})
+	if err != nil {
+		return err
+	}
+	for i := range videos {
+		if err := q.deleteVideo(ctx, &videos[i]); err != nil {
+			return err
+		}
+	}
+
+	report("likes", 40)
+	likes, err := q.repos.Likes.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, like := range likes {
+		err := q.repos.Likes.Delete(ctx, userID, like.VideoID)
+		if err == repository.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := q.repos.Videos.AdjustLikes(ctx, like.VideoID, -1); err != nil && err != repository.ErrNotFound {
+			log.Printf("jobs: failed to update like count of video %s: %v", like.VideoID, err)
+		}
+	}
+
+	report("comments", 50)
+	if err := q.repos.Comments.DeleteByOwner(ctx, userID); err != nil {
+		return err
+	}
+
+	report("subscriptions", 60)
+	if err := q.repos.Subscriptions.DeleteBySubscriber(ctx, userID); err != nil {
+		return err
+	}
+	if channelID := job.Payload["channelId"]; channelID != "" {
+		if err := q.repos.Subscriptions.DeleteByChannel(ctx, channelID); err != nil {
+			return err
+		}
+		// The banner goes before the channel that points at it, so a retry still finds it
+		channel, err := q.repos.Channels.FindByID(ctx, channelID)
+		if err != nil && err != repository.ErrNotFound {
+			return err
+		}
+		if channel != nil && channel.Banner != "" {
+			if err := utils.DeleteMedia(ctx, channel.Banner); err != nil && err != utils.ErrMediaNotFound {
+				log.Printf("jobs: failed to delete banner %s of deleted user %s: %v", channel.Banner, userID, err)
+			}
+		}
+		if err := q.repos.Channels.Delete(ctx, channelID); err != nil && err != repository.ErrNotFound {
+			return err
+		}
+	}
+
+	report("playlists and history", 70)
+	if err := q.repos.Playlists.DeleteByUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := q.repos.WatchHistory.DeleteByUser(ctx, userID); err != nil {
+		return err
+	}
+
+	report("sign-in data", 80)
+	if err := q.deleteSignInData(ctx, userID); err != nil {
+		return err
+	}
+
+	report("images", 90)
+	for _, imageURL := range []string{job.Payload["avatar"], job.Payload["coverImage"]} {
+		if imageURL == "" {
+			continue
+		}
+		// Images from elsewhere, like a sign-in provider's avatar, can't be deleted
+		if err := utils.DeleteMedia(ctx, imageURL); err != nil && err != utils.ErrMediaNotFound {
+			log.Printf("jobs: failed to delete image %s of deleted user %s: %v", imageURL, userID, err)
+		}
+	}
+
+	report("account", 95)
+	if err := q.repos.Users.Delete(ctx, userID); err != nil && err != repository.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+// deleteVideo removes one of the deleted user's videos, its media and what
+// other users left on it
+func (q *Queue) deleteVideo(ctx context.Context, video *models.Video) error {
+	for _, fileURL := range []string{video.URL, video.Thumbnail} {
+		if fileURL == "" {
+			continue
+		}
+		if err := utils.DeleteMedia(ctx, fileURL); err != nil && err != utils.ErrMediaNotFound {
+			return err
+		}
+	}
+	if video.PreviewSprite != "" {
+		if err := utils.DeleteMediaPrefix(ctx, utils.PreviewKeyPrefix(video.ID)); err != nil {
+			return err
+		}
+	}
+	if video.HLSURL != "" {
+		if err := utils.DeleteMediaPrefix(ctx, utils.HLSKeyPrefix(video.ID)); err != nil {
+			return err
+		}
+	}
+
+	if err := q.repos.Likes.DeleteByVideo(ctx, video.ID); err != nil {
+		return err
+	}
+	if err := q.repos.Comments.DeleteByVideo(ctx, video.ID); err != nil {
+		return err
+	}
+	if err := q.repos.WatchHistory.DeleteByVideo(ctx, video.ID); err != nil {
+		return err
+	}
+	if err := q.repos.Videos.Delete(ctx, video.ID); err != nil && err != repository.ErrNotFound {
+		return err
+	}
+
+	if err := q.search.Remove(ctx, video.ID); err != nil {
+		log.Printf("jobs: failed to remove video %s from the search index: %v", video.ID, err)
+	}
+	return nil
+}
+
+// deleteSignInData removes the user's sessions, linked identities, API keys,
+// two-factor settings, pending email links and failed login counters
+func (q *Queue) deleteSignInData(ctx context.Context, userID string) error {
+	if err := q.repos.Sessions.DeleteByUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := q.repos.Identities.DeleteByUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := q.repos.APIKeys.DeleteByUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := q.repos.TwoFactor.Delete(ctx, userID); err != nil && err != repository.ErrNotFound {
+		return err
+	}
+	for _, purpose := range []string{models.TokenPurposeVerifyEmail, models.TokenPurposeResetPassword, models.TokenPurposeMFAChallenge} {
+		if err := q.repos.UserTokens.DeleteByUser(ctx, userID, purpose); err != nil {
+			return err
+		}
+	}
+	for _, key := range []string{"user:" + userID, "mfa:" + userID} {
+		if err := q.repos.LoginThrottles.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *Queue) deleteAccountFailed(ctx context.Context, job *models.Job, err error) {
+	log.Printf("jobs: gave up deleting the data of user %s: %v", job.Payload["userId"], err)
+}
@@ -0,0 +1,123 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"yt_backend/mailer"
+	"yt_backend/models"
+	"yt_backend/repository"
+	"yt_backend/utils"
+)
+
+// TypeSendAccountEmail emails a user a verification or password reset link.
+// The payload only names the user and the purpose; the token is created when
+// the mail is sent, so it never sits in the jobs collection.
+const TypeSendAccountEmail = "send_account_email"
+
+// sendTimeout bounds a single delivery attempt
+const sendTimeout = time.Minute
+
+// EnqueueAccountEmail queues a verification (models.TokenPurposeVerifyEmail) or
+// password reset (models.TokenPurposeResetPassword) email for the user
+func (q *Queue) EnqueueAccountEmail(ctx context.Context, userID string, purpose string) error {
+	_, err := q.Enqueue(ctx, TypeSendAccountEmail, "", map[string]string{"userId": userID, "purpose": purpose})
+	return err
+}
+
+func (q *Queue) sendAccountEmail(ctx context.Context, job *models.Job, report func(step string, progress int)) error {
+	user, err := q.repos.Users.FindByID(ctx, job.Payload["userId"])
+	if err == repository.ErrNotFound {
+		// The account is gone; there is no one to send to
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	purpose := job.Payload["purpose"]
+	var ttl time.Duration
+	switch purpose {
+	case models.TokenPurposeVerifyEmail:
+		if user.EmailVerified() {
+			return nil
+		}
+		ttl = utils.EmailVerificationTTL()
+	case models.TokenPurposeResetPassword:
+		ttl = utils.PasswordResetTTL()
+	default:
+		return fmt.Errorf("unknown token purpose %q", purpose)
+	}
+
+	// Only the newest link works; issuing one invalidates any sent before
+	report("issuing token", 20)
+	token, hash, err := utils.NewOneTimeToken()
+	if err != nil {
+		return err
+	}
+	if err := q.repos.UserTokens.DeleteByUser(ctx, user.ID, purpose); err != nil {
+		return err
+	}
+	err = q.repos.UserTokens.Create(ctx, &models.UserToken{
+		ID:        hash,
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	report("sending", 60)
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	return q.mailer.Send(sendCtx, accountEmail(user, purpose, token, ttl))
+}
+
+func (q *Queue) sendAccountEmailFailed(ctx context.Context, job *models.Job, err error) {
+	log.Printf("jobs: gave up emailing %s link to user %s: %v", job.Payload["purpose"], job.Payload["userId"], err)
+}
+
+// accountEmail writes the message carrying the link
+func accountEmail(user *models.User, purpose string, token string, ttl time.Duration) mailer.Message {
+	query := url.Values{"token": {token}}
+	if purpose == models.TokenPurposeVerifyEmail {
+		return mailer.Message{
+			To:      user.Email,
+			Subject: "Confirm your email address",
+			Text: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening this link:\n\n%s\n\n"+
+				"The link expires in %s. If you didn't create an account, you can ignore this email.\n",
+				user.Username, utils.AppURL("/verify-email", query), describeDuration(ttl)),
+		}
+	}
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Text: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. To choose a new one, open this link:\n\n%s\n\n"+
+			"The link expires in %s and can only be used once. If you didn't ask for this, you can ignore this email; "+
+			"your password stays the same.\n",
+			user.Username, utils.AppURL("/reset-password", query), describeDuration(ttl)),
+	}
+}
+
+// describeDuration writes whole hours or minutes the way people read them
+func describeDuration(d time.Duration) string {
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	switch {
+	case d%time.Hour == 0:
+		return plural(int64(d/time.Hour), "hour")
+	case d%time.Minute == 0:
+		return plural(int64(d/time.Minute), "minute")
+	}
+	return d.String()
+}
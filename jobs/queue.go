@@ -0,0 +1,250 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"yt_backend/mailer"
+	"yt_backend/models"
+	"yt_backend/repository"
+	"yt_backend/search"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultMaxAttempts = 3
+	// lease is how long a claim lasts without a heartbeat before another worker
+	// may pick the job up again
+	lease             = 5 * time.Minute
+	heartbeatInterval = time.Minute
+	pollInterval      = 5 * time.Second
+	retryBackoff      = 30 * time.Second
+)
+
+// Handler runs a job. report records the current step and its progress (0-100).
+// Returning an error schedules a retry until the job runs out of attempts.
+type Handler func(ctx context.Context, job *models.Job, report func(step string, progress int)) error
+
+// FailureHandler is called once a job has failed for the last time
+type FailureHandler func(ctx context.Context, job *models.Job, err error)
+
+type registration struct {
+	handler   Handler
+	onFailure FailureHandler
+}
+
+// Queue persists jobs through the job repository and runs them on a worker pool
+type Queue struct {
+	repos    *repository.Repositories
+	search   search.Index
+	mailer   mailer.Mailer
+	handlers map[string]registration
+	wake     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewQueue creates a queue with the built-in job handlers registered
+func NewQueue(repos *repository.Repositories, index search.Index, mail mailer.Mailer) *Queue {
+	q := &Queue{
+		repos:    repos,
+		search:   index,
+		mailer:   mail,
+		handlers: make(map[string]registration),
+		wake:     make(chan struct{}, 1),
+	}
+	q.Register(TypeProcessVideo, q.processVideo, q.processVideoFailed)
+	q.Register(TypeSendAccountEmail, q.sendAccountEmail, q.sendAccountEmailFailed)
+	q.Register(TypeDeleteAccount, q.deleteAccount, q.deleteAccountFailed)
+	return q
+}
+
+// Register adds a handler for a job type
+func (q *Queue) Register(jobType string, handler Handler, onFailure FailureHandler) {
+	q.handlers[jobType] = registration{handler: handler, onFailure: onFailure}
+}
+
+// Enqueue stores a new job and wakes an idle worker
+func (q *Queue) Enqueue(ctx context.Context, jobType string, videoID string, payload map[string]string) (*models.Job, error) {
+	if _, ok := q.handlers[jobType]; !ok {
+		return nil, fmt.Errorf("unknown job type %q", jobType)
+	}
+
+	job := models.Job{
+		ID:          uuid.New().String(),
+		Type:        jobType,
+		VideoID:     videoID,
+		Payload:     payload,
+		Status:      models.JobStatusQueued,
+		Step:        "queued",
+		MaxAttempts: defaultMaxAttempts,
+		RunAt:       time.Now(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := q.repos.Jobs.Create(ctx, &job); err != nil {
+		return nil, err
+	}
+
+	q.notify()
+	return &job, nil
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start launches the workers; they stop when ctx is cancelled
+func (q *Queue) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.work(ctx)
+	}
+}
+
+// Wait blocks until every worker has stopped
+func (q *Queue) Wait() {
+	q.wg.Wait()
+}
+
+func (q *Queue) work(ctx context.Context) {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain every due job before going back to sleep
+		for ctx.Err() == nil {
+			job, err := q.repos.Jobs.ClaimNext(ctx, time.Now(), time.Now().Add(lease))
+			if err == repository.ErrNotFound {
+				break
+			}
+			if err != nil {
+				log.Printf("jobs: failed to claim job: %v", err)
+				break
+			}
+			q.run(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) run(ctx context.Context, job *models.Job) {
+	reg, ok := q.handlers[job.Type]
+	if !ok {
+		q.repos.Jobs.Fail(ctx, job.ID, job.Attempts, fmt.Sprintf("unknown job type %q", job.Type))
+		return
+	}
+
+	// The handler is stopped if the claim is lost, so two workers never keep
+	// processing the same job
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var lost atomic.Bool
+	stopHeartbeat := q.heartbeat(jobCtx, job, func() {
+		lost.Store(true)
+		cancel()
+	})
+
+	report := func(step string, progress int) {
+		if err := q.repos.Jobs.UpdateProgress(ctx, job.ID, job.Attempts, step, progress); err != nil {
+			log.Printf("jobs: failed to record progress for %s: %v", job.ID, err)
+		}
+	}
+
+	err := runHandler(jobCtx, reg.handler, job, report)
+	stopHeartbeat()
+	if lost.Load() {
+		log.Printf("jobs: %s %s lost its claim to another worker", job.Type, job.ID)
+		return
+	}
+	if err == nil {
+		if err := q.repos.Jobs.Complete(ctx, job.ID, job.Attempts); err != nil {
+			log.Printf("jobs: failed to complete %s: %v", job.ID, err)
+		}
+		return
+	}
+
+	// Leave the job running when shutting down so it is picked up again after its lease
+	if ctx.Err() != nil {
+		return
+	}
+
+	if job.Attempts < job.MaxAttempts {
+		// Exponential backoff: 30s, 60s, 120s, ...
+		delay := retryBackoff * time.Duration(math.Pow(2, float64(job.Attempts-1)))
+		log.Printf("jobs: %s %s failed (attempt %d/%d), retrying in %s: %v", job.Type, job.ID, job.Attempts, job.MaxAttempts, delay, err)
+		if err := q.repos.Jobs.Retry(ctx, job.ID, job.Attempts, err.Error(), time.Now().Add(delay)); err != nil {
+			log.Printf("jobs: failed to schedule a retry of %s: %v", job.ID, err)
+		}
+		return
+	}
+
+	log.Printf("jobs: %s %s failed permanently: %v", job.Type, job.ID, err)
+	if err := q.repos.Jobs.Fail(ctx, job.ID, job.Attempts, err.Error()); err != nil {
+		// Another worker holds the job now, so it decides how the job ends
+		log.Printf("jobs: failed to mark %s as failed: %v", job.ID, err)
+		return
+	}
+	if reg.onFailure != nil {
+		reg.onFailure(ctx, job, err)
+	}
+}
+
+// heartbeat extends the job's lease until the returned stop function is
+// called. onLost runs once if another worker has claimed the job meanwhile.
+func (q *Queue) heartbeat(ctx context.Context, job *models.Job, onLost func()) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			err := q.repos.Jobs.Extend(ctx, job.ID, job.Attempts, time.Now().Add(lease))
+			if err == repository.ErrNotFound {
+				onLost()
+				return
+			}
+			if err != nil {
+				log.Printf("jobs: failed to extend the lease of %s: %v", job.ID, err)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// runHandler turns a panicking handler into a failed attempt
+func runHandler(ctx context.Context, handler Handler, job *models.Job, report func(string, int)) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job, report)
+}
@@ -0,0 +1,328 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+
+	"yt_backend/models"
+	"yt_backend/repository"
+	"yt_backend/search"
+	"yt_backend/utils"
+)
+
+// TypeProcessVideo turns an uploaded source file into a playable video
+const TypeProcessVideo = "process_video"
+
+// transcodeEnabled reports whether uploads are re-encoded; set VIDEO_TRANSCODE=false to store them as uploaded
+func transcodeEnabled() bool {
+	return os.Getenv("VIDEO_TRANSCODE") != "false"
+}
+
+// hlsEnabled reports whether HLS renditions are produced; set VIDEO_HLS=false to skip them
+func hlsEnabled() bool {
+	return os.Getenv("VIDEO_HLS") != "false"
+}
+
+// processVideo probes the source file, transcodes it to MP4 and HLS, extracts a
+// poster frame and the hover preview sprite, and moves the results to media
+// storage before marking the video ready
+func (q *Queue) processVideo(ctx context.Context, job *models.Job, report func(step string, progress int)) error {
+	video, err := q.repos.Videos.FindByID(ctx, job.VideoID)
+	if err == repository.ErrNotFound {
+		// The video was deleted while it was waiting; nothing left to do
+		discardSource(ctx, job)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	workDir, err := os.MkdirTemp("", "process-"+video.ID+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create work directory: %v", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	report("download", 2)
+	source, err := fetchSource(ctx, job, workDir)
+	if err != nil {
+		return err
+	}
+
+	// Probe metadata
+	report("probe", 5)
+	probe, err := utils.ProbeVideo(ctx, source)
+	if err != nil {
+		return err
+	}
+	if err := q.repos.Videos.UpdateProbe(ctx, video.ID, probe.Duration(), probe.DurationSeconds, probe.Width, probe.Height); err != nil {
+		return err
+	}
+
+	// The encoding steps share the 10-70% progress range
+	encodes := 0
+	if transcodeEnabled() {
+		encodes++
+	}
+	if hlsEnabled() {
+		encodes++
+	}
+	progressFrom := 10
+	encodeProgress := func(step string) func(float64) {
+		from := progressFrom
+		span := 60 / encodes
+		progressFrom += span
+		report(step, from)
+		last := from
+		return func(done float64) {
+			if progress := from + int(done*float64(span)); progress != last {
+				last = progress
+				report(step, progress)
+			}
+		}
+	}
+
+	// Transcode to a web friendly MP4
+	playable := source
+	if transcodeEnabled() {
+		playable = filepath.Join(workDir, "video.mp4")
+		if err := utils.TranscodeToMP4(ctx, source, playable, probe.DurationSeconds, encodeProgress("transcode")); err != nil {
+			return err
+		}
+	}
+
+	// Encode the adaptive bitrate ladder
+	hlsDir := filepath.Join(workDir, "hls")
+	ladder := utils.HLSLadder(probe.Width, probe.Height)
+	if hlsEnabled() {
+		if err := utils.TranscodeToHLS(ctx, source, hlsDir, ladder, probe.HasAudio, probe.DurationSeconds, encodeProgress("hls")); err != nil {
+			return err
+		}
+	}
+
+	// Extract a poster frame
+	report("thumbnail", 75)
+	poster := filepath.Join(workDir, "poster.jpg")
+	if err := utils.ExtractFrame(ctx, source, poster, posterOffset(probe.DurationSeconds)); err != nil {
+		return err
+	}
+
+	// Render the hover-scrub sprite sheet
+	report("previews", 80)
+	sheet := utils.NewSpriteSheet(probe.DurationSeconds)
+	sprite := filepath.Join(workDir, "sprite.jpg")
+	if err := utils.GenerateSpriteSheet(ctx, source, sprite, sheet); err != nil {
+		return err
+	}
+
+	// Move the results to media storage; keys are derived from the video ID so retries overwrite
+	report("store", 85)
+
+	// Keep a thumbnail the owner uploaded while the video was processing
+	thumbnailURL := ""
+	if current, err := q.repos.Videos.FindByID(ctx, video.ID); err == nil && current.CustomThumbnail {
+		thumbnailURL = current.Thumbnail
+	} else {
+		thumbnailURL, err = utils.PutFile(ctx, "thumbnails/"+video.ID+".jpg", poster, utils.PutOptions{
+			ResourceType: "image",
+			ContentType:  "image/jpeg",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	spriteURL, vttURL, err := storePreview(ctx, video.ID, workDir, sprite, sheet)
+	if err != nil {
+		return err
+	}
+	if err := q.repos.Videos.SetPreview(ctx, video.ID, spriteURL, vttURL); err != nil && err != repository.ErrNotFound {
+		return err
+	}
+	videoURL, err := utils.PutFile(ctx, "videos/"+video.ID+filepath.Ext(playable), playable, utils.PutOptions{
+		ResourceType: "video",
+		ContentType:  "video/mp4",
+	})
+	if err != nil {
+		return err
+	}
+
+	if hlsEnabled() {
+		masterURL, renditions, err := storeHLS(ctx, video.ID, hlsDir, ladder)
+		if err != nil {
+			return err
+		}
+		if err := q.repos.Videos.SetHLS(ctx, video.ID, masterURL, renditions); err != nil && err != repository.ErrNotFound {
+			return err
+		}
+	}
+
+	err = q.repos.Videos.MarkReady(ctx, video.ID, videoURL, thumbnailURL)
+	if err == repository.ErrNotFound {
+		// Deleted while processing, so clean up what we just stored
+		utils.DeleteMedia(ctx, videoURL)
+		utils.DeleteMedia(ctx, thumbnailURL)
+		utils.DeleteMediaPrefix(ctx, utils.HLSKeyPrefix(video.ID))
+		utils.DeleteMediaPrefix(ctx, utils.PreviewKeyPrefix(video.ID))
+	} else if err != nil {
+		return err
+	}
+
+	// The video is ready, so it can now be found by search
+	if ready, err := q.repos.Videos.FindByID(ctx, video.ID); err == nil {
+		if err := search.Sync(ctx, q.search, ready); err != nil {
+			log.Printf("jobs: failed to index video %s: %v", video.ID, err)
+		}
+	}
+
+	discardSource(ctx, job)
+	return nil
+}
+
+// fetchSource copies the uploaded source from the media store into workDir.
+// Jobs queued before sources went to the media store name a local file instead.
+func fetchSource(ctx context.Context, job *models.Job, workDir string) (string, error) {
+	sourceURL := job.Payload["sourceUrl"]
+	if sourceURL == "" {
+		return job.Payload["source"], nil
+	}
+
+	store, err := utils.GetMediaStore()
+	if err != nil {
+		return "", err
+	}
+	src, err := store.Open(ctx, sourceURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to open source: %v", err)
+	}
+	defer src.Close()
+
+	source := filepath.Join(workDir, "source"+path.Ext(sourceURL))
+	dst, err := os.Create(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to create source file: %v", err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return "", fmt.Errorf("failed to download source: %v", err)
+	}
+	if err := dst.Close(); err != nil {
+		return "", fmt.Errorf("failed to download source: %v", err)
+	}
+	return source, nil
+}
+
+// discardSource deletes the uploaded source once it is no longer needed
+func discardSource(ctx context.Context, job *models.Job) {
+	if sourceURL := job.Payload["sourceUrl"]; sourceURL != "" {
+		if err := utils.DeleteMedia(ctx, sourceURL); err != nil && err != utils.ErrMediaNotFound {
+			log.Printf("jobs: failed to delete source of video %s: %v", job.VideoID, err)
+		}
+		return
+	}
+	os.Remove(job.Payload["source"])
+}
+
+// storeHLS uploads every playlist and segment under hls/<videoId>/, keeping the
+// directory layout so the relative references in the playlists stay valid
+func storeHLS(ctx context.Context, videoID string, hlsDir string, ladder []utils.HLSRendition) (string, []models.Rendition, error) {
+	urls := make(map[string]string)
+	err := filepath.WalkDir(hlsDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(hlsDir, filePath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		fileURL, err := utils.PutFile(ctx, utils.HLSKeyPrefix(videoID)+rel, filePath, utils.PutOptions{
+			ResourceType: "raw",
+			ContentType:  utils.HLSContentType(rel),
+		})
+		if err != nil {
+			return err
+		}
+		urls[rel] = fileURL
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	masterURL, ok := urls[utils.HLSMasterPlaylist]
+	if !ok {
+		return "", nil, fmt.Errorf("ffmpeg did not write %s", utils.HLSMasterPlaylist)
+	}
+
+	renditions := make([]models.Rendition, 0, len(ladder))
+	for _, rendition := range ladder {
+		playlistURL, ok := urls[rendition.Name+"/"+utils.HLSVariantPlaylist]
+		if !ok {
+			return "", nil, fmt.Errorf("ffmpeg did not write the %s playlist", rendition.Name)
+		}
+		renditions = append(renditions, models.Rendition{
+			Name:      rendition.Name,
+			Width:     rendition.Width,
+			Height:    rendition.Height,
+			Bandwidth: rendition.Bandwidth(),
+			Playlist:  playlistURL,
+		})
+	}
+	return masterURL, renditions, nil
+}
+
+// storePreview uploads the sprite sheet and then the WebVTT track that points into it
+func storePreview(ctx context.Context, videoID string, workDir string, sprite string, sheet utils.SpriteSheet) (string, string, error) {
+	spriteURL, err := utils.PutFile(ctx, utils.PreviewKeyPrefix(videoID)+"sprite.jpg", sprite, utils.PutOptions{
+		ResourceType: "image",
+		ContentType:  "image/jpeg",
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	vttPath := filepath.Join(workDir, "thumbnails.vtt")
+	vttFile, err := os.Create(vttPath)
+	if err != nil {
+		return "", "", err
+	}
+	if err := utils.WriteThumbnailVTT(vttFile, sheet, spriteURL); err != nil {
+		vttFile.Close()
+		return "", "", err
+	}
+	if err := vttFile.Close(); err != nil {
+		return "", "", err
+	}
+
+	vttURL, err := utils.PutFile(ctx, utils.PreviewKeyPrefix(videoID)+"thumbnails.vtt", vttPath, utils.PutOptions{
+		ResourceType: "raw",
+		ContentType:  "text/vtt",
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return spriteURL, vttURL, nil
+}
+
+// processVideoFailed marks the video as failed once every attempt has been used up
+func (q *Queue) processVideoFailed(ctx context.Context, job *models.Job, err error) {
+	if err := q.repos.Videos.SetStatus(ctx, job.VideoID, models.VideoStatusFailed); err != nil && err != repository.ErrNotFound {
+		log.Printf("jobs: failed to mark video %s as failed: %v", job.VideoID, err)
+	}
+	discardSource(ctx, job)
+}
+
+// posterOffset picks the poster frame 10% into the video, past most fade-ins and
+// title cards, but never more than 30 seconds in
+func posterOffset(durationSeconds float64) float64 {
+	return math.Min(durationSeconds*0.1, 30)
+}
@@ -0,0 +1,145 @@
+package mailer
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readMessages parses every message the file mailer wrote to dir
+func readMessages(t *testing.T, dir string) map[string]*mail.Message {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	messages := make(map[string]*mail.Message)
+	for _, entry := range entries {
+		file, err := os.Open(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		t.Cleanup(func() { file.Close() })
+		msg, err := mail.ReadMessage(file)
+		if err != nil {
+			t.Fatalf("%s is not a valid message: %v", entry.Name(), err)
+		}
+		messages[entry.Name()] = msg
+	}
+	return messages
+}
+
+func TestFileMailerWritesReadableMessages(t *testing.T) {
+	t.Setenv("MAIL_FROM", "Videos <no-reply@videos.example>")
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer, err := NewFileMailer(dir)
+	if err != nil {
+		t.Fatalf("NewFileMailer: %v", err)
+	}
+
+	link := "https://videos.example/verify-email?token=" + strings.Repeat("a1", 40)
+	err = mailer.Send(context.Background(), Message{
+		To:      "alice@example.com",
+		Subject: "Bestätige deine E-Mail",
+		Text:    "Hi alice,\n\nOpen this link:\n" + link + "\n",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	messages := readMessages(t, dir)
+	if len(messages) != 1 {
+		t.Fatalf("wrote %d messages, want 1", len(messages))
+	}
+	for name, msg := range messages {
+		if !strings.HasSuffix(name, "-alice@example.com.eml") {
+			t.Errorf("file name = %q, want it to end with the recipient", name)
+		}
+		if got := msg.Header.Get("To"); got != "alice@example.com" {
+			t.Errorf("To = %q", got)
+		}
+		if got := msg.Header.Get("From"); got != "Videos <no-reply@videos.example>" {
+			t.Errorf("From = %q", got)
+		}
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		if err != nil || subject != "Bestätige deine E-Mail" {
+			t.Errorf("Subject = %q (%v)", subject, err)
+		}
+		if !strings.HasSuffix(msg.Header.Get("Message-ID"), "@videos.example>") {
+			t.Errorf("Message-ID = %q, want the sender's domain", msg.Header.Get("Message-ID"))
+		}
+
+		// Long lines are wrapped by the encoding, so the link only survives decoding
+		body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+		if err != nil {
+			t.Fatalf("decoding body: %v", err)
+		}
+		if !strings.Contains(string(body), link+"\r\n") {
+			t.Errorf("body does not contain the link:\n%s", body)
+		}
+	}
+}
+
+func TestFileMailerRejectsHeaderInjection(t *testing.T) {
+	dir := t.TempDir()
+	mailer, err := NewFileMailer(dir)
+	if err != nil {
+		t.Fatalf("NewFileMailer: %v", err)
+	}
+
+	for _, msg := range []Message{
+		{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "Hi", Text: "x"},
+		{To: "alice@example.com", Subject: "Hi\nBcc: eve@example.com", Text: "x"},
+	} {
+		if err := mailer.Send(context.Background(), msg); err == nil {
+			t.Errorf("Send(%q, %q) succeeded, want an error", msg.To, msg.Subject)
+		}
+	}
+	if messages := readMessages(t, dir); len(messages) != 0 {
+		t.Errorf("wrote %d messages for rejected input", len(messages))
+	}
+}
+
+func TestFileMailerKeepsFileNamesInsideDir(t *testing.T) {
+	dir := t.TempDir()
+	mailer, err := NewFileMailer(dir)
+	if err != nil {
+		t.Fatalf("NewFileMailer: %v", err)
+	}
+
+	if err := mailer.Send(context.Background(), Message{To: "../../evil/x@example.com", Subject: "Hi", Text: "x"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	messages := readMessages(t, dir)
+	if len(messages) != 1 {
+		t.Fatalf("wrote %d messages inside the mail directory, want 1", len(messages))
+	}
+	for name := range messages {
+		if !strings.HasSuffix(name, "-.._.._evil_x@example.com.eml") {
+			t.Errorf("file name = %q, want the separators replaced", name)
+		}
+	}
+}
+
+func TestFromEnvPicksTheDriver(t *testing.T) {
+	t.Setenv("MAIL_DRIVER", "")
+	t.Setenv("MAIL_DIR", filepath.Join(t.TempDir(), "out"))
+	mailer, err := FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv: %v", err)
+	}
+	if _, ok := mailer.(*FileMailer); !ok {
+		t.Errorf("default mailer is %T, want *FileMailer", mailer)
+	}
+
+	t.Setenv("MAIL_DRIVER", "carrier-pigeon")
+	if _, err := FromEnv(); err == nil {
+		t.Error("FromEnv accepted an unknown driver")
+	}
+}
@@ -1,16 +1,92 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"yt_backend/controllers"
 	"yt_backend/db"
+	"yt_backend/jobs"
+	"yt_backend/mailer"
+	"yt_backend/middleware"
+	"yt_backend/migrations"
+	"yt_backend/models"
+	"yt_backend/oidc"
+	"yt_backend/repository"
 	"yt_backend/routes"
+	"yt_backend/search"
+	"yt_backend/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
 )
 
 func main() {
-	db.ConnectDB()
+	// Load environment variables from .env file
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, reading configuration from the environment")
+	}
+
+	// "migrate up|down|status" manages the database schema version
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrations(os.Args[2:])
+		return
+	}
+
+	// "keygen [rsa|ed25519] [file]" writes a new token signing key
+	if len(os.Args) > 1 && os.Args[1] == "keygen" {
+		generateSigningKey(os.Args[2:])
+		return
+	}
+
+	// "set-role <username> <role>" grants a role, e.g. to create the first admin
+	if len(os.Args) > 1 && os.Args[1] == "set-role" {
+		setUserRole(os.Args[2:])
+		return
+	}
+
+	// "mock-oidc [addr]" runs a fake OpenID provider for trying out social login locally
+	if len(os.Args) > 1 && os.Args[1] == "mock-oidc" {
+		runMockOIDC(os.Args[2:])
+		return
+	}
+
+	// Refuse to start without a key to sign access tokens with
+	if err := utils.LoadSigningKeys(); err != nil {
+		log.Fatal(err)
+	}
+
+	// OIDC_PROVIDERS lists the providers users can sign in with
+	providers, err := oidc.ProvidersFromEnv(utils.AppURL("", nil))
+	if err != nil {
+		log.Fatal(err)
+	}
+	controllers.SetOIDCProviders(providers)
+
+	repos, index := newRepositories()
+	controllers.SetRepositories(repos)
+	controllers.SetSearchIndex(index)
+	middleware.SetRepositories(repos)
+
+	// MAIL_DRIVER picks how account emails are delivered
+	mail, err := mailer.FromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Start the background workers that process uploaded videos and send email
+	queue := jobs.NewQueue(repos, index, mail)
+	controllers.SetJobQueue(queue)
+	queue.Start(context.Background(), videoWorkers())
+	controllers.StartUploadSweeper(context.Background())
 
 	router := gin.Default()
 
@@ -21,12 +97,228 @@ func main() {
 	})
 
 	routes.UserRoutes(router)
+	routes.ChannelRoutes(router)
 	routes.VideoRoutes(router)
 	routes.SubscriptionRoutes(router)
 	routes.VideoCommentRoutes(router)
 	routes.LikeRoutes(router)
 	routes.SetupWatchHistoryRoutes(router)
 	routes.PlaylistRoutes(router)
+	routes.UploadRoutes(router)
+	routes.MediaRoutes(router)
+	routes.SearchRoutes(router)
+	routes.WellKnownRoutes(router)
+	routes.AdminRoutes(router)
+	routes.OIDCRoutes(router)
 
 	router.Run(":8080") // "localhost:8080"
 }
+
+// newRepositories picks the storage backend and search index from DB_DRIVER ("mongo" or "memory")
+func newRepositories() (*repository.Repositories, search.Index) {
+	if os.Getenv("DB_DRIVER") == "memory" {
+		log.Println("Using in-memory repositories")
+		return repository.NewMemoryRepositories(), search.NewMemoryIndex()
+	}
+
+	db.ConnectDB()
+
+	// Set MIGRATE_ON_START=true to bring the schema up to date before serving
+	if os.Getenv("MIGRATE_ON_START") == "true" {
+		runner, err := migrations.NewRunner(db.Database(), migrations.All)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// Another instance starting at the same time may hold the lock; wait for it to finish
+		done, err := runner.Up(context.Background(), 0)
+		for err == migrations.ErrLocked {
+			log.Println("Waiting for another instance to finish migrating")
+			time.Sleep(5 * time.Second)
+			done, err = runner.Up(context.Background(), 0)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, migration := range done {
+			log.Printf("Applied migration %d %s", migration.Version, migration.Name)
+		}
+	}
+
+	if err := db.EnsureIndexes(context.Background(), db.Database()); err != nil {
+		log.Fatal(err)
+	}
+
+	repos := repository.NewMongoRepositories(db.Database())
+	index, err := search.NewMongoIndex(context.Background(), db.Database())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Set SEARCH_REINDEX=true once to index videos uploaded before search existed
+	if os.Getenv("SEARCH_REINDEX") == "true" {
+		go func() {
+			if err := search.Reindex(context.Background(), index, repos.Videos); err != nil {
+				log.Printf("search: reindex failed: %v", err)
+			}
+		}()
+	}
+	return repos, index
+}
+
+// runMigrations handles "migrate up [version]", "migrate down [steps]" and "migrate status"
+func runMigrations(args []string) {
+	db.ConnectDB()
+	runner, err := migrations.NewRunner(db.Database(), migrations.All)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+
+	command := "status"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	// The optional argument is the target version for up and the number of steps for down
+	number := 0
+	if len(args) > 1 {
+		if number, err = strconv.Atoi(args[1]); err != nil || number < 0 {
+			log.Fatalf("invalid number %q", args[1])
+		}
+	}
+
+	switch command {
+	case "up":
+		done, err := runner.Up(ctx, number)
+		for _, migration := range done {
+			log.Printf("applied %d %s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(done) == 0 {
+			log.Println("database is up to date")
+		}
+	case "down":
+		if number == 0 {
+			number = 1
+		}
+		done, err := runner.Down(ctx, number)
+		for _, migration := range done {
+			log.Printf("rolled back %d %s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d  %-30s %s\n", status.Version, status.Name, applied)
+		}
+	default:
+		log.Fatalf("unknown migrate command %q; use up, down or status", command)
+	}
+}
+
+// generateSigningKey writes a new private key for signing access tokens. Point
+// JWT_SIGNING_KEY at it, and keep the previous key in JWT_VERIFY_KEYS until
+// the tokens it signed have expired.
+func generateSigningKey(args []string) {
+	kind, path := "ed25519", "jwt-signing-key.pem"
+	if len(args) > 0 {
+		kind = args[0]
+	}
+	if len(args) > 1 {
+		path = args[1]
+	}
+
+	data, err := utils.GenerateSigningKey(kind)
+	if err != nil {
+		log.Fatal(err)
+	}
+	key, err := utils.ParseSigningKey(data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// O_EXCL so an existing key is never overwritten
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := file.Write(data); err != nil {
+		log.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("wrote %s key %s to %s\n", key.Method.Alg(), key.ID, path)
+}
+
+// setUserRole changes a user's role from the command line. The admin API can
+// only be used by an existing admin, so this is how the first one is made.
+func setUserRole(args []string) {
+	if len(args) != 2 || !slices.Contains(models.Roles, args[1]) {
+		log.Fatalf("usage: set-role <username> <%s>", strings.Join(models.Roles, "|"))
+	}
+	db.ConnectDB()
+	repos := repository.NewMongoRepositories(db.Database())
+	ctx := context.Background()
+
+	user, err := repos.Users.FindByUsername(ctx, args[0])
+	if err != nil {
+		log.Fatalf("failed to find user %q: %v", args[0], err)
+	}
+	if err := repos.Users.SetRole(ctx, user.ID, args[1]); err != nil {
+		log.Fatal(err)
+	}
+	err = repos.AuditLogs.Record(ctx, &models.AuditLog{
+		ID:         uuid.New().String(),
+		ActorRole:  "cli",
+		Action:     models.AuditUserRoleChanged,
+		TargetType: "user",
+		TargetID:   user.ID,
+		Details:    map[string]string{"username": user.Username, "from": user.Role, "to": args[1]},
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		log.Printf("failed to record audit entry: %v", err)
+	}
+	fmt.Printf("%s is now %s\n", user.Username, args[1])
+}
+
+// runMockOIDC serves a mock OpenID provider. Point a provider at it with e.g.
+// OIDC_PROVIDERS=mock OIDC_MOCK_ISSUER=http://localhost:9000 OIDC_MOCK_CLIENT_ID=dev;
+// MOCK_OIDC_ISSUER changes the issuer it announces.
+func runMockOIDC(args []string) {
+	addr := ":9000"
+	if len(args) > 0 {
+		addr = args[0]
+	}
+	issuer := os.Getenv("MOCK_OIDC_ISSUER")
+	if issuer == "" {
+		issuer = "http://" + addr
+		if strings.HasPrefix(addr, ":") {
+			issuer = "http://localhost" + addr
+		}
+	}
+	provider, err := oidc.NewMockProvider(issuer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("mock OpenID provider %s listening on %s", issuer, addr)
+	log.Fatal(http.ListenAndServe(addr, provider))
+}
+
+// videoWorkers reads the number of processing workers from VIDEO_WORKERS
+func videoWorkers() int {
+	if workers, err := strconv.Atoi(os.Getenv("VIDEO_WORKERS")); err == nil && workers > 0 {
+		return workers
+	}
+	return 2
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"time"
+
+	"yt_backend/repository"
+	"yt_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// lastUsedInterval limits how often a key's last use is written, so busy
+// scripts don't cost a database write per request
+const lastUsedInterval = time.Minute
+
+// authenticateAPIKey checks an API key sent as a bearer token and that it was
+// granted every one of scopes. On success it sets user_id and api_key_id and
+// reports true; otherwise it has already aborted the request.
+func authenticateAPIKey(c *gin.Context, token string, scopes []string) bool {
+	// Routes declare the scopes that let API keys in; without any they are for people only
+	if len(scopes) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys can't be used for this endpoint"})
+		c.Abort()
+		return false
+	}
+
+	prefix, secret, ok := utils.ParseAPIKey(token)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		c.Abort()
+		return false
+	}
+	ctx := c.Request.Context()
+	key, err := repos.APIKeys.FindByPrefix(ctx, prefix)
+	if err != nil && err != repository.ErrNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		c.Abort()
+		return false
+	}
+	if err == repository.ErrNotFound || subtle.ConstantTimeCompare([]byte(utils.HashOneTimeToken(secret)), []byte(key.SecretHash)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		c.Abort()
+		return false
+	}
+	now := time.Now()
+	if key.Expired(now) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API key has expired"})
+		c.Abort()
+		return false
+	}
+	if !key.HasScopes(scopes...) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key is missing a required scope", "requiredScopes": scopes})
+		c.Abort()
+		return false
+	}
+
+	// Keys act for their owner, so they stop working while the owner is suspended
+	user, err := repos.Users.FindByID(ctx, key.UserID)
+	if err != nil || user.Suspended() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		c.Abort()
+		return false
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedInterval {
+		if err := repos.APIKeys.TouchLastUsed(ctx, key.ID, now, c.ClientIP()); err != nil {
+			log.Printf("Failed to record use of API key %s: %v", key.ID, err)
+		}
+	}
+
+	c.Set("user_id", key.UserID)
+	c.Set("api_key_id", key.ID)
+	return true
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"yt_backend/repository"
+	"yt_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireFreshMFA guards sensitive operations: users with two-factor enabled
+// must have entered a code on this session within utils.FreshMFAWindow, at
+// login or through POST /users/2fa/verify. Users without two-factor, and API
+// keys, pass. It must run after AuthMiddleware.
+func RequireFreshMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// A key stands in for the second factor; creating one needs a fresh one
+		if c.GetString("api_key_id") != "" {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		twoFactor, err := repos.TwoFactor.FindByUser(ctx, c.GetString("user_id"))
+		if err == repository.ErrNotFound || (err == nil && !twoFactor.Enabled()) {
+			c.Next()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			c.Abort()
+			return
+		}
+
+		session, err := repos.Sessions.FindByID(ctx, c.GetString("session_id"))
+		if err != nil && err != repository.ErrNotFound {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			c.Abort()
+			return
+		}
+		if err == repository.ErrNotFound || session.MFAVerifiedAt == nil || time.Since(*session.MFAVerifiedAt) > utils.FreshMFAWindow() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Enter your two-factor code to continue", "mfaRequired": true})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
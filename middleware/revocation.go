@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"yt_backend/repository"
+	"yt_backend/utils"
+)
+
+// revocationCacheSize bounds the number of remembered lookups
+const revocationCacheSize = 100000
+
+// revocationCacheTTL is how long a token or session found to be valid is trusted
+// without asking the database again, from REVOCATION_CACHE_TTL (default 30s).
+// Revocations made by this process apply immediately; revocations made by other
+// instances apply within this window.
+func revocationCacheTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("REVOCATION_CACHE_TTL")); err == nil && ttl >= 0 {
+		return ttl
+	}
+	return 30 * time.Second
+}
+
+type revocationEntry struct {
+	revoked bool
+	until   time.Time
+}
+
+// revocationCache remembers whether token IDs and sessions are revoked. Revoked
+// results are kept until the token would have expired, since they never change back.
+type revocationCache struct {
+	mu      sync.Mutex
+	entries map[string]revocationEntry
+}
+
+var revocations = &revocationCache{entries: make(map[string]revocationEntry)}
+
+func (c *revocationCache) get(key string, now time.Time) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.until) {
+		return false, false
+	}
+	return entry.revoked, true
+}
+
+func (c *revocationCache) set(key string, revoked bool, until time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= revocationCacheSize {
+		// Make room by dropping what has expired, or everything if nothing has
+		now := time.Now()
+		for k, entry := range c.entries {
+			if !now.Before(entry.until) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= revocationCacheSize {
+			c.entries = make(map[string]revocationEntry)
+		}
+	}
+	c.entries[key] = revocationEntry{revoked: revoked, until: until}
+}
+
+// NoteRevokedToken tells this process's cache that a token was just revoked
+func NoteRevokedToken(tokenID string, expiresAt time.Time) {
+	revocations.set("jti:"+tokenID, true, expiresAt)
+}
+
+// NoteRevokedSession tells this process's cache that a session was just revoked
+func NoteRevokedSession(sessionID string) {
+	revocations.set("sid:"+sessionID, true, time.Now().Add(utils.AccessTokenTTL()))
+}
+
+// tokenRevoked reports whether the token was revoked, either directly by its jti
+// or because its session ended
+func tokenRevoked(ctx context.Context, claims *utils.Claims) (bool, error) {
+	now := time.Now()
+	expiresAt := now.Add(utils.AccessTokenTTL())
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	revoked, err := cachedLookup("jti:"+claims.ID, now, expiresAt, func() (bool, error) {
+		return repos.TokenBlacklist.Exists(ctx, claims.ID)
+	})
+	if err != nil || revoked || claims.SessionID == "" {
+		return revoked, err
+	}
+
+	return cachedLookup("sid:"+claims.SessionID, now, expiresAt, func() (bool, error) {
+		session, err := repos.Sessions.FindByID(ctx, claims.SessionID)
+		if err == repository.ErrNotFound {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return !session.Active(now), nil
+	})
+}
+
+// cachedLookup answers from the cache when it can and remembers what lookup says otherwise
+func cachedLookup(key string, now time.Time, expiresAt time.Time, lookup func() (bool, error)) (bool, error) {
+	if revoked, ok := revocations.get(key, now); ok {
+		return revoked, nil
+	}
+
+	revoked, err := lookup()
+	if err != nil {
+		return false, err
+	}
+	if revoked {
+		revocations.set(key, true, expiresAt)
+	} else if ttl := revocationCacheTTL(); ttl > 0 {
+		revocations.set(key, false, now.Add(ttl))
+	}
+	return revoked, nil
+}
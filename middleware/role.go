@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+
+	"yt_backend/models"
+	"yt_backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole lets the request through only if the authenticated user's role is
+// at least role (see models.Roles) and the account isn't suspended. It must run
+// after AuthMiddleware. The role is read from the database on every request so
+// a demotion applies at once rather than when the access token expires.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := repos.Users.FindByID(c.Request.Context(), c.GetString("user_id"))
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			c.Abort()
+			return
+		}
+
+		if user.Suspended() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended"})
+			c.Abort()
+			return
+		}
+		if !user.HasRole(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to do this"})
+			c.Abort()
+			return
+		}
+
+		// Accounts created before roles existed count as plain users
+		if user.Role == "" {
+			user.Role = models.RoleUser
+		}
+		c.Set("user_role", user.Role)
+		c.Next()
+	}
+}
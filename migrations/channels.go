@@ -0,0 +1,124 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// channelOwners records on each channel the user whose account points at it
+// and gives it a handle made from its name, then refreshes the copy of the
+// channel kept on the user. Channels no account points at keep no owner and
+// are logged.
+var channelOwners = Migration{
+	Version: 8,
+	Name:    "channel_owners",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		users := db.Collection("users")
+		channels := db.Collection("channels")
+		cursor, err := users.Find(ctx, bson.M{"channelName._id": bson.M{"$nin": bson.A{"", nil}}})
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var user struct {
+				ID      string `bson:"_id"`
+				Channel struct {
+					ID string `bson:"_id"`
+				} `bson:"channelName"`
+			}
+			if err := cursor.Decode(&user); err != nil {
+				return err
+			}
+
+			var channel bson.M
+			err := channels.FindOne(ctx, bson.M{"_id": user.Channel.ID}).Decode(&channel)
+			if err == mongo.ErrNoDocuments {
+				log.Printf("migrations: channel %s of user %s doesn't exist", user.Channel.ID, user.ID)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			set := bson.M{}
+			if owner, _ := channel["ownerId"].(string); owner == "" {
+				set["ownerId"] = user.ID
+			}
+			if handle, _ := channel["handle"].(string); handle == "" {
+				name, _ := channel["channelName"].(string)
+				handle, err := freeHandle(ctx, channels, name, user.Channel.ID)
+				if err != nil {
+					return err
+				}
+				set["handle"] = handle
+			}
+			if len(set) > 0 {
+				if _, err := channels.UpdateOne(ctx, bson.M{"_id": user.Channel.ID}, bson.M{"$set": set}); err != nil {
+					return err
+				}
+			}
+			for field, value := range set {
+				channel[field] = value
+			}
+			if _, err := users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"channelName": channel}}); err != nil {
+				return err
+			}
+		}
+		if err := cursor.Err(); err != nil {
+			return err
+		}
+
+		orphans, err := channels.CountDocuments(ctx, bson.M{"ownerId": bson.M{"$exists": false}})
+		if err != nil {
+			return err
+		}
+		if orphans > 0 {
+			log.Printf("migrations: %d channels belong to no account and were left without an owner", orphans)
+		}
+		return nil
+	},
+	// Older code ignores the owner and handle, so they can stay
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		return nil
+	},
+}
+
+var handleUnsafe = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// freeHandle makes a handle from a channel name, adding part of the channel
+// ID when another channel already has it. Checked here rather than left to
+// the unique index, which may not exist yet.
+func freeHandle(ctx context.Context, channels *mongo.Collection, name string, channelID string) (string, error) {
+	base := strings.Trim(handleUnsafe.ReplaceAllString(strings.ToLower(name), "-"), "-._")
+	if base == "" {
+		base = "channel"
+	}
+	if len(base) > 21 {
+		base = base[:21]
+	}
+	for len(base) < 3 {
+		base += "0"
+	}
+	suffix := strings.ReplaceAll(channelID, "-", "")
+	if len(suffix) > 8 {
+		suffix = suffix[:8]
+	}
+	for _, handle := range []string{base, base + "-" + suffix} {
+		taken, err := channels.CountDocuments(ctx, bson.M{"handle": handle, "_id": bson.M{"$ne": channelID}})
+		if err != nil {
+			return "", err
+		}
+		if taken == 0 {
+			return handle, nil
+		}
+	}
+	return "", fmt.Errorf("no free handle for channel %s", channelID)
+}
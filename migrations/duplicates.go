@@ -0,0 +1,78 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// removeDuplicates deletes the duplicate likes and subscriptions that racing
+// requests could create before the unique indexes existed, keeping the oldest
+// of each. The like counter of affected videos is recounted.
+var removeDuplicates = Migration{
+	Version: 3,
+	Name:    "remove_duplicates",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		likes, err := deleteDuplicates(ctx, db.Collection("likes"), "userId", "videoId")
+		if err != nil {
+			return fmt.Errorf("likes: %v", err)
+		}
+		for videoID := range likes {
+			count, err := db.Collection("likes").CountDocuments(ctx, bson.M{"videoId": videoID})
+			if err != nil {
+				return fmt.Errorf("likes: %v", err)
+			}
+			if _, err := db.Collection("videos").UpdateOne(ctx, bson.M{"_id": videoID}, bson.M{"$set": bson.M{"likes": count}}); err != nil {
+				return fmt.Errorf("videos: %v", err)
+			}
+		}
+
+		if _, err := deleteDuplicates(ctx, db.Collection("subscriptions"), "subscriberId", "channelId"); err != nil {
+			return fmt.Errorf("subscriptions: %v", err)
+		}
+		return nil
+	},
+	// Deleted duplicates can't be brought back, and nothing depends on them
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		return nil
+	},
+}
+
+// deleteDuplicates keeps the oldest document for every (first, second) pair and
+// returns how many were removed per value of second
+func deleteDuplicates(ctx context.Context, collection *mongo.Collection, first string, second string) (map[string]int, error) {
+	pipeline := []bson.M{
+		{"$sort": bson.M{"createdAt": 1}},
+		{"$group": bson.M{
+			"_id":   bson.M{"first": "$" + first, "second": "$" + second},
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$match": bson.M{"count": bson.M{"$gt": 1}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var groups []struct {
+		Key struct {
+			Second string `bson:"second"`
+		} `bson:"_id"`
+		IDs []string `bson:"ids"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	removed := make(map[string]int)
+	for _, group := range groups {
+		result, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": group.IDs[1:]}})
+		if err != nil {
+			return nil, err
+		}
+		removed[group.Key.Second] += int(result.DeletedCount)
+	}
+	return removed, nil
+}
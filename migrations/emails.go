@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// lowercaseEmails stores every address lowercased, as signup now does, so
+// logging in and password resets find accounts whatever case was typed. An
+// address whose lowercase form another account already uses is left alone
+// and logged.
+var lowercaseEmails = Migration{
+	Version: 7,
+	Name:    "lowercase_emails",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		users := db.Collection("users")
+		cursor, err := users.Find(ctx, bson.M{"email": primitive.Regex{Pattern: "[A-Z]"}})
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var user struct {
+				ID    string `bson:"_id"`
+				Email string `bson:"email"`
+			}
+			if err := cursor.Decode(&user); err != nil {
+				return err
+			}
+			lower := strings.ToLower(user.Email)
+			// Checked here rather than left to the unique index, which may not exist yet
+			taken, err := users.CountDocuments(ctx, bson.M{"email": lower, "_id": bson.M{"$ne": user.ID}})
+			if err != nil {
+				return err
+			}
+			if taken > 0 {
+				log.Printf("migrations: left email of user %s as is; another account uses %s", user.ID, lower)
+				continue
+			}
+			if _, err := users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"email": lower}}); err != nil {
+				return err
+			}
+		}
+		return cursor.Err()
+	},
+	// The original case isn't kept, and lowercase addresses work either way
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		return nil
+	},
+}
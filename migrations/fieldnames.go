@@ -0,0 +1,74 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// normalizeFieldNames renames stored fields to camelCase. Videos and user
+// timestamps were stored under the driver's default lowercase keys and watch
+// history and the token blacklist used snake_case.
+var normalizeFieldNames = Migration{
+	Version: 2,
+	Name:    "normalize_field_names",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		return renameAll(ctx, db, fieldRenames, false)
+	},
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		return renameAll(ctx, db, fieldRenames, true)
+	},
+}
+
+var fieldRenames = map[string]map[string]string{
+	"videos": {
+		"publishat":       "publishAt",
+		"customthumbnail": "customThumbnail",
+		"previewsprite":   "previewSprite",
+		"previewvtt":      "previewVtt",
+		"ownerid":         "ownerId",
+		"channelid":       "channelId",
+		"durationseconds": "durationSeconds",
+		"hlsurl":          "hlsUrl",
+		"createdat":       "createdAt",
+		"updatedat":       "updatedAt",
+	},
+	"users": {
+		"createdat": "createdAt",
+		"updatedat": "updatedAt",
+	},
+	"video_watches": {
+		"user_id":    "userId",
+		"video_id":   "videoId",
+		"watched_at": "watchedAt",
+	},
+	"token_blacklist": {
+		"created_at": "createdAt",
+	},
+	"search_documents": {
+		"channelid":       "channelId",
+		"channelname":     "channelName",
+		"durationseconds": "durationSeconds",
+		"createdat":       "createdAt",
+		"publishat":       "publishAt",
+	},
+}
+
+func renameAll(ctx context.Context, db *mongo.Database, renames map[string]map[string]string, back bool) error {
+	// The text index names the channel field, so it is dropped here and
+	// recreated with the current name when the search index starts
+	if err := dropIndex(ctx, db.Collection("search_documents"), "video_text"); err != nil {
+		return fmt.Errorf("search_documents: %v", err)
+	}
+
+	for collection, fields := range renames {
+		if back {
+			fields = reversed(fields)
+		}
+		if err := renameFields(ctx, db.Collection(collection), fields); err != nil {
+			return fmt.Errorf("%s: %v", collection, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// countLikes sets the like counter of every video from the likes collection.
+// Videos from before the counter existed have none, so they showed 0 likes
+// and sorted last by likes. It runs against a live database, so each video is
+// written once with its count rather than reset to 0 first.
+var countLikes = Migration{
+	Version: 9,
+	Name:    "count_likes",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		videos := db.Collection("videos")
+		cursor, err := db.Collection("likes").Aggregate(ctx, []bson.M{
+			{"$group": bson.M{"_id": "$videoId", "count": bson.M{"$sum": 1}}},
+		})
+		if err != nil {
+			return fmt.Errorf("likes: %v", err)
+		}
+		defer cursor.Close(ctx)
+
+		liked := bson.A{}
+		for cursor.Next(ctx) {
+			var group struct {
+				VideoID string `bson:"_id"`
+				Count   int    `bson:"count"`
+			}
+			if err := cursor.Decode(&group); err != nil {
+				return fmt.Errorf("likes: %v", err)
+			}
+			if _, err := videos.UpdateOne(ctx, bson.M{"_id": group.VideoID}, bson.M{"$set": bson.M{"likes": group.Count}}); err != nil {
+				return fmt.Errorf("videos: %v", err)
+			}
+			liked = append(liked, group.VideoID)
+		}
+		if err := cursor.Err(); err != nil {
+			return fmt.Errorf("likes: %v", err)
+		}
+
+		// Everything else has no likes; $ne also matches videos without the counter
+		_, err = videos.UpdateMany(ctx,
+			bson.M{"_id": bson.M{"$nin": liked}, "likes": bson.M{"$ne": 0}},
+			bson.M{"$set": bson.M{"likes": 0}},
+		)
+		if err != nil {
+			return fmt.Errorf("videos: %v", err)
+		}
+		return nil
+	},
+	// The counter is kept up to date by the code either way
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		return nil
+	},
+}
@@ -0,0 +1,240 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migration is one versioned change to the database. Up and Down must be
+// idempotent: a run that fails after changing data but before it is recorded
+// is simply repeated next time.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	// Down undoes Up; nil when the change cannot be rolled back
+	Down func(ctx context.Context, db *mongo.Database) error
+}
+
+// All lists every migration in the order it is applied. Append new migrations
+// with the next version number; never renumber or remove released ones.
+var All = []Migration{
+	referenceIDs,
+	normalizeFieldNames,
+	removeDuplicates,
+	dropUserRefreshToken,
+	clearRawTokenBlacklist,
+	assignRoles,
+	lowercaseEmails,
+	channelOwners,
+	countLikes,
+}
+
+// ErrLocked is returned while another process is running migrations
+var ErrLocked = errors.New("migrations are locked by another process")
+
+// lockTTL bounds how long a crashed process can hold the lock
+const lockTTL = 10 * time.Minute
+
+// Record is a migration applied to the database, stored in the migrations collection
+type Record struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"appliedAt"`
+}
+
+// Status is a known migration and when it was applied, if it has been
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Runner applies and rolls back migrations against a database
+type Runner struct {
+	db         *mongo.Database
+	records    *mongo.Collection
+	locks      *mongo.Collection
+	migrations []Migration
+}
+
+// NewRunner creates a runner for the given migrations, which must be in ascending version order
+func NewRunner(db *mongo.Database, migrations []Migration) (*Runner, error) {
+	for i, migration := range migrations {
+		if migration.Version <= 0 || migration.Up == nil {
+			return nil, fmt.Errorf("migration %q needs a positive version and an Up function", migration.Name)
+		}
+		if i > 0 && migration.Version <= migrations[i-1].Version {
+			return nil, fmt.Errorf("migration %d is out of order", migration.Version)
+		}
+	}
+	return &Runner{
+		db:         db,
+		records:    db.Collection("migrations"),
+		locks:      db.Collection("migration_lock"),
+		migrations: migrations,
+	}, nil
+}
+
+// Status lists every known migration along with when it was applied
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(r.migrations))
+	for _, migration := range r.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration up to and including target; a target of 0 means all of them
+func (r *Runner) Up(ctx context.Context, target int) ([]Migration, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for _, migration := range r.migrations {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		if err := migration.Up(ctx, r.db); err != nil {
+			return done, fmt.Errorf("migration %d (%s) failed: %v", migration.Version, migration.Name, err)
+		}
+		record := Record{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}
+		if _, err := r.records.InsertOne(ctx, record); err != nil {
+			return done, fmt.Errorf("failed to record migration %d: %v", migration.Version, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down rolls back the given number of most recently applied migrations
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for i := len(r.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := r.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == nil {
+			return done, fmt.Errorf("migration %d (%s) cannot be rolled back", migration.Version, migration.Name)
+		}
+
+		if err := migration.Down(ctx, r.db); err != nil {
+			return done, fmt.Errorf("rolling back migration %d (%s) failed: %v", migration.Version, migration.Name, err)
+		}
+		if _, err := r.records.DeleteOne(ctx, bson.M{"_id": migration.Version}); err != nil {
+			return done, fmt.Errorf("failed to unrecord migration %d: %v", migration.Version, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// applied returns the recorded migrations keyed by version
+func (r *Runner) applied(ctx context.Context) (map[int]Record, error) {
+	cursor, err := r.records.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	records := []Record{}
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]Record, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// lock takes the migration lock so two instances starting together don't both
+// migrate. The upsert only matches an expired lock, so a held one makes the
+// insert collide on _id.
+func (r *Runner) lock(ctx context.Context) (func(), error) {
+	owner := uuid.New().String()
+	now := time.Now()
+	_, err := r.locks.UpdateOne(ctx,
+		bson.M{"_id": "migrate", "lockedUntil": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{"owner": owner, "lockedUntil": now.Add(lockTTL)}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrLocked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take the migration lock: %v", err)
+	}
+
+	return func() {
+		r.locks.DeleteOne(context.Background(), bson.M{"_id": "migrate", "owner": owner})
+	}, nil
+}
+
+// renameFields renames top-level fields in every document of the collection that still has one of the old names
+func renameFields(ctx context.Context, collection *mongo.Collection, renames map[string]string) error {
+	legacy := bson.A{}
+	for from := range renames {
+		legacy = append(legacy, bson.M{from: bson.M{"$exists": true}})
+	}
+	_, err := collection.UpdateMany(ctx, bson.M{"$or": legacy}, bson.M{"$rename": renames})
+	return err
+}
+
+// reversed swaps the keys and values of a rename map
+func reversed(renames map[string]string) map[string]string {
+	back := make(map[string]string, len(renames))
+	for from, to := range renames {
+		back[to] = from
+	}
+	return back
+}
+
+// dropIndex removes an index, ignoring a missing index or collection
+func dropIndex(ctx context.Context, collection *mongo.Collection, name string) error {
+	_, err := collection.Indexes().DropOne(ctx, name)
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && (commandErr.Name == "IndexNotFound" || commandErr.Name == "NamespaceNotFound") {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,124 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// referenceIDs replaces the copies of users, videos and channels that older
+// documents embedded with the IDs of those documents
+var referenceIDs = Migration{
+	Version: 1,
+	Name:    "reference_ids",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		for _, rewrite := range referenceRewrites {
+			update := bson.A{}
+			if len(rewrite.set) > 0 {
+				update = append(update, bson.M{"$set": rewrite.set})
+			}
+			update = append(update, bson.M{"$unset": rewrite.unset})
+
+			// Rewritten documents no longer match legacy, so running this again is a no-op
+			if _, err := db.Collection(rewrite.collection).UpdateMany(ctx, rewrite.legacy, update); err != nil {
+				return fmt.Errorf("%s: %v", rewrite.collection, err)
+			}
+		}
+		return nil
+	},
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		for _, rewrite := range referenceRewrites {
+			if len(rewrite.embeds) == 0 {
+				continue
+			}
+			if err := embedReferences(ctx, db.Collection(rewrite.collection), rewrite); err != nil {
+				return fmt.Errorf("%s: %v", rewrite.collection, err)
+			}
+		}
+		return nil
+	},
+}
+
+// referenceRewrite describes how one collection moves from embedded copies to IDs
+type referenceRewrite struct {
+	collection string
+	// legacy matches documents that still have the embedded copy
+	legacy bson.M
+	set    bson.M
+	unset  []string
+	// embeds undo set: each ID field is replaced by the document it points at
+	embeds []embed
+}
+
+type embed struct {
+	idField string
+	from    string
+	as      string
+}
+
+var referenceRewrites = []referenceRewrite{
+	{
+		collection: "videos",
+		legacy:     bson.M{"$or": bson.A{bson.M{"owner": bson.M{"$exists": true}}, bson.M{"channelname": bson.M{"$exists": true}}}},
+		set:        bson.M{"ownerid": "$owner._id", "channelid": "$channelname._id"},
+		unset:      []string{"owner", "channelname"},
+		embeds:     []embed{{"ownerid", "users", "owner"}, {"channelid", "channels", "channelname"}},
+	},
+	{
+		collection: "likes",
+		legacy:     bson.M{"owner": bson.M{"$exists": true}},
+		set:        bson.M{"userId": "$owner._id", "videoId": "$vlike._id"},
+		unset:      []string{"owner", "vlike"},
+		embeds:     []embed{{"userId", "users", "owner"}, {"videoId", "videos", "vlike"}},
+	},
+	{
+		collection: "videocomments",
+		legacy:     bson.M{"owner": bson.M{"$exists": true}},
+		set:        bson.M{"ownerId": "$owner._id", "videoId": "$vcomment._id"},
+		unset:      []string{"owner", "vcomment"},
+		embeds:     []embed{{"ownerId", "users", "owner"}, {"videoId", "videos", "vcomment"}},
+	},
+	{
+		collection: "subscriptions",
+		legacy:     bson.M{"subscribers": bson.M{"$exists": true}},
+		set:        bson.M{"channelId": "$channelName._id", "subscriberId": "$subscribers._id"},
+		unset:      []string{"channelName", "subscribers"},
+		embeds:     []embed{{"channelId", "channels", "channelName"}, {"subscriberId", "users", "subscribers"}},
+	},
+	{
+		// Watch entries already carry video_id; the embedded video was never filled in
+		collection: "video_watches",
+		legacy:     bson.M{"video": bson.M{"$exists": true}},
+		unset:      []string{"video"},
+	},
+}
+
+// embedReferences copies the referenced documents back in place of their IDs
+func embedReferences(ctx context.Context, collection *mongo.Collection, rewrite referenceRewrite) error {
+	pipeline := []bson.M{{"$match": bson.M{rewrite.embeds[0].idField: bson.M{"$exists": true}}}}
+	unset := bson.A{}
+	for _, embed := range rewrite.embeds {
+		pipeline = append(pipeline,
+			bson.M{"$lookup": bson.M{
+				"from":         embed.from,
+				"localField":   embed.idField,
+				"foreignField": "_id",
+				"as":           embed.as,
+			}},
+			bson.M{"$unwind": bson.M{"path": "$" + embed.as, "preserveNullAndEmptyArrays": true}},
+		)
+		unset = append(unset, embed.idField)
+	}
+	pipeline = append(pipeline,
+		bson.M{"$unset": unset},
+		bson.M{"$merge": bson.M{"into": collection.Name(), "on": "_id", "whenMatched": "replace", "whenNotMatched": "discard"}},
+	)
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.Close(ctx)
+}
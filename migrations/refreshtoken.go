@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// dropUserRefreshToken removes the refreshToken field users never had written;
+// refresh tokens now belong to sessions
+var dropUserRefreshToken = Migration{
+	Version: 4,
+	Name:    "drop_user_refresh_token",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection("users").UpdateMany(ctx,
+			bson.M{"refreshToken": bson.M{"$exists": true}},
+			bson.M{"$unset": bson.M{"refreshToken": ""}},
+		)
+		return err
+	},
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection("users").UpdateMany(ctx,
+			bson.M{"refreshToken": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"refreshToken": ""}},
+		)
+		return err
+	},
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// assignRoles gives every existing account a role: creator if it already has a
+// channel, user otherwise. Staff roles are granted afterwards with "set-role".
+var assignRoles = Migration{
+	Version: 6,
+	Name:    "assign_roles",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		users := db.Collection("users")
+		_, err := users.UpdateMany(ctx,
+			bson.M{"role": bson.M{"$exists": false}, "channelName._id": bson.M{"$nin": bson.A{"", nil}}},
+			bson.M{"$set": bson.M{"role": "creator"}},
+		)
+		if err != nil {
+			return err
+		}
+		_, err = users.UpdateMany(ctx,
+			bson.M{"role": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"role": "user"}},
+		)
+		return err
+	},
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection("users").UpdateMany(ctx,
+			bson.M{},
+			bson.M{"$unset": bson.M{"role": "", "suspendedAt": "", "suspendedReason": ""}},
+		)
+		return err
+	},
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// clearRawTokenBlacklist deletes blacklist entries keyed by the raw
+// Authorization header. Lookups use the token's jti now, and those rows never
+// matched a lookup anyway.
+var clearRawTokenBlacklist = Migration{
+	Version: 5,
+	Name:    "clear_raw_token_blacklist",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection("token_blacklist").DeleteMany(ctx, bson.M{"token": bson.M{"$exists": true}})
+		return err
+	},
+	// The deleted rows had no effect, so there is nothing to restore
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		return nil
+	},
+}
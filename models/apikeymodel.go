@@ -0,0 +1,58 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// Scopes an API key can be granted; each opens the routes that declare it
+const (
+	ScopeVideosRead         = "videos:read"
+	ScopeVideosWrite        = "videos:write"
+	ScopeCommentsWrite      = "comments:write"
+	ScopeLikesWrite         = "likes:write"
+	ScopePlaylistsWrite     = "playlists:write"
+	ScopeSubscriptionsWrite = "subscriptions:write"
+	ScopeHistoryRead        = "history:read"
+	ScopeHistoryWrite       = "history:write"
+)
+
+// APIKeyScopes lists every scope an API key can be granted
+var APIKeyScopes = []string{
+	ScopeVideosRead, ScopeVideosWrite,
+	ScopeCommentsWrite,
+	ScopeLikesWrite,
+	ScopePlaylistsWrite,
+	ScopeSubscriptionsWrite,
+	ScopeHistoryRead, ScopeHistoryWrite,
+}
+
+// APIKey is a named, scoped credential a user creates for scripts and CI. It
+// is sent as a bearer token; only the hash of its secret is stored.
+type APIKey struct {
+	ID         string     `json:"id" bson:"_id"`
+	UserID     string     `json:"userId" bson:"userId"`
+	Name       string     `json:"name" bson:"name"`
+	Prefix     string     `json:"prefix" bson:"prefix"` // public part of the key, "ytk_<prefix>_..."
+	SecretHash string     `json:"-" bson:"secretHash"`
+	Scopes     []string   `json:"scopes" bson:"scopes"`
+	ExpiresAt  time.Time  `json:"expiresAt" bson:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"`
+	LastUsedIP string     `json:"lastUsedIp,omitempty" bson:"lastUsedIp,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt" bson:"createdAt"`
+}
+
+// HasScopes reports whether the key was granted every one of scopes
+func (k *APIKey) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(k.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// Expired reports whether the key can no longer be used
+func (k *APIKey) Expired(now time.Time) bool {
+	return !now.Before(k.ExpiresAt)
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Actions recorded in the audit log
+const (
+	AuditUserSuspended   = "user.suspended"
+	AuditUserUnsuspended = "user.unsuspended"
+	AuditUserRoleChanged = "user.role_changed"
+	AuditVideoTakenDown  = "video.taken_down"
+	AuditVideoRestored   = "video.restored"
+	AuditVideoDeleted    = "video.deleted"
+	AuditCommentDeleted  = "comment.deleted"
+	AuditLoginLocked     = "login.locked"
+)
+
+// AuditLog records a moderation or administration action taken by a staff
+// member, or a security event such as a login lockout (ActorRole "system")
+type AuditLog struct {
+	ID         string            `json:"id" bson:"_id"`
+	ActorID    string            `json:"actorId" bson:"actorId"`
+	ActorRole  string            `json:"actorRole" bson:"actorRole"`
+	Action     string            `json:"action" bson:"action"`
+	TargetType string            `json:"targetType" bson:"targetType"`
+	TargetID   string            `json:"targetId" bson:"targetId"`
+	Reason     string            `json:"reason,omitempty" bson:"reason,omitempty"`
+	Details    map[string]string `json:"details,omitempty" bson:"details,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt" bson:"createdAt"`
+}
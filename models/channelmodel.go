@@ -7,4 +7,11 @@ type Channel struct {
 	ChannelName string `json:"channelName" bson:"channelName"`
 	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
 	UpdatedAt   time.Time `json:"updatedAt" bson:"updatedAt"`
+
+	// Channels created before these were added get an owner and handle from a migration
+	OwnerID     string        `json:"ownerId,omitempty" bson:"ownerId,omitempty"`
+	Handle      string        `json:"handle,omitempty" bson:"handle,omitempty"` // unique, lowercase, without the "@"
+	Description string        `json:"description,omitempty" bson:"description,omitempty"`
+	Banner      string        `json:"banner,omitempty" bson:"banner,omitempty"`
+	Links       []ProfileLink `json:"links,omitempty" bson:"links,omitempty"`
 }
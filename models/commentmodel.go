@@ -5,8 +5,11 @@ import "time"
 type VideoComment struct {
 	ID        string    `json:"id" bson:"_id"`
 	Content   string    `json:"content" bson:"content"`
-	Owner     User      `json:"owner"`
-	VComment  Video     `json:"vcomment" bson:"vcomment"`
+	OwnerID   string    `json:"ownerId" bson:"ownerId"`
+	VideoID   string    `json:"videoId" bson:"videoId"`
 	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+
+	// Filled in from the users collection when the comment is read; never stored
+	Owner *UserSummary `json:"owner,omitempty" bson:"owner,omitempty"`
 }
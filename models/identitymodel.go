@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Identity links an account at an external OpenID provider to a user, who can
+// then sign in with that provider. A provider account links to one user only.
+type Identity struct {
+	ID          string    `json:"id" bson:"_id"`
+	UserID      string    `json:"userId" bson:"userId"`
+	Provider    string    `json:"provider" bson:"provider"`
+	Subject     string    `json:"subject" bson:"subject"` // the provider's ID for the account
+	Email       string    `json:"email" bson:"email"`
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
+	LastLoginAt time.Time `json:"lastLoginAt" bson:"lastLoginAt"`
+}
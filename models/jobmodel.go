@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+)
+
+// Job is a unit of background work picked up by the worker pool
+type Job struct {
+	ID          string            `json:"id" bson:"_id"`
+	Type        string            `json:"type" bson:"type"`
+	VideoID     string            `json:"videoId,omitempty" bson:"videoId,omitempty"`
+	Payload     map[string]string `json:"-" bson:"payload"`
+	Status      string            `json:"status" bson:"status"`
+	Step        string            `json:"step" bson:"step"`
+	Progress    int               `json:"progress" bson:"progress"`
+	Attempts    int               `json:"attempts" bson:"attempts"`
+	MaxAttempts int               `json:"maxAttempts" bson:"maxAttempts"`
+	Error       string            `json:"error,omitempty" bson:"error,omitempty"`
+	RunAt       time.Time         `json:"runAt" bson:"runAt"`
+	LockedUntil time.Time         `json:"-" bson:"lockedUntil"`
+	CreatedAt   time.Time         `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt" bson:"updatedAt"`
+	FinishedAt  *time.Time        `json:"finishedAt,omitempty" bson:"finishedAt,omitempty"`
+}
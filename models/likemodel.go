@@ -4,8 +4,8 @@ import "time"
 
 type Like struct {
 	ID        string    `json:"id" bson:"_id"`
-	Owner     User      `json:"owner"`
-	VLike     Video     `json:"vlike" bson:"vlike"`
+	UserID    string    `json:"userId" bson:"userId"`
+	VideoID   string    `json:"videoId" bson:"videoId"`
 	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
 }
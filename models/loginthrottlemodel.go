@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// LoginThrottle counts recent failed logins against one account, or wrong
+// two-factor codes, or failed logins from one IP address. It is forgotten once ExpiresAt passes without another failure.
+type LoginThrottle struct {
+	ID            string     `json:"id" bson:"_id"` // "user:<id>", "name:<unknown login name>", "mfa:<user id>" or "ip:<address>"
+	Failures      int        `json:"failures" bson:"failures"`
+	LastFailureAt time.Time  `json:"lastFailureAt" bson:"lastFailureAt"`
+	LockedUntil   *time.Time `json:"lockedUntil,omitempty" bson:"lockedUntil,omitempty"`
+	ExpiresAt     time.Time  `json:"expiresAt" bson:"expiresAt"`
+}
+
+// RetryAfter is how long logins stay locked, or zero if they aren't
+func (t *LoginThrottle) RetryAfter(now time.Time) time.Duration {
+	if t.LockedUntil == nil || !now.Before(*t.LockedUntil) {
+		return 0
+	}
+	return t.LockedUntil.Sub(now)
+}
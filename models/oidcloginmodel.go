@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// OIDCLogin is a sign-in with an external provider that was started but not
+// yet finished. It is keyed by the state parameter and holds the secrets the
+// provider must not see until the code is exchanged.
+type OIDCLogin struct {
+	ID           string    `bson:"_id"`
+	Provider     string    `bson:"provider"`
+	Nonce        string    `bson:"nonce"`
+	CodeVerifier string    `bson:"codeVerifier"`
+	LinkUserID   string    `bson:"linkUserId,omitempty"` // set when linking to a signed-in user
+	ExpiresAt    time.Time `bson:"expiresAt"`
+	CreatedAt    time.Time `bson:"createdAt"`
+}
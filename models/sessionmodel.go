@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Session is one signed-in device. Its refresh token rotates on every use; the
+// hashes of earlier tokens are kept so a replayed one can be recognised.
+type Session struct {
+	ID             string     `json:"id" bson:"_id"`
+	UserID         string     `json:"userId" bson:"userId"`
+	SecretHash     string     `json:"-" bson:"secretHash"`
+	PreviousHashes []string   `json:"-" bson:"previousHashes"`
+	UserAgent      string     `json:"userAgent" bson:"userAgent"`
+	IP             string     `json:"ip" bson:"ip"`
+	CreatedAt      time.Time  `json:"createdAt" bson:"createdAt"`
+	LastUsedAt     time.Time  `json:"lastUsedAt" bson:"lastUsedAt"`
+	ExpiresAt      time.Time  `json:"expiresAt" bson:"expiresAt"`
+	RevokedAt      *time.Time `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+	RevokedReason  string     `json:"revokedReason,omitempty" bson:"revokedReason,omitempty"`
+
+	// MFAVerifiedAt is when the user last entered their second factor on this device
+	MFAVerifiedAt *time.Time `json:"mfaVerifiedAt,omitempty" bson:"mfaVerifiedAt,omitempty"`
+}
+
+// Active reports whether the session can still be refreshed
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
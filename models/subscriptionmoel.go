@@ -3,10 +3,20 @@ package models
 import "time"
 
 type Subscription struct {
-	ID        string `json:"id" bson:"_id"`
-	ChannelName Channel  `json:"channelName" bson:"channelName"`
-	Subscribers User `json:"subscribers" bson:"subscribers"`
-	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+	ID           string    `json:"id" bson:"_id"`
+	ChannelID    string    `json:"channelId" bson:"channelId"`
+	SubscriberID string    `json:"subscriberId" bson:"subscriberId"`
+	CreatedAt    time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt" bson:"updatedAt"`
 }
 
+// SubscribedChannel is a channel the user is subscribed to, as listed by SubscribedToChannel
+type SubscribedChannel struct {
+	ChannelID       string    `json:"channelId" bson:"channelId"`
+	ChannelName     string    `json:"channelName" bson:"channelName"`
+	Handle          string    `json:"handle,omitempty" bson:"handle,omitempty"`
+	Banner          string    `json:"banner,omitempty" bson:"banner,omitempty"`
+	CreatedAt       time.Time `json:"createdAt" bson:"createdAt"`
+	SubscribedAt    time.Time `json:"subscribedAt" bson:"subscribedAt"`
+	SubscriberCount int64     `json:"subscriberCount" bson:"subscriberCount"`
+}
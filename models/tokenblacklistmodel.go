@@ -1,13 +1,11 @@
 package models
 
-import (
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"time"
-)
+import "time"
 
-// TokenBlacklist represents a blacklisted token
+// TokenBlacklist represents a revoked access token, identified by its jti claim.
+// Entries are only needed until the token would have expired anyway.
 type TokenBlacklist struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Token     string            `bson:"token" json:"token"`
-	CreatedAt time.Time         `bson:"created_at" json:"created_at"`
+	TokenID   string    `bson:"_id" json:"id"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"expires_at"`
+	CreatedAt time.Time `bson:"createdAt" json:"created_at"`
 }
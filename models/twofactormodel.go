@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// TwoFactor is a user's authenticator app enrollment, keyed by user ID. It is
+// pending until the user confirms a code, and only then required at login.
+type TwoFactor struct {
+	UserID    string     `json:"userId" bson:"_id"`
+	Secret    string     `json:"-" bson:"secret"`
+	EnabledAt *time.Time `json:"enabledAt,omitempty" bson:"enabledAt,omitempty"`
+	// RecoveryCodes holds the hashes of the unused recovery codes
+	RecoveryCodes []string `json:"-" bson:"recoveryCodes"`
+	// LastUsedStep is the time step of the last accepted code, so a code works only once
+	LastUsedStep int64     `json:"-" bson:"lastUsedStep"`
+	CreatedAt    time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// Enabled reports whether the second factor is required at login
+func (t *TwoFactor) Enabled() bool {
+	return t.EnabledAt != nil
+}
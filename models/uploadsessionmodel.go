@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	UploadStatusActive    = "active"
+	UploadStatusCompleted = "completed"
+)
+
+// UploadSession tracks a resumable video upload. The bytes received so far
+// are kept on disk until the session is finalized.
+type UploadSession struct {
+	ID        string    `json:"id" bson:"_id"`
+	UserID    string    `json:"userId" bson:"userId"`
+	Filename  string    `json:"filename" bson:"filename"`
+	Title     string    `json:"title" bson:"title"`
+	Length    int64     `json:"length" bson:"length"`
+	Offset    int64     `json:"offset" bson:"offset"`
+	Status    string    `json:"status" bson:"status"`
+	VideoID   string    `json:"videoId,omitempty" bson:"videoId,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+
+	// The rest of the metadata the video is created with once the upload is finalized
+	Description string     `json:"description,omitempty" bson:"description,omitempty"`
+	Tags        []string   `json:"tags,omitempty" bson:"tags,omitempty"`
+	Category    string     `json:"category,omitempty" bson:"category,omitempty"`
+	Language    string     `json:"language,omitempty" bson:"language,omitempty"`
+	Visibility  string     `json:"visibility,omitempty" bson:"visibility,omitempty"`
+	PublishAt   *time.Time `json:"publishAt,omitempty" bson:"publishAt,omitempty"`
+}
@@ -1,16 +1,118 @@
 package models
 
-import "time"
+import (
+	"slices"
+	"time"
+)
+
+// Roles, from least to most privileged. Each role may do everything the roles
+// before it may: creators own channels, moderators remove other people's
+// content and suspend accounts, admins also manage roles and read the audit log.
+const (
+	RoleUser      = "user"
+	RoleCreator   = "creator"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Roles lists every role in order of privilege
+var Roles = []string{RoleUser, RoleCreator, RoleModerator, RoleAdmin}
+
+// RoleRank orders roles by privilege; unknown roles rank below every known one
+func RoleRank(role string) int {
+	if role == "" {
+		role = RoleUser
+	}
+	return slices.Index(Roles, role)
+}
 
 type User struct {
-	ID           string    `json:"id" bson:"_id" validate:"required"`
-	Username     string    `json:"username" bson:"username" validate:"required,min=3,max=20"`
-	ChannelName  Channel   `json:"channelName" bson:"channelName"`
-	Email        string    `json:"email" bson:"email" validate:"required,email" lowercase:"true"`
-	Password     string    `json:"password" bson:"password" validate:"required,min=8"`
-	Avatar       string    `json:"avatar" bson:"avatar" validate:"omitempty,url"`
-	CoverImage   string    `json:"coverImage" bson:"coverImage" validate:"omitempty,url"`
-	RefreshToken string    `json:"refreshToken" bson:"refreshToken" validate:"omitempty"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	ID          string    `json:"id" bson:"_id" validate:"required"`
+	Username    string    `json:"username" bson:"username" validate:"required,min=3,max=20"`
+	ChannelName Channel   `json:"channelName" bson:"channelName"`
+	Email       string    `json:"email" bson:"email" validate:"required,email" lowercase:"true"`
+	Password    string    `json:"-" bson:"password" validate:"required,min=8"`
+	Avatar      string    `json:"avatar" bson:"avatar" validate:"omitempty,url"`
+	CoverImage  string    `json:"coverImage" bson:"coverImage" validate:"omitempty,url"`
+	Role        string    `json:"role" bson:"role"`
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt" bson:"updatedAt"`
+
+	// Shown on the user's public profile
+	Bio   string        `json:"bio,omitempty" bson:"bio,omitempty"`
+	Links []ProfileLink `json:"links,omitempty" bson:"links,omitempty"`
+
+	// Set once the user follows the link sent to Email
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty" bson:"emailVerifiedAt,omitempty"`
+
+	// Set while a moderator has suspended the account
+	SuspendedAt     *time.Time `json:"suspendedAt,omitempty" bson:"suspendedAt,omitempty"`
+	SuspendedReason string     `json:"suspendedReason,omitempty" bson:"suspendedReason,omitempty"`
+}
+
+// HasRole reports whether the user's role is at least as privileged as role.
+// Accounts created before roles existed count as plain users.
+func (u *User) HasRole(role string) bool {
+	return RoleRank(u.Role) >= RoleRank(role)
+}
+
+// EmailVerified reports whether the user has confirmed they own their email address
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// Suspended reports whether the account is currently suspended
+func (u *User) Suspended() bool {
+	return u.SuspendedAt != nil
+}
+
+// ProfileLink is a link the user shows on their profile
+type ProfileLink struct {
+	Title string `json:"title" bson:"title"`
+	URL   string `json:"url" bson:"url"`
+}
+
+// Profile is the public view of a user; it leaves out their email, role and account state
+type Profile struct {
+	ID         string        `json:"id"`
+	Username   string        `json:"username"`
+	Avatar     string        `json:"avatar"`
+	CoverImage string        `json:"coverImage"`
+	Bio        string        `json:"bio"`
+	Links      []ProfileLink `json:"links"`
+	Channel    *Channel      `json:"channel,omitempty"`
+	CreatedAt  time.Time     `json:"createdAt"`
+}
+
+// Profile returns the public view of the user
+func (u *User) Profile() *Profile {
+	profile := &Profile{
+		ID:         u.ID,
+		Username:   u.Username,
+		Avatar:     u.Avatar,
+		CoverImage: u.CoverImage,
+		Bio:        u.Bio,
+		Links:      u.Links,
+		CreatedAt:  u.CreatedAt,
+	}
+	if profile.Links == nil {
+		profile.Links = []ProfileLink{}
+	}
+	if u.ChannelName.ID != "" {
+		channel := u.ChannelName
+		profile.Channel = &channel
+	}
+	return profile
+}
+
+// UserSummary is the public part of a user shown next to their videos and comments
+type UserSummary struct {
+	ID       string `json:"id" bson:"_id"`
+	Username string `json:"username" bson:"username"`
+	Avatar   string `json:"avatar" bson:"avatar"`
+}
+
+// Summary returns the public part of the user
+func (u *User) Summary() *UserSummary {
+	return &UserSummary{ID: u.ID, Username: u.Username, Avatar: u.Avatar}
 }
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Purposes of the single-use tokens given to users
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+	// TokenPurposeMFAChallenge is handed out after a correct password, to be
+	// sent back with the second factor
+	TokenPurposeMFAChallenge = "mfa_challenge"
+)
+
+// UserToken is a single-use token sent in an emailed link or returned as a
+// login challenge. Only its hash is stored, as the ID, and it is deleted as
+// soon as it is used.
+type UserToken struct {
+	ID        string    `json:"-" bson:"_id"`
+	UserID    string    `json:"userId" bson:"userId"`
+	Purpose   string    `json:"purpose" bson:"purpose"`
+	Email     string    `json:"email" bson:"email"` // the address the link was sent to
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+
+	// Failures counts wrong codes entered against a login challenge
+	Failures int `json:"-" bson:"failures,omitempty"`
+}
@@ -2,14 +2,90 @@ package models
 
 import "time"
 
+const (
+	VideoStatusProcessing = "processing"
+	VideoStatusReady      = "ready"
+	VideoStatusFailed     = "failed"
+)
+
+const (
+	// VisibilityPublic videos are listed and playable by everyone
+	VisibilityPublic = "public"
+	// VisibilityUnlisted videos are playable by anyone with the link but never listed
+	VisibilityUnlisted = "unlisted"
+	// VisibilityPrivate videos are only visible to their owner
+	VisibilityPrivate = "private"
+)
+
+// VideoCategories lists the categories a video can be filed under
+var VideoCategories = []string{
+	"film", "autos", "music", "pets", "sports", "travel", "gaming", "people",
+	"comedy", "entertainment", "news", "howto", "education", "science", "nonprofit",
+}
+
 type Video struct {
-	ID           string    `json:"id" bson:"_id" validate:"required"`
-	Title       string    `json:"title"`
-	URL         string    `json:"url"`
-	Owner       User      `json:"owner"`
-	ChannelName Channel   `json:"channel_name"`
-	Views       int       `json:"views" default:"0"`
-	Duration    string    `json:"duration"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID              string      `json:"id" bson:"_id" validate:"required"`
+	Title           string      `json:"title" bson:"title"`
+	Description     string      `json:"description" bson:"description"`
+	Tags            []string    `json:"tags" bson:"tags"`
+	Category        string      `json:"category" bson:"category"`
+	Language        string      `json:"language" bson:"language"`
+	Visibility      string      `json:"visibility" bson:"visibility"`
+	PublishAt       *time.Time  `json:"publish_at" bson:"publishAt"`
+	URL             string      `json:"url" bson:"url"`
+	Thumbnail       string      `json:"thumbnail" bson:"thumbnail"`
+	CustomThumbnail bool        `json:"custom_thumbnail" bson:"customThumbnail"`
+	PreviewSprite   string      `json:"preview_sprite" bson:"previewSprite"`
+	PreviewVTT      string      `json:"preview_vtt" bson:"previewVtt"`
+	OwnerID         string      `json:"owner_id" bson:"ownerId"`
+	ChannelID       string      `json:"channel_id" bson:"channelId"`
+	Views           int         `json:"views" bson:"views" default:"0"`
+	Likes           int         `json:"likes" bson:"likes"`
+	Duration        string      `json:"duration" bson:"duration"`
+	DurationSeconds float64     `json:"duration_seconds" bson:"durationSeconds"`
+	Width           int         `json:"width" bson:"width"`
+	Height          int         `json:"height" bson:"height"`
+	Status          string      `json:"status" bson:"status"`
+	HLSURL          string      `json:"hls_url" bson:"hlsUrl"`
+	Renditions      []Rendition `json:"renditions" bson:"renditions"`
+	CreatedAt       time.Time   `json:"created_at" bson:"createdAt"`
+	UpdatedAt       time.Time   `json:"updated_at" bson:"updatedAt"`
+
+	// Set while a moderator has taken the video down; only its owner still sees it
+	TakenDownAt    *time.Time `json:"taken_down_at,omitempty" bson:"takenDownAt,omitempty"`
+	TakedownReason string     `json:"takedown_reason,omitempty" bson:"takedownReason,omitempty"`
+
+	// Filled in from the users and channels collections when the video is read; never stored
+	Owner   *UserSummary `json:"owner,omitempty" bson:"owner,omitempty"`
+	Channel *Channel     `json:"channel,omitempty" bson:"channel,omitempty"`
+}
+
+// IsPublished reports whether a scheduled video has reached its publish time
+func (v *Video) IsPublished(now time.Time) bool {
+	return v.PublishAt == nil || !v.PublishAt.After(now)
+}
+
+// VisibleTo reports whether the user may watch the video. Owners always can;
+// everyone else needs a ready, published video that isn't private or taken
+// down. An empty userID is an anonymous viewer.
+func (v *Video) VisibleTo(userID string, now time.Time) bool {
+	if userID != "" && v.OwnerID == userID {
+		return true
+	}
+	if v.TakenDownAt != nil {
+		return false
+	}
+	if v.Status != "" && v.Status != VideoStatusReady {
+		return false
+	}
+	return v.Visibility != VisibilityPrivate && v.IsPublished(now)
+}
+
+// Rendition is one HLS variant stream of a video
+type Rendition struct {
+	Name      string `json:"name" bson:"name"`
+	Width     int    `json:"width" bson:"width"`
+	Height    int    `json:"height" bson:"height"`
+	Bandwidth int    `json:"bandwidth" bson:"bandwidth"`
+	Playlist  string `json:"playlist" bson:"playlist"`
 }
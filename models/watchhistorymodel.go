@@ -4,8 +4,10 @@ import "time"
 
 type VideoWatchEntry struct {
 	ID        string    `json:"id" bson:"_id" validate:"required"`
-	UserID    string    `json:"user_id" bson:"user_id" validate:"required"`
-	VideoID   string    `json:"video_id" bson:"video_id" validate:"required"`
-	Video     Video     `json:"video" bson:"video"`
-	WatchedAt time.Time `json:"watched_at" bson:"watched_at"`
+	UserID    string    `json:"user_id" bson:"userId" validate:"required"`
+	VideoID   string    `json:"video_id" bson:"videoId" validate:"required"`
+	WatchedAt time.Time `json:"watched_at" bson:"watchedAt"`
+
+	// Filled in from the videos collection when the history is read; never stored
+	Video *Video `json:"video,omitempty" bson:"video,omitempty"`
 }
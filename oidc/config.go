@@ -0,0 +1,67 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var providerName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ProvidersFromEnv reads the providers named in OIDC_PROVIDERS (comma separated,
+// e.g. "google,gitlab"). Each name NAME is configured with OIDC_NAME_ISSUER,
+// OIDC_NAME_CLIENT_ID, OIDC_NAME_CLIENT_SECRET (optional), OIDC_NAME_SCOPES
+// (default "openid email profile") and OIDC_NAME_REDIRECT_URL (default
+// APP_URL + "/auth/callback/name", the web app page that finishes the login).
+func ProvidersFromEnv(appURL string) (map[string]*Provider, error) {
+	providers := make(map[string]*Provider)
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !providerName.MatchString(name) {
+			return nil, fmt.Errorf("oidc: invalid provider name %q; use lowercase letters, digits, - and _", name)
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		config := Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if config.Issuer == "" || config.ClientID == "" {
+			return nil, fmt.Errorf("oidc: %sISSUER and %sCLIENT_ID are required for provider %q", prefix, prefix, name)
+		}
+		if config.RedirectURL == "" {
+			config.RedirectURL = strings.TrimRight(appURL, "/") + "/auth/callback/" + name
+		}
+		if len(config.Scopes) == 0 {
+			config.Scopes = []string{"openid", "email", "profile"}
+		}
+		providers[name] = NewProvider(config)
+	}
+	return providers, nil
+}
+
+// RandomString returns 32 random bytes, base64url encoded, for states, nonces and PKCE verifiers
+func RandomString() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// CodeChallenge derives the PKCE S256 challenge from a verifier (RFC 7636 section 4.2)
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
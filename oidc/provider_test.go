@@ -0,0 +1,144 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// newMockServer serves a MockProvider whose issuer is the test server's URL
+func newMockServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var mock *MockProvider
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mock.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	var err error
+	if mock, err = NewMockProvider(server.URL); err != nil {
+		t.Fatalf("NewMockProvider: %v", err)
+	}
+	return server
+}
+
+func newTestProvider(server *httptest.Server) *Provider {
+	return NewProvider(Config{
+		Name:        "mock",
+		Issuer:      server.URL,
+		ClientID:    "videos",
+		RedirectURL: "https://videos.example/auth/callback/mock",
+		Scopes:      []string{"openid", "email"},
+	})
+}
+
+// authorize signs in as user at the provider and returns the code it redirects back with
+func authorize(t *testing.T, provider *Provider, user string, nonce string, verifier string) string {
+	t.Helper()
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-1", nonce, CodeChallenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	authURL += "&login_hint=" + url.QueryEscape(user)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %d, want a redirect", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("redirect location: %v", err)
+	}
+	if location.Host != "videos.example" || location.Query().Get("state") != "state-1" {
+		t.Fatalf("redirected to %s, want the callback with our state", location)
+	}
+	return location.Query().Get("code")
+}
+
+func TestExchangeVerifiesPKCEAndNonce(t *testing.T) {
+	server := newMockServer(t)
+	ctx := context.Background()
+	nonce, _ := RandomString()
+	verifier, _ := RandomString()
+
+	t.Run("valid", func(t *testing.T) {
+		provider := newTestProvider(server)
+		code := authorize(t, provider, "alice", nonce, verifier)
+
+		claims, err := provider.Exchange(ctx, code, verifier, nonce)
+		if err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+		if claims.Subject != "alice" || claims.Email != "alice@example.com" || !claims.EmailVerified {
+			t.Errorf("claims = %s, %s, verified %v; want alice", claims.Subject, claims.Email, claims.EmailVerified)
+		}
+
+		// Codes work once
+		if _, err := provider.Exchange(ctx, code, verifier, nonce); !errors.Is(err, ErrExchangeFailed) {
+			t.Errorf("reusing the code = %v, want ErrExchangeFailed", err)
+		}
+	})
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		provider := newTestProvider(server)
+		code := authorize(t, provider, "alice", nonce, verifier)
+		other, _ := RandomString()
+
+		if _, err := provider.Exchange(ctx, code, other, nonce); !errors.Is(err, ErrExchangeFailed) {
+			t.Errorf("Exchange = %v, want ErrExchangeFailed", err)
+		}
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		provider := newTestProvider(server)
+		code := authorize(t, provider, "alice", nonce, verifier)
+		other, _ := RandomString()
+
+		if _, err := provider.Exchange(ctx, code, verifier, other); !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("Exchange = %v, want ErrInvalidIDToken", err)
+		}
+	})
+
+	t.Run("other client", func(t *testing.T) {
+		code := authorize(t, newTestProvider(server), "alice", nonce, verifier)
+		provider := newTestProvider(server)
+		provider.ClientID = "someone-else"
+
+		if _, err := provider.Exchange(ctx, code, verifier, nonce); !errors.Is(err, ErrExchangeFailed) {
+			t.Errorf("Exchange = %v, want ErrExchangeFailed", err)
+		}
+	})
+
+	t.Run("confidential client", func(t *testing.T) {
+		provider := newTestProvider(server)
+		provider.ClientSecret = "s3cret&more"
+		code := authorize(t, provider, "bob@example.org", nonce, verifier)
+
+		claims, err := provider.Exchange(ctx, code, verifier, nonce)
+		if err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+		if claims.Email != "bob@example.org" {
+			t.Errorf("Email = %q, want bob@example.org", claims.Email)
+		}
+	})
+}
+
+func TestDiscoveryMustNameTheConfiguredIssuer(t *testing.T) {
+	server := newMockServer(t)
+	provider := newTestProvider(server)
+	provider.Issuer = server.URL + "/"
+
+	if _, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "challenge"); err == nil {
+		t.Error("AuthCodeURL accepted a discovery document for another issuer")
+	}
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APIKeyRepo stores users' API keys
+type APIKeyRepo interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	FindByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	// ListByUser returns the user's keys, newest first
+	ListByUser(ctx context.Context, userID string) ([]models.APIKey, error)
+	// Delete revokes the key, which must belong to userID
+	Delete(ctx context.Context, id string, userID string) error
+	TouchLastUsed(ctx context.Context, id string, at time.Time, ip string) error
+	DeleteByUser(ctx context.Context, userID string) error
+}
+
+type mongoAPIKeyRepo struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoAPIKeyRepo) Create(ctx context.Context, key *models.APIKey) error {
+	_, err := r.collection.InsertOne(ctx, key)
+	return translate(err)
+}
+
+func (r *mongoAPIKeyRepo) FindByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.collection.FindOne(ctx, bson.M{"prefix": prefix}).Decode(&key); err != nil {
+		return nil, translate(err)
+	}
+	return &key, nil
+}
+
+func (r *mongoAPIKeyRepo) ListByUser(ctx context.Context, userID string) ([]models.APIKey, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"userId": userID}, options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		return nil, err
+	}
+	keys := []models.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *mongoAPIKeyRepo) Delete(ctx context.Context, id string, userID string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "userId": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoAPIKeyRepo) TouchLastUsed(ctx context.Context, id string, at time.Time, ip string) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"lastUsedAt": at, "lastUsedIp": ip}})
+	return err
+}
+
+func (r *mongoAPIKeyRepo) DeleteByUser(ctx context.Context, userID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	return err
+}
+
+type memAPIKeyRepo struct {
+	table *memTable[models.APIKey]
+}
+
+func (r *memAPIKeyRepo) Create(ctx context.Context, key *models.APIKey) error {
+	// Matches the unique prefix index on the Mongo side
+	return r.table.insert(key.ID, *key, func(existing models.APIKey) bool {
+		return existing.Prefix == key.Prefix
+	})
+}
+
+func (r *memAPIKeyRepo) FindByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	key, ok := r.table.find(func(key models.APIKey) bool { return key.Prefix == prefix })
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &key, nil
+}
+
+func (r *memAPIKeyRepo) ListByUser(ctx context.Context, userID string) ([]models.APIKey, error) {
+	return r.table.filter(
+		func(key models.APIKey) bool { return key.UserID == userID },
+		func(a, b models.APIKey) bool { return a.CreatedAt.After(b.CreatedAt) },
+	), nil
+}
+
+func (r *memAPIKeyRepo) Delete(ctx context.Context, id string, userID string) error {
+	deleted := r.table.deleteWhere(func(key models.APIKey) bool {
+		return key.ID == id && key.UserID == userID
+	})
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memAPIKeyRepo) TouchLastUsed(ctx context.Context, id string, at time.Time, ip string) error {
+	r.table.update(id, func(key *models.APIKey) {
+		key.LastUsedAt = &at
+		key.LastUsedIP = ip
+	})
+	return nil
+}
+
+func (r *memAPIKeyRepo) DeleteByUser(ctx context.Context, userID string) error {
+	r.table.deleteWhere(func(key models.APIKey) bool { return key.UserID == userID })
+	return nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditLogQuery filters the audit log; empty fields match everything
+type AuditLogQuery struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	Skip       int64
+	Limit      int64
+}
+
+// AuditLogRepo stores the record of moderation and administration actions.
+// Entries are only ever added, never changed.
+type AuditLogRepo interface {
+	Record(ctx context.Context, entry *models.AuditLog) error
+	// List returns matching entries, newest first
+	List(ctx context.Context, query AuditLogQuery) ([]models.AuditLog, error)
+}
+
+type mongoAuditLogRepo struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoAuditLogRepo) Record(ctx context.Context, entry *models.AuditLog) error {
+	_, err := r.collection.InsertOne(ctx, entry)
+	return translate(err)
+}
+
+func (r *mongoAuditLogRepo) List(ctx context.Context, query AuditLogQuery) ([]models.AuditLog, error) {
+	filter := bson.M{}
+	if query.ActorID != "" {
+		filter["actorId"] = query.ActorID
+	}
+	if query.Action != "" {
+		filter["action"] = query.Action
+	}
+	if query.TargetType != "" {
+		filter["targetType"] = query.TargetType
+	}
+	if query.TargetID != "" {
+		filter["targetId"] = query.TargetID
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(query.Skip).
+		SetLimit(query.Limit)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.AuditLog{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+type memAuditLogRepo struct {
+	table *memTable[models.AuditLog]
+}
+
+func (r *memAuditLogRepo) Record(ctx context.Context, entry *models.AuditLog) error {
+	return r.table.insert(entry.ID, *entry, nil)
+}
+
+func (r *memAuditLogRepo) List(ctx context.Context, query AuditLogQuery) ([]models.AuditLog, error) {
+	entries := r.table.filter(
+		func(entry models.AuditLog) bool {
+			return (query.ActorID == "" || entry.ActorID == query.ActorID) &&
+				(query.Action == "" || entry.Action == query.Action) &&
+				(query.TargetType == "" || entry.TargetType == query.TargetType) &&
+				(query.TargetID == "" || entry.TargetID == query.TargetID)
+		},
+		func(a, b models.AuditLog) bool {
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.After(b.CreatedAt)
+			}
+			return a.ID > b.ID
+		},
+	)
+	return page(entries, query.Skip, query.Limit), nil
+}
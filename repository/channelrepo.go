@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ChannelUpdate holds the channel fields to change; nil fields are left as they are
+type ChannelUpdate struct {
+	ChannelName *string
+	Handle      *string
+	Description *string
+	Links       *[]models.ProfileLink
+}
+
+// ChannelRepo stores channels
+type ChannelRepo interface {
+	// Create returns ErrDuplicate if the handle is taken or the owner already has a channel
+	Create(ctx context.Context, channel *models.Channel) error
+	FindByID(ctx context.Context, id string) (*models.Channel, error)
+	FindByHandle(ctx context.Context, handle string) (*models.Channel, error)
+	// Update returns the updated channel, or ErrDuplicate if the new handle is taken
+	Update(ctx context.Context, id string, update ChannelUpdate) (*models.Channel, error)
+	SetBanner(ctx context.Context, id string, bannerURL string) (*models.Channel, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type mongoChannelRepo struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoChannelRepo) Create(ctx context.Context, channel *models.Channel) error {
+	_, err := r.collection.InsertOne(ctx, channel)
+	return translate(err)
+}
+
+func (r *mongoChannelRepo) FindByID(ctx context.Context, id string) (*models.Channel, error) {
+	var channel models.Channel
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&channel); err != nil {
+		return nil, translate(err)
+	}
+	return &channel, nil
+}
+
+func (r *mongoChannelRepo) FindByHandle(ctx context.Context, handle string) (*models.Channel, error) {
+	var channel models.Channel
+	if err := r.collection.FindOne(ctx, bson.M{"handle": handle}).Decode(&channel); err != nil {
+		return nil, translate(err)
+	}
+	return &channel, nil
+}
+
+func (r *mongoChannelRepo) Update(ctx context.Context, id string, update ChannelUpdate) (*models.Channel, error) {
+	fields := bson.M{}
+	if update.ChannelName != nil {
+		fields["channelName"] = *update.ChannelName
+	}
+	if update.Handle != nil {
+		fields["handle"] = *update.Handle
+	}
+	if update.Description != nil {
+		fields["description"] = *update.Description
+	}
+	if update.Links != nil {
+		fields["links"] = *update.Links
+	}
+	return r.set(ctx, id, fields)
+}
+
+func (r *mongoChannelRepo) SetBanner(ctx context.Context, id string, bannerURL string) (*models.Channel, error) {
+	return r.set(ctx, id, bson.M{"banner": bannerURL})
+}
+
+func (r *mongoChannelRepo) Delete(ctx context.Context, id string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// set updates the given fields, bumps updatedAt and returns the updated channel
+func (r *mongoChannelRepo) set(ctx context.Context, id string, fields bson.M) (*models.Channel, error) {
+	fields["updatedAt"] = time.Now()
+	var channel models.Channel
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": fields},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&channel)
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &channel, nil
+}
+
+type memChannelRepo struct {
+	table *memTable[models.Channel]
+}
+
+func (r *memChannelRepo) Create(ctx context.Context, channel *models.Channel) error {
+	// Matches the unique owner and handle indexes on the Mongo side
+	return r.table.insert(channel.ID, *channel, func(existing models.Channel) bool {
+		return channelsClash(*channel, existing)
+	})
+}
+
+func (r *memChannelRepo) FindByID(ctx context.Context, id string) (*models.Channel, error) {
+	channel, ok := r.table.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &channel, nil
+}
+
+func (r *memChannelRepo) FindByHandle(ctx context.Context, handle string) (*models.Channel, error) {
+	channel, ok := r.table.find(func(channel models.Channel) bool { return channel.Handle == handle })
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &channel, nil
+}
+
+func (r *memChannelRepo) Update(ctx context.Context, id string, update ChannelUpdate) (*models.Channel, error) {
+	return r.set(id, func(channel *models.Channel) {
+		if update.ChannelName != nil {
+			channel.ChannelName = *update.ChannelName
+		}
+		if update.Handle != nil {
+			channel.Handle = *update.Handle
+		}
+		if update.Description != nil {
+			channel.Description = *update.Description
+		}
+		if update.Links != nil {
+			channel.Links = *update.Links
+		}
+	})
+}
+
+func (r *memChannelRepo) SetBanner(ctx context.Context, id string, bannerURL string) (*models.Channel, error) {
+	return r.set(id, func(channel *models.Channel) { channel.Banner = bannerURL })
+}
+
+func (r *memChannelRepo) Delete(ctx context.Context, id string) error {
+	if !r.table.delete(id) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memChannelRepo) set(id string, fn func(channel *models.Channel)) (*models.Channel, error) {
+	var updated models.Channel
+	err := r.table.updateUnique(id, func(channel *models.Channel) {
+		fn(channel)
+		channel.UpdatedAt = time.Now()
+		updated = *channel
+	}, channelsClash)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// channelsClash reports whether two channels share an owner or a handle.
+// Channels from before owners and handles existed have neither.
+func channelsClash(a models.Channel, b models.Channel) bool {
+	return (a.OwnerID != "" && a.OwnerID == b.OwnerID) || (a.Handle != "" && a.Handle == b.Handle)
+}
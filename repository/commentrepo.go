@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CommentRepo stores video comments
+type CommentRepo interface {
+	Create(ctx context.Context, comment *models.VideoComment) error
+	FindByID(ctx context.Context, id string) (*models.VideoComment, error)
+	UpdateContent(ctx context.Context, id string, content string) error
+	Delete(ctx context.Context, id string) error
+	// ListByOwner returns the comments the user wrote, newest first
+	ListByOwner(ctx context.Context, userID string) ([]models.VideoComment, error)
+	DeleteByOwner(ctx context.Context, userID string) error
+	DeleteByVideo(ctx context.Context, videoID string) error
+}
+
+type mongoCommentRepo struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoCommentRepo) Create(ctx context.Context, comment *models.VideoComment) error {
+	stored := *comment
+	stored.Owner = nil
+	_, err := r.collection.InsertOne(ctx, stored)
+	return translate(err)
+}
+
+func (r *mongoCommentRepo) FindByID(ctx context.Context, id string) (*models.VideoComment, error) {
+	pipeline := append([]bson.M{{"$match": bson.M{"_id": id}}}, ownerLookup("ownerId")...)
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	comments := []models.VideoComment{}
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+	if len(comments) == 0 {
+		return nil, ErrNotFound
+	}
+	return &comments[0], nil
+}
+
+func (r *mongoCommentRepo) UpdateContent(ctx context.Context, id string, content string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"content":   content,
+			"updatedAt": time.Now(),
+		},
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoCommentRepo) Delete(ctx context.Context, id string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoCommentRepo) ListByOwner(ctx context.Context, userID string) ([]models.VideoComment, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"ownerId": userID}, options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		return nil, err
+	}
+	comments := []models.VideoComment{}
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (r *mongoCommentRepo) DeleteByOwner(ctx context.Context, userID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"ownerId": userID})
+	return err
+}
+
+func (r *mongoCommentRepo) DeleteByVideo(ctx context.Context, videoID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"videoId": videoID})
+	return err
+}
+
+type memCommentRepo struct {
+	table *memTable[models.VideoComment]
+	users *memTable[models.User]
+}
+
+func (r *memCommentRepo) Create(ctx context.Context, comment *models.VideoComment) error {
+	stored := *comment
+	stored.Owner = nil
+	return r.table.insert(comment.ID, stored, nil)
+}
+
+func (r *memCommentRepo) FindByID(ctx context.Context, id string) (*models.VideoComment, error) {
+	comment, ok := r.table.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	comment.Owner = userSummary(r.users, comment.OwnerID)
+	return &comment, nil
+}
+
+func (r *memCommentRepo) UpdateContent(ctx context.Context, id string, content string) error {
+	ok := r.table.update(id, func(comment *models.VideoComment) {
+		comment.Content = content
+		comment.UpdatedAt = time.Now()
+	})
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memCommentRepo) Delete(ctx context.Context, id string) error {
+	if !r.table.delete(id) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memCommentRepo) ListByOwner(ctx context.Context, userID string) ([]models.VideoComment, error) {
+	return r.table.filter(
+		func(comment models.VideoComment) bool { return comment.OwnerID == userID },
+		func(a, b models.VideoComment) bool { return a.CreatedAt.After(b.CreatedAt) },
+	), nil
+}
+
+func (r *memCommentRepo) DeleteByOwner(ctx context.Context, userID string) error {
+	r.table.deleteWhere(func(comment models.VideoComment) bool { return comment.OwnerID == userID })
+	return nil
+}
+
+func (r *memCommentRepo) DeleteByVideo(ctx context.Context, videoID string) error {
+	r.table.deleteWhere(func(comment models.VideoComment) bool { return comment.VideoID == videoID })
+	return nil
+}
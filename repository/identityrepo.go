@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IdentityRepo stores the external provider accounts linked to users
+type IdentityRepo interface {
+	// Create links the identity; ErrDuplicate means the provider account is already linked
+	Create(ctx context.Context, identity *models.Identity) error
+	FindByProviderSubject(ctx context.Context, provider string, subject string) (*models.Identity, error)
+	ListByUser(ctx context.Context, userID string) ([]models.Identity, error)
+	// Delete unlinks the identity, which must belong to userID
+	Delete(ctx context.Context, id string, userID string) error
+	TouchLogin(ctx context.Context, id string, at time.Time) error
+	DeleteByUser(ctx context.Context, userID string) error
+}
+
+type mongoIdentityRepo struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoIdentityRepo) Create(ctx context.Context, identity *models.Identity) error {
+	_, err := r.collection.InsertOne(ctx, identity)
+	return translate(err)
+}
+
+func (r *mongoIdentityRepo) FindByProviderSubject(ctx context.Context, provider string, subject string) (*models.Identity, error) {
+	var identity models.Identity
+	if err := r.collection.FindOne(ctx, bson.M{"provider": provider, "subject": subject}).Decode(&identity); err != nil {
+		return nil, translate(err)
+	}
+	return &identity, nil
+}
+
+func (r *mongoIdentityRepo) ListByUser(ctx context.Context, userID string) ([]models.Identity, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"userId": userID}, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		return nil, err
+	}
+	identities := []models.Identity{}
+	if err := cursor.All(ctx, &identities); err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+func (r *mongoIdentityRepo) Delete(ctx context.Context, id string, userID string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "userId": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoIdentityRepo) TouchLogin(ctx context.Context, id string, at time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"lastLoginAt": at}})
+	return err
+}
+
+func (r *mongoIdentityRepo) DeleteByUser(ctx context.Context, userID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	return err
+}
+
+type memIdentityRepo struct {
+	table *memTable[models.Identity]
+}
+
+func (r *memIdentityRepo) Create(ctx context.Context, identity *models.Identity) error {
+	// Matches the unique provider and subject index on the Mongo side
+	return r.table.insert(identity.ID, *identity, func(existing models.Identity) bool {
+		return existing.Provider == identity.Provider && existing.Subject == identity.Subject
+	})
+}
+
+func (r *memIdentityRepo) FindByProviderSubject(ctx context.Context, provider string, subject string) (*models.Identity, error) {
+	identity, ok := r.table.find(func(identity models.Identity) bool {
+		return identity.Provider == provider && identity.Subject == subject
+	})
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &identity, nil
+}
+
+func (r *memIdentityRepo) ListByUser(ctx context.Context, userID string) ([]models.Identity, error) {
+	return r.table.filter(
+		func(identity models.Identity) bool { return identity.UserID == userID },
+		func(a, b models.Identity) bool { return a.CreatedAt.Before(b.CreatedAt) },
+	), nil
+}
+
+func (r *memIdentityRepo) Delete(ctx context.Context, id string, userID string) error {
+	deleted := r.table.deleteWhere(func(identity models.Identity) bool {
+		return identity.ID == id && identity.UserID == userID
+	})
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memIdentityRepo) TouchLogin(ctx context.Context, id string, at time.Time) error {
+	r.table.update(id, func(identity *models.Identity) { identity.LastLoginAt = at })
+	return nil
+}
+
+func (r *memIdentityRepo) DeleteByUser(ctx context.Context, userID string) error {
+	r.table.deleteWhere(func(identity models.Identity) bool { return identity.UserID == userID })
+	return nil
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// JobRepo stores background jobs
+type JobRepo interface {
+	Create(ctx context.Context, job *models.Job) error
+	FindByID(ctx context.Context, id string) (*models.Job, error)
+	FindLatestByVideo(ctx context.Context, videoID string) (*models.Job, error)
+	// ClaimNext atomically picks a due job (or one whose worker lease expired),
+	// marks it running until lockedUntil and counts the attempt
+	ClaimNext(ctx context.Context, now time.Time, lockedUntil time.Time) (*models.Job, error)
+
+	// The methods below only change a job while the worker still holds the
+	// claim it made on the given attempt. Every claim counts an attempt, so once
+	// the lease has run out and another worker has claimed the job they return
+	// ErrNotFound.
+	UpdateProgress(ctx context.Context, id string, attempt int, step string, progress int) error
+	// Extend keeps the job claimed until lockedUntil
+	Extend(ctx context.Context, id string, attempt int, lockedUntil time.Time) error
+	Complete(ctx context.Context, id string, attempt int) error
+	Retry(ctx context.Context, id string, attempt int, errMsg string, runAt time.Time) error
+	Fail(ctx context.Context, id string, attempt int, errMsg string) error
+}
+
+type mongoJobRepo struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoJobRepo) Create(ctx context.Context, job *models.Job) error {
+	_, err := r.collection.InsertOne(ctx, job)
+	return translate(err)
+}
+
+func (r *mongoJobRepo) FindByID(ctx context.Context, id string) (*models.Job, error) {
+	var job models.Job
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&job); err != nil {
+		return nil, translate(err)
+	}
+	return &job, nil
+}
+
+func (r *mongoJobRepo) FindLatestByVideo(ctx context.Context, videoID string) (*models.Job, error) {
+	var job models.Job
+	opts := options.FindOne().SetSort(bson.M{"createdAt": -1})
+	if err := r.collection.FindOne(ctx, bson.M{"videoId": videoID}, opts).Decode(&job); err != nil {
+		return nil, translate(err)
+	}
+	return &job, nil
+}
+
+func (r *mongoJobRepo) ClaimNext(ctx context.Context, now time.Time, lockedUntil time.Time) (*models.Job, error) {
+	filter := bson.M{
+		"$or": []bson.M{
+			{"status": models.JobStatusQueued, "runAt": bson.M{"$lte": now}},
+			{"status": models.JobStatusRunning, "lockedUntil": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      models.JobStatusRunning,
+			"lockedUntil": lockedUntil,
+			"updatedAt":   now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"runAt": 1}).
+		SetReturnDocument(options.After)
+
+	var job models.Job
+	if err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job); err != nil {
+		return nil, translate(err)
+	}
+	return &job, nil
+}
+
+func (r *mongoJobRepo) UpdateProgress(ctx context.Context, id string, attempt int, step string, progress int) error {
+	return r.set(ctx, id, attempt, bson.M{"step": step, "progress": progress})
+}
+
+func (r *mongoJobRepo) Extend(ctx context.Context, id string, attempt int, lockedUntil time.Time) error {
+	return r.set(ctx, id, attempt, bson.M{"lockedUntil": lockedUntil})
+}
+
+func (r *mongoJobRepo) Complete(ctx context.Context, id string, attempt int) error {
+	return r.set(ctx, id, attempt, bson.M{
+		"status":     models.JobStatusSucceeded,
+		"progress":   100,
+		"error":      "",
+		"finishedAt": time.Now(),
+	})
+}
+
+func (r *mongoJobRepo) Retry(ctx context.Context, id string, attempt int, errMsg string, runAt time.Time) error {
+	return r.set(ctx, id, attempt, bson.M{
+		"status": models.JobStatusQueued,
+		"error":  errMsg,
+		"runAt":  runAt,
+	})
+}
+
+func (r *mongoJobRepo) Fail(ctx context.Context, id string, attempt int, errMsg string) error {
+	return r.set(ctx, id, attempt, bson.M{
+		"status":     models.JobStatusFailed,
+		"error":      errMsg,
+		"finishedAt": time.Now(),
+	})
+}
+
+// set updates a job the worker still holds the claim on
+func (r *mongoJobRepo) set(ctx context.Context, id string, attempt int, fields bson.M) error {
+	fields["updatedAt"] = time.Now()
+	filter := bson.M{"_id": id, "status": models.JobStatusRunning, "attempts": attempt}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memJobRepo struct {
+	table *memTable[models.Job]
+}
+
+func (r *memJobRepo) Create(ctx context.Context, job *models.Job) error {
+	return r.table.insert(job.ID, *job, nil)
+}
+
+func (r *memJobRepo) FindByID(ctx context.Context, id string) (*models.Job, error) {
+	job, ok := r.table.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &job, nil
+}
+
+func (r *memJobRepo) FindLatestByVideo(ctx context.Context, videoID string) (*models.Job, error) {
+	jobs := r.table.filter(
+		func(job models.Job) bool { return job.VideoID == videoID },
+		func(a, b models.Job) bool { return a.CreatedAt.After(b.CreatedAt) },
+	)
+	if len(jobs) == 0 {
+		return nil, ErrNotFound
+	}
+	return &jobs[0], nil
+}
+
+func (r *memJobRepo) ClaimNext(ctx context.Context, now time.Time, lockedUntil time.Time) (*models.Job, error) {
+	job, ok := r.table.updateFirst(
+		func(job models.Job) bool {
+			return (job.Status == models.JobStatusQueued && !job.RunAt.After(now)) ||
+				(job.Status == models.JobStatusRunning && job.LockedUntil.Before(now))
+		},
+		func(a, b models.Job) bool { return a.RunAt.Before(b.RunAt) },
+		func(job *models.Job) {
+			job.Status = models.JobStatusRunning
+			job.LockedUntil = lockedUntil
+			job.Attempts++
+			job.UpdatedAt = now
+		},
+	)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &job, nil
+}
+
+func (r *memJobRepo) UpdateProgress(ctx context.Context, id string, attempt int, step string, progress int) error {
+	return r.set(id, attempt, func(job *models.Job) {
+		job.Step = step
+		job.Progress = progress
+	})
+}
+
+func (r *memJobRepo) Extend(ctx context.Context, id string, attempt int, lockedUntil time.Time) error {
+	return r.set(id, attempt, func(job *models.Job) { job.LockedUntil = lockedUntil })
+}
+
+func (r *memJobRepo) Complete(ctx context.Context, id string, attempt int) error {
+	return r.set(id, attempt, func(job *models.Job) {
+		now := time.Now()
+		job.Status = models.JobStatusSucceeded
+		job.Progress = 100
+		job.Error = ""
+		job.FinishedAt = &now
+	})
+}
+
+func (r *memJobRepo) Retry(ctx context.Context, id string, attempt int, errMsg string, runAt time.Time) error {
+	return r.set(id, attempt, func(job *models.Job) {
+		job.Status = models.JobStatusQueued
+		job.Error = errMsg
+		job.RunAt = runAt
+	})
+}
+
+func (r *memJobRepo) Fail(ctx context.Context, id string, attempt int, errMsg string) error {
+	return r.set(id, attempt, func(job *models.Job) {
+		now := time.Now()
+		job.Status = models.JobStatusFailed
+		job.Error = errMsg
+		job.FinishedAt = &now
+	})
+}
+
+func (r *memJobRepo) set(id string, attempt int, fn func(job *models.Job)) error {
+	updated := r.table.updateWhere(
+		func(job models.Job) bool {
+			return job.ID == id && job.Status == models.JobStatusRunning && job.Attempts == attempt
+		},
+		func(job *models.Job) {
+			fn(job)
+			job.UpdatedAt = time.Now()
+		},
+	)
+	if updated == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"yt_backend/models"
+)
+
+func TestJobClaimIsGuardedByAttempt(t *testing.T) {
+	ctx := context.Background()
+	jobs := NewMemoryRepositories().Jobs
+	start := time.Now()
+
+	job := &models.Job{ID: "j1", Status: models.JobStatusQueued, MaxAttempts: 3, RunAt: start}
+	if err := jobs.Create(ctx, job); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	first, err := jobs.ClaimNext(ctx, start, start.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("ClaimNext: %v", err)
+	}
+	if first.Attempts != 1 || first.Status != models.JobStatusRunning {
+		t.Fatalf("claimed job = attempt %d, %s; want attempt 1, running", first.Attempts, first.Status)
+	}
+	if _, err := jobs.ClaimNext(ctx, start, start.Add(time.Minute)); err != ErrNotFound {
+		t.Fatalf("claiming a leased job = %v, want ErrNotFound", err)
+	}
+
+	// A heartbeat keeps the job away from other workers
+	if err := jobs.Extend(ctx, "j1", first.Attempts, start.Add(3*time.Minute)); err != nil {
+		t.Fatalf("Extend: %v", err)
+	}
+	if _, err := jobs.ClaimNext(ctx, start.Add(2*time.Minute), start.Add(4*time.Minute)); err != ErrNotFound {
+		t.Fatalf("claiming an extended job = %v, want ErrNotFound", err)
+	}
+
+	// Once the lease runs out another worker takes over
+	second, err := jobs.ClaimNext(ctx, start.Add(5*time.Minute), start.Add(6*time.Minute))
+	if err != nil {
+		t.Fatalf("reclaiming an expired job: %v", err)
+	}
+	if second.Attempts != 2 {
+		t.Fatalf("reclaimed job attempt = %d, want 2", second.Attempts)
+	}
+
+	// and the first worker can no longer change it
+	if err := jobs.UpdateProgress(ctx, "j1", first.Attempts, "stale", 50); err != ErrNotFound {
+		t.Errorf("stale UpdateProgress = %v, want ErrNotFound", err)
+	}
+	if err := jobs.Extend(ctx, "j1", first.Attempts, start.Add(time.Hour)); err != ErrNotFound {
+		t.Errorf("stale Extend = %v, want ErrNotFound", err)
+	}
+	if err := jobs.Complete(ctx, "j1", first.Attempts); err != ErrNotFound {
+		t.Errorf("stale Complete = %v, want ErrNotFound", err)
+	}
+	if err := jobs.Fail(ctx, "j1", first.Attempts, "stale"); err != ErrNotFound {
+		t.Errorf("stale Fail = %v, want ErrNotFound", err)
+	}
+
+	if err := jobs.Complete(ctx, "j1", second.Attempts); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	done, _ := jobs.FindByID(ctx, "j1")
+	if done.Status != models.JobStatusSucceeded || done.Step == "stale" {
+		t.Errorf("finished job = %s at step %q, want succeeded without the stale progress", done.Status, done.Step)
+	}
+	if err := jobs.Retry(ctx, "j1", second.Attempts, "late", time.Now()); err != ErrNotFound {
+		t.Errorf("Retry after Complete = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LikeRepo stores video likes
+type LikeRepo interface {
+	Create(ctx context.Context, like *models.Like) error
+	Exists(ctx context.Context, userID string, videoID string) (bool, error)
+	Delete(ctx context.Context, userID string, videoID string) error
+	CountByVideo(ctx context.Context, videoID string) (int64, error)
+	// ListByUser returns the user's likes, newest first
+	ListByUser(ctx context.Context, userID string) ([]models.Like, error)
+	DeleteByVideo(ctx context.Context, videoID string) error
+}
+
+type mongoLikeRepo struct {
+	collection *mongo.Collection
+}
+
+func likeFilter(userID string, videoID string) bson.M {
+	return bson.M{
+		"userId":  userID,
+		"videoId": videoID,
+	}
+}
+
+func (r *mongoLikeRepo) Create(ctx context.Context, like *models.Like) error {
+	_, err := r.collection.InsertOne(ctx, like)
+	return translate(err)
+}
+
+func (r *mongoLikeRepo) Exists(ctx context.Context, userID string, videoID string) (bool, error) {
+	err := r.collection.FindOne(ctx, likeFilter(userID, videoID)).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *mongoLikeRepo) Delete(ctx context.Context, userID string, videoID string) error {
+	result, err := r.collection.DeleteOne(ctx, likeFilter(userID, videoID))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoLikeRepo) CountByVideo(ctx context.Context, videoID string) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"videoId": videoID})
+}
+
+func (r *mongoLikeRepo) ListByUser(ctx context.Context, userID string) ([]models.Like, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"userId": userID}, options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		return nil, err
+	}
+	likes := []models.Like{}
+	if err := cursor.All(ctx, &likes); err != nil {
+		return nil, err
+	}
+	return likes, nil
+}
+
+func (r *mongoLikeRepo) DeleteByVideo(ctx context.Context, videoID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"videoId": videoID})
+	return err
+}
+
+type memLikeRepo struct {
+	table *memTable[models.Like]
+}
+
+func (r *memLikeRepo) Create(ctx context.Context, like *models.Like) error {
+	return r.table.insert(like.ID, *like, func(existing models.Like) bool {
+		return existing.UserID == like.UserID && existing.VideoID == like.VideoID
+	})
+}
+
+func (r *memLikeRepo) Exists(ctx context.Context, userID string, videoID string) (bool, error) {
+	_, ok := r.table.find(func(like models.Like) bool {
+		return like.UserID == userID && like.VideoID == videoID
+	})
+	return ok, nil
+}
+
+func (r *memLikeRepo) Delete(ctx context.Context, userID string, videoID string) error {
+	deleted := r.table.deleteWhere(func(like models.Like) bool {
+		return like.UserID == userID && like.VideoID == videoID
+	})
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memLikeRepo) CountByVideo(ctx context.Context, videoID string) (int64, error) {
+	return r.table.count(func(like models.Like) bool { return like.VideoID == videoID }), nil
+}
+
+func (r *memLikeRepo) ListByUser(ctx context.Context, userID string) ([]models.Like, error) {
+	return r.table.filter(
+		func(like models.Like) bool { return like.UserID == userID },
+		func(a, b models.Like) bool { return a.CreatedAt.After(b.CreatedAt) },
+	), nil
+}
+
+func (r *memLikeRepo) DeleteByVideo(ctx context.Context, videoID string) error {
+	r.table.deleteWhere(func(like models.Like) bool { return like.VideoID == videoID })
+	return nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LoginThrottleRepo stores failed login counters per account and per IP address
+type LoginThrottleRepo interface {
+	// Find returns the counter, or ErrNotFound if there is none or it has expired
+	Find(ctx context.Context, id string, now time.Time) (*models.LoginThrottle, error)
+	// RecordFailure counts a failed login and returns the updated counter. An
+	// expired counter starts again from zero; the counter expires after forgetAfter.
+	RecordFailure(ctx context.Context, id string, now time.Time, forgetAfter time.Duration) (*models.LoginThrottle, error)
+	Lock(ctx context.Context, id string, until time.Time) error
+	Delete(ctx context.Context, id string) error
+}
+
+type mongoLoginThrottleRepo struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoLoginThrottleRepo) Find(ctx context.Context, id string, now time.Time) (*models.LoginThrottle, error) {
+	var throttle models.LoginThrottle
+	filter := bson.M{"_id": id, "expiresAt": bson.M{"$gt": now}}
+	if err := r.collection.FindOne(ctx, filter).Decode(&throttle); err != nil {
+		return nil, translate(err)
+	}
+	return &throttle, nil
+}
+
+func (r *mongoLoginThrottleRepo) RecordFailure(ctx context.Context, id string, now time.Time, forgetAfter time.Duration) (*models.LoginThrottle, error) {
+	// The TTL monitor only runs every minute, so clear an expired counter ourselves
+	if _, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "expiresAt": bson.M{"$lte": now}}); err != nil {
+		return nil, err
+	}
+
+	var throttle models.LoginThrottle
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$inc": bson.M{"failures": 1},
+			"$set": bson.M{"lastFailureAt": now, "expiresAt": now.Add(forgetAfter)},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&throttle)
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &throttle, nil
+}
+
+func (r *mongoLoginThrottleRepo) Lock(ctx context.Context, id string, until time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"lockedUntil": until}})
+	return err
+}
+
+func (r *mongoLoginThrottleRepo) Delete(ctx context.Context, id string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+type memLoginThrottleRepo struct {
+	table *memTable[models.LoginThrottle]
+}
+
+func (r *memLoginThrottleRepo) Find(ctx context.Context, id string, now time.Time) (*models.LoginThrottle, error) {
+	throttle, ok := r.table.get(id)
+	if !ok || !now.Before(throttle.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	return &throttle, nil
+}
+
+func (r *memLoginThrottleRepo) RecordFailure(ctx context.Context, id string, now time.Time, forgetAfter time.Duration) (*models.LoginThrottle, error) {
+	r.table.deleteWhere(func(throttle models.LoginThrottle) bool {
+		return throttle.ID == id && !now.Before(throttle.ExpiresAt)
+	})
+
+	// Insert the first failure, or count another one if the row already exists
+	first := models.LoginThrottle{ID: id, Failures: 1, LastFailureAt: now, ExpiresAt: now.Add(forgetAfter)}
+	if err := r.table.insert(id, first, nil); err == nil {
+		return &first, nil
+	}
+	var throttle models.LoginThrottle
+	r.table.update(id, func(row *models.LoginThrottle) {
+		row.Failures++
+		row.LastFailureAt = now
+		row.ExpiresAt = now.Add(forgetAfter)
+		throttle = *row
+	})
+	return &throttle, nil
+}
+
+func (r *memLoginThrottleRepo) Lock(ctx context.Context, id string, until time.Time) error {
+	r.table.update(id, func(throttle *models.LoginThrottle) { throttle.LockedUntil = &until })
+	return nil
+}
+
+func (r *memLoginThrottleRepo) Delete(ctx context.Context, id string) error {
+	r.table.delete(id)
+	return nil
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+)
+
+// memTable is a mutex guarded map used by the in-memory repositories.
+// Rows are stored and returned by value so callers never share state
+// with the table.
+type memTable[T any] struct {
+	mu   sync.RWMutex
+	rows map[string]T
+}
+
+func newMemTable[T any]() *memTable[T] {
+	return &memTable[T]{rows: make(map[string]T)}
+}
+
+func (t *memTable[T]) get(id string) (T, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	row, ok := t.rows[id]
+	return row, ok
+}
+
+// insert adds a row unless the id is taken or conflicts reports a clash with an existing row
+func (t *memTable[T]) insert(id string, row T, conflicts func(existing T) bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.rows[id]; ok {
+		return ErrDuplicate
+	}
+	if conflicts != nil {
+		for _, existing := range t.rows {
+			if conflicts(existing) {
+				return ErrDuplicate
+			}
+		}
+	}
+	t.rows[id] = row
+	return nil
+}
+
+// update applies fn to the row with the given id
+func (t *memTable[T]) update(id string, fn func(row *T)) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	row, ok := t.rows[id]
+	if !ok {
+		return false
+	}
+	fn(&row)
+	t.rows[id] = row
+	return true
+}
+
+// updateUnique applies fn to the row with the given id unless the changed row
+// conflicts with another one; it returns ErrNotFound or ErrDuplicate otherwise
+func (t *memTable[T]) updateUnique(id string, fn func(row *T), conflicts func(updated T, existing T) bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	row, ok := t.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	fn(&row)
+	for otherID, existing := range t.rows {
+		if otherID != id && conflicts(row, existing) {
+			return ErrDuplicate
+		}
+	}
+	t.rows[id] = row
+	return nil
+}
+
+// updateWhere applies fn to every row matching the predicate and returns the number of rows changed
+func (t *memTable[T]) updateWhere(match func(row T) bool, fn func(row *T)) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	count := 0
+	for id, row := range t.rows {
+		if match(row) {
+			fn(&row)
+			t.rows[id] = row
+			count++
+		}
+	}
+	return count
+}
+
+// updateFirst applies fn to the first row (ordered by less) matching the predicate
+// and returns the updated row, all under a single lock
+func (t *memTable[T]) updateFirst(match func(row T) bool, less func(a, b T) bool, fn func(row *T)) (T, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var firstID string
+	var first T
+	found := false
+	for id, row := range t.rows {
+		if match(row) && (!found || less(row, first)) {
+			firstID, first, found = id, row, true
+		}
+	}
+	if !found {
+		return first, false
+	}
+	fn(&first)
+	t.rows[firstID] = first
+	return first, true
+}
+
+func (t *memTable[T]) delete(id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.rows[id]; !ok {
+		return false
+	}
+	delete(t.rows, id)
+	return true
+}
+
+// deleteWhere removes every row matching the predicate and returns the number removed
+func (t *memTable[T]) deleteWhere(match func(row T) bool) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	count := 0
+	for id, row := range t.rows {
+		if match(row) {
+			delete(t.rows, id)
+			count++
+		}
+	}
+	return count
+}
+
+// find returns the first row matching the predicate
+func (t *memTable[T]) find(match func(row T) bool) (T, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, row := range t.rows {
+		if match(row) {
+			return row, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// filter returns every row matching the predicate, ordered by less when given
+func (t *memTable[T]) filter(match func(row T) bool, less func(a, b T) bool) []T {
+	t.mu.RLock()
+	rows := make([]T, 0)
+	for _, row := range t.rows {
+		if match == nil || match(row) {
+			rows = append(rows, row)
+		}
+	}
+	t.mu.RUnlock()
+
+	if less != nil {
+		sort.SliceStable(rows, func(i, j int) bool { return less(rows[i], rows[j]) })
+	}
+	return rows
+}
+
+func (t *memTable[T]) count(match func(row T) bool) int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var count int64
+	for _, row := range t.rows {
+		if match(row) {
+			count++
+		}
+	}
+	return count
+}
+
+// page applies skip and limit to an already ordered slice
+func page[T any](rows []T, skip int64, limit int64) []T {
+	if skip < 0 {
+		skip = 0
+	}
+	if skip >= int64(len(rows)) {
+		return []T{}
+	}
+	rows = rows[skip:]
+	if limit > 0 && limit < int64(len(rows)) {
+		rows = rows[:limit]
+	}
+	return rows
+}
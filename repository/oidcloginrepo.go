@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// OIDCLoginRepo stores sign-ins with external providers that are in progress
+type OIDCLoginRepo interface {
+	Create(ctx context.Context, login *models.OIDCLogin) error
+	// Consume deletes and returns the login if it exists, is for the given
+	// provider and hasn't expired; otherwise it returns ErrNotFound
+	Consume(ctx context.Context, id string, provider string, now time.Time) (*models.OIDCLogin, error)
+}
+
+type mongoOIDCLoginRepo struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoOIDCLoginRepo) Create(ctx context.Context, login *models.OIDCLogin) error {
+	_, err := r.collection.InsertOne(ctx, login)
+	return translate(err)
+}
+
+func (r *mongoOIDCLoginRepo) Consume(ctx context.Context, id string, provider string, now time.Time) (*models.OIDCLogin, error) {
+	var login models.OIDCLogin
+	filter := bson.M{"_id": id, "provider": provider, "expiresAt": bson.M{"$gt": now}}
+	if err := r.collection.FindOneAndDelete(ctx, filter).Decode(&login); err != nil {
+		return nil, translate(err)
+	}
+	return &login, nil
+}
+
+type memOIDCLoginRepo struct {
+	table *memTable[models.OIDCLogin]
+}
+
+func (r *memOIDCLoginRepo) Create(ctx context.Context, login *models.OIDCLogin) error {
+	// Drop abandoned logins, as the TTL index does on the Mongo side
+	now := time.Now()
+	r.table.deleteWhere(func(row models.OIDCLogin) bool { return !now.Before(row.ExpiresAt) })
+	return r.table.insert(login.ID, *login, nil)
+}
+
+func (r *memOIDCLoginRepo) Consume(ctx context.Context, id string, provider string, now time.Time) (*models.OIDCLogin, error) {
+	consumed := false
+	var login models.OIDCLogin
+	r.table.deleteWhere(func(row models.OIDCLogin) bool {
+		if row.ID != id || row.Provider != provider || !now.Before(row.ExpiresAt) {
+			return false
+		}
+		consumed, login = true, row
+		return true
+	})
+	if !consumed {
+		return nil, ErrNotFound
+	}
+	return &login, nil
+}
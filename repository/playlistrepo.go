@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PlaylistRepo stores playlists
+type PlaylistRepo interface {
+	Create(ctx context.Context, playlist *models.Playlist) error
+	FindByID(ctx context.Context, id string) (*models.Playlist, error)
+	AddVideo(ctx context.Context, id string, videoID string) error
+	RemoveVideo(ctx context.Context, id string, videoID string) error
+	Delete(ctx context.Context, id string) error
+	// ListByUser returns the user's playlists, newest first
+	ListByUser(ctx context.Context, userID string) ([]models.Playlist, error)
+	DeleteByUser(ctx context.Context, userID string) error
+}
+
+type mongoPlaylistRepo struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoPlaylistRepo) Create(ctx context.Context, playlist *models.Playlist) error {
+	_, err := r.collection.InsertOne(ctx, playlist)
+	return translate(err)
+}
+
+func (r *mongoPlaylistRepo) FindByID(ctx context.Context, id string) (*models.Playlist, error) {
+	var playlist models.Playlist
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&playlist); err != nil {
+		return nil, translate(err)
+	}
+	return &playlist, nil
+}
+
+func (r *mongoPlaylistRepo) AddVideo(ctx context.Context, id string, videoID string) error {
+	// Update playlist in database using $push operator
+	return r.update(ctx, id, bson.M{
+		"$push": bson.M{
+			"videoIds": videoID,
+		},
+		"$set": bson.M{
+			"updatedAt": time.Now(),
+		},
+	})
+}
+
+func (r *mongoPlaylistRepo) RemoveVideo(ctx context.Context, id string, videoID string) error {
+	// Update playlist in database using $pull operator
+	return r.update(ctx, id, bson.M{
+		"$pull": bson.M{
+			"videoIds": videoID,
+		},
+		"$set": bson.M{
+			"updatedAt": time.Now(),
+		},
+	})
+}
+
+func (r *mongoPlaylistRepo) Delete(ctx context.Context, id string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoPlaylistRepo) ListByUser(ctx context.Context, userID string) ([]models.Playlist, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"userId": userID}, options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		return nil, err
+	}
+	playlists := []models.Playlist{}
+	if err := cursor.All(ctx, &playlists); err != nil {
+		return nil, err
+	}
+	return playlists, nil
+}
+
+func (r *mongoPlaylistRepo) DeleteByUser(ctx context.Context, userID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	return err
+}
+
+func (r *mongoPlaylistRepo) update(ctx context.Context, id string, update bson.M) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memPlaylistRepo struct {
+	table *memTable[models.Playlist]
+}
+
+func (r *memPlaylistRepo) Create(ctx context.Context, playlist *models.Playlist) error {
+	row := *playlist
+	row.VideoIDs = slices.Clone(playlist.VideoIDs)
+	return r.table.insert(playlist.ID, row, nil)
+}
+
+func (r *memPlaylistRepo) FindByID(ctx context.Context, id string) (*models.Playlist, error) {
+	playlist, ok := r.table.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	playlist.VideoIDs = slices.Clone(playlist.VideoIDs)
+	return &playlist, nil
+}
+
+func (r *memPlaylistRepo) AddVideo(ctx context.Context, id string, videoID string) error {
+	ok := r.table.update(id, func(playlist *models.Playlist) {
+		playlist.VideoIDs = append(slices.Clone(playlist.VideoIDs), videoID)
+		playlist.UpdatedAt = time.Now()
+	})
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memPlaylistRepo) RemoveVideo(ctx context.Context, id string, videoID string) error {
+	ok := r.table.update(id, func(playlist *models.Playlist) {
+		playlist.VideoIDs = slices.DeleteFunc(slices.Clone(playlist.VideoIDs), func(existing string) bool {
+			return existing == videoID
+		})
+		playlist.UpdatedAt = time.Now()
+	})
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memPlaylistRepo) Delete(ctx context.Context, id string) error {
+	if !r.table.delete(id) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memPlaylistRepo) ListByUser(ctx context.Context, userID string) ([]models.Playlist, error) {
+	playlists := r.table.filter(
+		func(playlist models.Playlist) bool { return playlist.UserID == userID },
+		func(a, b models.Playlist) bool { return a.CreatedAt.After(b.CreatedAt) },
+	)
+	for i := range playlists {
+		playlists[i].VideoIDs = slices.Clone(playlists[i].VideoIDs)
+	}
+	return playlists, nil
+}
+
+func (r *memPlaylistRepo) DeleteByUser(ctx context.Context, userID string) error {
+	r.table.deleteWhere(func(playlist models.Playlist) bool { return playlist.UserID == userID })
+	return nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Documents point at users, videos and channels by ID. The stages below join
+// the referenced documents back in when reading, so a rename or new avatar
+// shows up everywhere without rewriting the documents that mention the user.
+
+// ownerLookup joins the public fields of the user whose ID is in localField as "owner"
+func ownerLookup(localField string) []bson.M {
+	return []bson.M{
+		{
+			"$lookup": bson.M{
+				"from":         "users",
+				"localField":   localField,
+				"foreignField": "_id",
+				"pipeline":     []bson.M{{"$project": bson.M{"username": 1, "avatar": 1}}},
+				"as":           "owner",
+			},
+		},
+		{
+			"$unwind": bson.M{"path": "$owner", "preserveNullAndEmptyArrays": true},
+		},
+	}
+}
+
+// channelLookup joins the channel whose ID is in localField as "channel"
+func channelLookup(localField string) []bson.M {
+	return []bson.M{
+		{
+			"$lookup": bson.M{
+				"from":         "channels",
+				"localField":   localField,
+				"foreignField": "_id",
+				"as":           "channel",
+			},
+		},
+		{
+			"$unwind": bson.M{"path": "$channel", "preserveNullAndEmptyArrays": true},
+		},
+	}
+}
+
+// videoLookups hydrates the owner and channel of video documents
+func videoLookups() []bson.M {
+	return append(ownerLookup("ownerId"), channelLookup("channelId")...)
+}
+
+// userSummary looks up the public fields of a user in the in-memory table
+func userSummary(users *memTable[models.User], id string) *models.UserSummary {
+	user, ok := users.get(id)
+	if !ok {
+		return nil
+	}
+	return user.Summary()
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"errors"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrNotFound is returned when a lookup matches no document
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when an insert would break a uniqueness rule
+	ErrDuplicate = errors.New("duplicate")
+)
+
+// Repositories groups every repository used by the controllers
+type Repositories struct {
+	Users          UserRepo
+	Channels       ChannelRepo
+	Videos         VideoRepo
+	Likes          LikeRepo
+	Subscriptions  SubscriptionRepo
+	Comments       CommentRepo
+	Playlists      PlaylistRepo
+	WatchHistory   WatchHistoryRepo
+	TokenBlacklist TokenBlacklistRepo
+	UploadSessions UploadSessionRepo
+	Jobs           JobRepo
+	Sessions       SessionRepo
+	AuditLogs      AuditLogRepo
+	UserTokens     UserTokenRepo
+	Identities     IdentityRepo
+	OIDCLogins     OIDCLoginRepo
+	TwoFactor      TwoFactorRepo
+	LoginThrottles LoginThrottleRepo
+	APIKeys        APIKeyRepo
+}
+
+// NewMongoRepositories creates repositories backed by the given database
+func NewMongoRepositories(database *mongo.Database) *Repositories {
+	return &Repositories{
+		Users:          &mongoUserRepo{collection: database.Collection("users")},
+		Channels:       &mongoChannelRepo{collection: database.Collection("channels")},
+		Videos:         &mongoVideoRepo{collection: database.Collection("videos")},
+		Likes:          &mongoLikeRepo{collection: database.Collection("likes")},
+		Subscriptions:  &mongoSubscriptionRepo{collection: database.Collection("subscriptions")},
+		Comments:       &mongoCommentRepo{collection: database.Collection("videocomments")},
+		Playlists:      &mongoPlaylistRepo{collection: database.Collection("playlists")},
+		WatchHistory:   &mongoWatchHistoryRepo{collection: database.Collection("video_watches")},
+		TokenBlacklist: &mongoTokenBlacklistRepo{collection: database.Collection("token_blacklist")},
+		UploadSessions: &mongoUploadSessionRepo{collection: database.Collection("upload_sessions")},
+		Jobs:           &mongoJobRepo{collection: database.Collection("jobs")},
+		Sessions:       &mongoSessionRepo{collection: database.Collection("sessions")},
+		AuditLogs:      &mongoAuditLogRepo{collection: database.Collection("audit_logs")},
+		UserTokens:     &mongoUserTokenRepo{collection: database.Collection("user_tokens")},
+		Identities:     &mongoIdentityRepo{collection: database.Collection("identities")},
+		OIDCLogins:     &mongoOIDCLoginRepo{collection: database.Collection("oidc_logins")},
+		TwoFactor:      &mongoTwoFactorRepo{collection: database.Collection("two_factor")},
+		LoginThrottles: &mongoLoginThrottleRepo{collection: database.Collection("login_throttles")},
+		APIKeys:        &mongoAPIKeyRepo{collection: database.Collection("api_keys")},
+	}
+}
+
+// NewMemoryRepositories creates thread-safe in-memory repositories for tests and local demos
+func NewMemoryRepositories() *Repositories {
+	users := &memUserRepo{table: newMemTable[models.User]()}
+	channels := &memChannelRepo{table: newMemTable[models.Channel]()}
+	videos := &memVideoRepo{table: newMemTable[models.Video](), users: users.table, channels: channels.table}
+	return &Repositories{
+		Users:          users,
+		Channels:       channels,
+		Videos:         videos,
+		Likes:          &memLikeRepo{table: newMemTable[models.Like]()},
+		Subscriptions:  &memSubscriptionRepo{table: newMemTable[models.Subscription](), channels: channels},
+		Comments:       &memCommentRepo{table: newMemTable[models.VideoComment](), users: users.table},
+		Playlists:      &memPlaylistRepo{table: newMemTable[models.Playlist]()},
+		WatchHistory:   &memWatchHistoryRepo{table: newMemTable[models.VideoWatchEntry](), videos: videos},
+		TokenBlacklist: &memTokenBlacklistRepo{table: newMemTable[models.TokenBlacklist]()},
+		UploadSessions: &memUploadSessionRepo{table: newMemTable[models.UploadSession]()},
+		Jobs:           &memJobRepo{table: newMemTable[models.Job]()},
+		Sessions:       &memSessionRepo{table: newMemTable[models.Session]()},
+		AuditLogs:      &memAuditLogRepo{table: newMemTable[models.AuditLog]()},
+		UserTokens:     &memUserTokenRepo{table: newMemTable[models.UserToken]()},
+		Identities:     &memIdentityRepo{table: newMemTable[models.Identity]()},
+		OIDCLogins:     &memOIDCLoginRepo{table: newMemTable[models.OIDCLogin]()},
+		TwoFactor:      &memTwoFactorRepo{table: newMemTable[models.TwoFactor]()},
+		LoginThrottles: &memLoginThrottleRepo{table: newMemTable[models.LoginThrottle]()},
+		APIKeys:        &memAPIKeyRepo{table: newMemTable[models.APIKey]()},
+	}
+}
+
+// translate maps driver errors onto the repository errors
+func translate(err error) error {
+	if err == mongo.ErrNoDocuments {
+		return ErrNotFound
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"yt_backend/models"
+)
+
+func TestMemoryUsersAreUniqueByUsernameAndEmail(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+
+	alice := &models.User{ID: "u1", Username: "alice", Email: "alice@example.com"}
+	if err := repos.Users.Create(ctx, alice); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	for _, user := range []*models.User{
+		{ID: "u1", Username: "other", Email: "other@example.com"},
+		{ID: "u2", Username: "alice", Email: "other@example.com"},
+		{ID: "u3", Username: "other", Email: "alice@example.com"},
+	} {
+		if err := repos.Users.Create(ctx, user); err != ErrDuplicate {
+			t.Errorf("Create(%s, %s, %s) = %v, want ErrDuplicate", user.ID, user.Username, user.Email, err)
+		}
+	}
+
+	found, err := repos.Users.FindByUsername(ctx, "alice")
+	if err != nil || found.ID != "u1" {
+		t.Fatalf("FindByUsername = %v, %v; want u1", found, err)
+	}
+	if _, err := repos.Users.FindByID(ctx, "missing"); err != ErrNotFound {
+		t.Errorf("FindByID(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryRowsAreCopies(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+
+	user := &models.User{ID: "u1", Username: "alice", Email: "alice@example.com"}
+	if err := repos.Users.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	user.Username = "changed"
+
+	found, err := repos.Users.FindByID(ctx, "u1")
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	found.Username = "changed again"
+
+	again, _ := repos.Users.FindByID(ctx, "u1")
+	if again.Username != "alice" {
+		t.Errorf("stored username = %q, want it unaffected by callers", again.Username)
+	}
+}
+
+func TestMemoryLikesAndSubscriptionsAreUnique(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+
+	if err := repos.Likes.Create(ctx, &models.Like{ID: "l1", UserID: "u1", VideoID: "v1"}); err != nil {
+		t.Fatalf("Likes.Create: %v", err)
+	}
+	if err := repos.Likes.Create(ctx, &models.Like{ID: "l2", UserID: "u1", VideoID: "v1"}); err != ErrDuplicate {
+		t.Errorf("second like = %v, want ErrDuplicate", err)
+	}
+	if err := repos.Likes.Delete(ctx, "u1", "v1"); err != nil {
+		t.Fatalf("Likes.Delete: %v", err)
+	}
+	if err := repos.Likes.Delete(ctx, "u1", "v1"); err != ErrNotFound {
+		t.Errorf("deleting a missing like = %v, want ErrNotFound", err)
+	}
+
+	subscription := &models.Subscription{ID: "s1", SubscriberID: "u1", ChannelID: "c1", CreatedAt: time.Now()}
+	if err := repos.Subscriptions.Create(ctx, subscription); err != nil {
+		t.Fatalf("Subscriptions.Create: %v", err)
+	}
+	subscription.ID = "s2"
+	if err := repos.Subscriptions.Create(ctx, subscription); err != ErrDuplicate {
+		t.Errorf("second subscription = %v, want ErrDuplicate", err)
+	}
+	if count, _ := repos.Subscriptions.CountByChannel(ctx, "c1"); count != 1 {
+		t.Errorf("CountByChannel = %d, want 1", count)
+	}
+}
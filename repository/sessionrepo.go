@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxPreviousHashes bounds how many rotated refresh tokens a session remembers for reuse detection
+const maxPreviousHashes = 20
+
+// SessionRepo stores signed-in device sessions
+type SessionRepo interface {
+	Create(ctx context.Context, session *models.Session) error
+	FindByID(ctx context.Context, id string) (*models.Session, error)
+	// Rotate swaps the refresh token hash, but only if oldHash is still the
+	// current one and the session is active; otherwise it returns ErrNotFound
+	Rotate(ctx context.Context, id string, oldHash string, newHash string, expiresAt time.Time) (*models.Session, error)
+	Revoke(ctx context.Context, id string, reason string) error
+	// RevokeAllForUser revokes every active session of the user except exceptID
+	RevokeAllForUser(ctx context.Context, userID string, exceptID string, reason string) (int64, error)
+	ListActiveByUser(ctx context.Context, userID string, now time.Time) ([]models.Session, error)
+	// MarkMFAVerified records that the user entered their second factor on an active session
+	MarkMFAVerified(ctx context.Context, id string, at time.Time) error
+	DeleteByUser(ctx context.Context, userID string) error
+}
+
+type mongoSessionRepo struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoSessionRepo) Create(ctx context.Context, session *models.Session) error {
+	_, err := r.collection.InsertOne(ctx, session)
+	return translate(err)
+}
+
+func (r *mongoSessionRepo) FindByID(ctx context.Context, id string) (*models.Session, error) {
+	var session models.Session
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&session); err != nil {
+		return nil, translate(err)
+	}
+	return &session, nil
+}
+
+func (r *mongoSessionRepo) Rotate(ctx context.Context, id string, oldHash string, newHash string, expiresAt time.Time) (*models.Session, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id":        id,
+		"secretHash": oldHash,
+		"revokedAt":  nil,
+		"expiresAt":  bson.M{"$gt": now},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"secretHash": newHash,
+			"lastUsedAt": now,
+			"expiresAt":  expiresAt,
+		},
+		"$push": bson.M{
+			"previousHashes": bson.M{"$each": bson.A{oldHash}, "$slice": -maxPreviousHashes},
+		},
+	}
+
+	var session models.Session
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&session); err != nil {
+		return nil, translate(err)
+	}
+	return &session, nil
+}
+
+func (r *mongoSessionRepo) Revoke(ctx context.Context, id string, reason string) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "revokedAt": nil},
+		bson.M{"$set": bson.M{"revokedAt": time.Now(), "revokedReason": reason}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoSessionRepo) RevokeAllForUser(ctx context.Context, userID string, exceptID string, reason string) (int64, error) {
+	filter := bson.M{"userId": userID, "revokedAt": nil}
+	if exceptID != "" {
+		filter["_id"] = bson.M{"$ne": exceptID}
+	}
+	result, err := r.collection.UpdateMany(ctx, filter,
+		bson.M{"$set": bson.M{"revokedAt": time.Now(), "revokedReason": reason}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *mongoSessionRepo) ListActiveByUser(ctx context.Context, userID string, now time.Time) ([]models.Session, error) {
+	filter := bson.M{"userId": userID, "revokedAt": nil, "expiresAt": bson.M{"$gt": now}}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"lastUsedAt": -1}))
+	if err != nil {
+		return nil, err
+	}
+	sessions := []models.Session{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *mongoSessionRepo) MarkMFAVerified(ctx context.Context, id string, at time.Time) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "revokedAt": nil},
+		bson.M{"$set": bson.M{"mfaVerifiedAt": at}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoSessionRepo) DeleteByUser(ctx context.Context, userID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	return err
+}
+
+type memSessionRepo struct {
+	table *memTable[models.Session]
+}
+
+func (r *memSessionRepo) Create(ctx context.Context, session *models.Session) error {
+	return r.table.insert(session.ID, *session, nil)
+}
+
+func (r *memSessionRepo) FindByID(ctx context.Context, id string) (*models.Session, error) {
+	session, ok := r.table.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+func (r *memSessionRepo) Rotate(ctx context.Context, id string, oldHash string, newHash string, expiresAt time.Time) (*models.Session, error) {
+	now := time.Now()
+	rotated := false
+	var result models.Session
+	r.table.update(id, func(session *models.Session) {
+		if session.SecretHash != oldHash || !session.Active(now) {
+			return
+		}
+		session.PreviousHashes = append(session.PreviousHashes, oldHash)
+		if len(session.PreviousHashes) > maxPreviousHashes {
+			session.PreviousHashes = session.PreviousHashes[len(session.PreviousHashes)-maxPreviousHashes:]
+		}
+		session.SecretHash = newHash
+		session.LastUsedAt = now
+		session.ExpiresAt = expiresAt
+		rotated = true
+		result = *session
+	})
+	if !rotated {
+		return nil, ErrNotFound
+	}
+	return &result, nil
+}
+
+func (r *memSessionRepo) Revoke(ctx context.Context, id string, reason string) error {
+	revoked := false
+	r.table.update(id, func(session *models.Session) {
+		if session.RevokedAt != nil {
+			return
+		}
+		now := time.Now()
+		session.RevokedAt = &now
+		session.RevokedReason = reason
+		revoked = true
+	})
+	if !revoked {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memSessionRepo) RevokeAllForUser(ctx context.Context, userID string, exceptID string, reason string) (int64, error) {
+	now := time.Now()
+	count := r.table.updateWhere(
+		func(session models.Session) bool {
+			return session.UserID == userID && session.RevokedAt == nil && session.ID != exceptID
+		},
+		func(session *models.Session) {
+			session.RevokedAt = &now
+			session.RevokedReason = reason
+		},
+	)
+	return int64(count), nil
+}
+
+func (r *memSessionRepo) ListActiveByUser(ctx context.Context, userID string, now time.Time) ([]models.Session, error) {
+	return r.table.filter(
+		func(session models.Session) bool { return session.UserID == userID && session.Active(now) },
+		func(a, b models.Session) bool { return a.LastUsedAt.After(b.LastUsedAt) },
+	), nil
+}
+
+func (r *memSessionRepo) MarkMFAVerified(ctx context.Context, id string, at time.Time) error {
+	marked := false
+	r.table.update(id, func(session *models.Session) {
+		if session.RevokedAt != nil {
+			return
+		}
+		session.MFAVerifiedAt = &at
+		marked = true
+	})
+	if !marked {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memSessionRepo) DeleteByUser(ctx context.Context, userID string) error {
+	r.table.deleteWhere(func(session models.Session) bool { return session.UserID == userID })
+	return nil
+}
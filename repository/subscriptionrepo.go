@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SubscriptionRepo stores channel subscriptions
+type SubscriptionRepo interface {
+	Create(ctx context.Context, subscription *models.Subscription) error
+	Exists(ctx context.Context, userID string, channelID string) (bool, error)
+	Delete(ctx context.Context, userID string, channelID string) error
+	CountByChannel(ctx context.Context, channelID string) (int64, error)
+	ListSubscribedChannels(ctx context.Context, userID string) ([]models.SubscribedChannel, error)
+	// ListBySubscriber returns the user's subscriptions, newest first
+	ListBySubscriber(ctx context.Context, userID string) ([]models.Subscription, error)
+	// ListChannelIDs returns the IDs of the channels the user is subscribed to
+	ListChannelIDs(ctx context.Context, userID string) ([]string, error)
+	DeleteBySubscriber(ctx context.Context, userID string) error
+	DeleteByChannel(ctx context.Context, channelID string) error
+}
+
+type mongoSubscriptionRepo struct {
+	collection *mongo.Collection
+}
+
+func subscriptionFilter(userID string, channelID string) bson.M {
+	return bson.M{
+		"subscriberId": userID,
+		"channelId":    channelID,
+	}
+}
+
+func (r *mongoSubscriptionRepo) Create(ctx context.Context, subscription *models.Subscription) error {
+	_, err := r.collection.InsertOne(ctx, subscription)
+	return translate(err)
+}
+
+func (r *mongoSubscriptionRepo) Exists(ctx context.Context, userID string, channelID string) (bool, error) {
+	err := r.collection.FindOne(ctx, subscriptionFilter(userID, channelID)).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *mongoSubscriptionRepo) Delete(ctx context.Context, userID string, channelID string) error {
+	result, err := r.collection.DeleteOne(ctx, subscriptionFilter(userID, channelID))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoSubscriptionRepo) CountByChannel(ctx context.Context, channelID string) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"channelId": channelID})
+}
+
+func (r *mongoSubscriptionRepo) ListSubscribedChannels(ctx context.Context, userID string) ([]models.SubscribedChannel, error) {
+	// Create pipeline to get subscribed channels
+	pipeline := []bson.M{
+		{
+			"$match": bson.M{
+				"subscriberId": userID,
+			},
+		},
+		{
+			"$lookup": bson.M{ // Join with channels collection
+				"from":         "channels",
+				"localField":   "channelId",
+				"foreignField": "_id",
+				"as":           "channelDetails",
+			},
+		},
+		{
+			"$unwind": "$channelDetails", // Flatten the channelDetails array
+		},
+		{
+			"$lookup": bson.M{ // Count the channel's subscriptions
+				"from": "subscriptions",
+				"let":  bson.M{"channelId": "$channelId"},
+				"pipeline": bson.A{
+					bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$channelId", "$$channelId"}}}},
+					bson.M{"$count": "count"},
+				},
+				"as": "subscriberCounts",
+			},
+		},
+		{
+			"$project": bson.M{ // Shape the output
+				"channelId":    "$channelDetails._id",
+				"channelName":  "$channelDetails.channelName",
+				"handle":       "$channelDetails.handle",
+				"banner":       "$channelDetails.banner",
+				"createdAt":    "$channelDetails.createdAt",
+				"subscribedAt": "$createdAt", // When user subscribed
+				"subscriberCount": bson.M{ // No subscriptions means no count document
+					"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$subscriberCounts.count", 0}}, 0},
+				},
+			},
+		},
+		{
+			"$sort": bson.M{ // Sort by subscription date
+				"subscribedAt": -1,
+			},
+		},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	channels := []models.SubscribedChannel{}
+	if err := cursor.All(ctx, &channels); err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+func (r *mongoSubscriptionRepo) ListBySubscriber(ctx context.Context, userID string) ([]models.Subscription, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"subscriberId": userID}, options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		return nil, err
+	}
+	subscriptions := []models.Subscription{}
+	if err := cursor.All(ctx, &subscriptions); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *mongoSubscriptionRepo) ListChannelIDs(ctx context.Context, userID string) ([]string, error) {
+	values, err := r.collection.Distinct(ctx, "channelId", bson.M{"subscriberId": userID})
+	if err != nil {
+		return nil, err
+	}
+	channelIDs := make([]string, 0, len(values))
+	for _, value := range values {
+		if channelID, ok := value.(string); ok {
+			channelIDs = append(channelIDs, channelID)
+		}
+	}
+	return channelIDs, nil
+}
+
+func (r *mongoSubscriptionRepo) DeleteBySubscriber(ctx context.Context, userID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"subscriberId": userID})
+	return err
+}
+
+func (r *mongoSubscriptionRepo) DeleteByChannel(ctx context.Context, channelID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"channelId": channelID})
+	return err
+}
+
+type memSubscriptionRepo struct {
+	table    *memTable[models.Subscription]
+	channels *memChannelRepo
+}
+
+func (r *memSubscriptionRepo) Create(ctx context.Context, subscription *models.Subscription) error {
+	return r.table.insert(subscription.ID, *subscription, func(existing models.Subscription) bool {
+		return existing.SubscriberID == subscription.SubscriberID && existing.ChannelID == subscription.ChannelID
+	})
+}
+
+func (r *memSubscriptionRepo) Exists(ctx context.Context, userID string, channelID string) (bool, error) {
+	_, ok := r.table.find(func(subscription models.Subscription) bool {
+		return subscription.SubscriberID == userID && subscription.ChannelID == channelID
+	})
+	return ok, nil
+}
+
+func (r *memSubscriptionRepo) Delete(ctx context.Context, userID string, channelID string) error {
+	deleted := r.table.deleteWhere(func(subscription models.Subscription) bool {
+		return subscription.SubscriberID == userID && subscription.ChannelID == channelID
+	})
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memSubscriptionRepo) CountByChannel(ctx context.Context, channelID string) (int64, error) {
+	return r.table.count(func(subscription models.Subscription) bool {
+		return subscription.ChannelID == channelID
+	}), nil
+}
+
+func (r *memSubscriptionRepo) ListSubscribedChannels(ctx context.Context, userID string) ([]models.SubscribedChannel, error) {
+	subscriptions := r.table.filter(
+		func(subscription models.Subscription) bool { return subscription.SubscriberID == userID },
+		func(a, b models.Subscription) bool { return a.CreatedAt.After(b.CreatedAt) },
+	)
+
+	channels := []models.SubscribedChannel{}
+	for _, subscription := range subscriptions {
+		channel, ok := r.channels.table.get(subscription.ChannelID)
+		if !ok {
+			continue
+		}
+		count, _ := r.CountByChannel(ctx, channel.ID)
+		channels = append(channels, models.SubscribedChannel{
+			ChannelID:       channel.ID,
+			ChannelName:     channel.ChannelName,
+			Handle:          channel.Handle,
+			Banner:          channel.Banner,
+			CreatedAt:       channel.CreatedAt,
+			SubscribedAt:    subscription.CreatedAt,
+			SubscriberCount: count,
+		})
+	}
+	return channels, nil
+}
+
+func (r *memSubscriptionRepo) ListBySubscriber(ctx context.Context, userID string) ([]models.Subscription, error) {
+	return r.table.filter(
+		func(subscription models.Subscription) bool { return subscription.SubscriberID == userID },
+		func(a, b models.Subscription) bool { return a.CreatedAt.After(b.CreatedAt) },
+	), nil
+}
+
+func (r *memSubscriptionRepo) ListChannelIDs(ctx context.Context, userID string) ([]string, error) {
+	channelIDs := []string{}
+	for _, subscription := range r.table.filter(func(subscription models.Subscription) bool { return subscription.SubscriberID == userID }, nil) {
+		channelIDs = append(channelIDs, subscription.ChannelID)
+	}
+	return channelIDs, nil
+}
+
+func (r *memSubscriptionRepo) DeleteBySubscriber(ctx context.Context, userID string) error {
+	r.table.deleteWhere(func(subscription models.Subscription) bool { return subscription.SubscriberID == userID })
+	return nil
+}
+
+func (r *memSubscriptionRepo) DeleteByChannel(ctx context.Context, channelID string) error {
+	r.table.deleteWhere(func(subscription models.Subscription) bool { return subscription.ChannelID == channelID })
+	return nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TokenBlacklistRepo stores revoked access tokens by jti
+type TokenBlacklistRepo interface {
+	Add(ctx context.Context, entry *models.TokenBlacklist) error
+	Exists(ctx context.Context, tokenID string) (bool, error)
+}
+
+type mongoTokenBlacklistRepo struct {
+	collection *mongo.Collection
+}
+
+// Add revokes the token; revoking it twice is not an error. A TTL index on
+// expiresAt removes the entry once the token has expired.
+func (r *mongoTokenBlacklistRepo) Add(ctx context.Context, entry *models.TokenBlacklist) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": entry.TokenID},
+		bson.M{"$setOnInsert": entry},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+func (r *mongoTokenBlacklistRepo) Exists(ctx context.Context, tokenID string) (bool, error) {
+	err := r.collection.FindOne(ctx, bson.M{"_id": tokenID}).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+type memTokenBlacklistRepo struct {
+	table *memTable[models.TokenBlacklist]
+}
+
+func (r *memTokenBlacklistRepo) Add(ctx context.Context, entry *models.TokenBlacklist) error {
+	// Drop expired entries the way the TTL index does
+	now := time.Now()
+	r.table.deleteWhere(func(existing models.TokenBlacklist) bool { return !existing.ExpiresAt.After(now) })
+
+	err := r.table.insert(entry.TokenID, *entry, nil)
+	if err == ErrDuplicate {
+		return nil
+	}
+	return err
+}
+
+func (r *memTokenBlacklistRepo) Exists(ctx context.Context, tokenID string) (bool, error) {
+	_, ok := r.table.get(tokenID)
+	return ok, nil
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TwoFactorRepo stores users' authenticator app enrollments
+type TwoFactorRepo interface {
+	// Begin stores a pending enrollment, replacing any earlier pending one; it
+	// returns ErrDuplicate if the user already has two-factor enabled
+	Begin(ctx context.Context, twoFactor *models.TwoFactor) error
+	FindByUser(ctx context.Context, userID string) (*models.TwoFactor, error)
+	// Enable turns on a pending enrollment, whose first code was for step
+	Enable(ctx context.Context, userID string, enabledAt time.Time, step int64, recoveryCodes []string) error
+	// UseStep records a code's time step; ErrNotFound means it isn't newer than the last one used
+	UseStep(ctx context.Context, userID string, step int64) error
+	// UseRecoveryCode removes the recovery code with the given hash; ErrNotFound means it isn't unused
+	UseRecoveryCode(ctx context.Context, userID string, hash string) error
+	ReplaceRecoveryCodes(ctx context.Context, userID string, recoveryCodes []string) error
+	Delete(ctx context.Context, userID string) error
+}
+
+type mongoTwoFactorRepo struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoTwoFactorRepo) Begin(ctx context.Context, twoFactor *models.TwoFactor) error {
+	// An enabled enrollment doesn't match, so the upsert's insert collides on _id
+	_, err := r.collection.ReplaceOne(ctx,
+		bson.M{"_id": twoFactor.UserID, "enabledAt": nil},
+		twoFactor,
+		options.Replace().SetUpsert(true),
+	)
+	return translate(err)
+}
+
+func (r *mongoTwoFactorRepo) FindByUser(ctx context.Context, userID string) (*models.TwoFactor, error) {
+	var twoFactor models.TwoFactor
+	if err := r.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&twoFactor); err != nil {
+		return nil, translate(err)
+	}
+	return &twoFactor, nil
+}
+
+func (r *mongoTwoFactorRepo) Enable(ctx context.Context, userID string, enabledAt time.Time, step int64, recoveryCodes []string) error {
+	return r.updateOne(ctx,
+		bson.M{"_id": userID, "enabledAt": nil},
+		bson.M{"$set": bson.M{"enabledAt": enabledAt, "lastUsedStep": step, "recoveryCodes": recoveryCodes}},
+	)
+}
+
+func (r *mongoTwoFactorRepo) UseStep(ctx context.Context, userID string, step int64) error {
+	return r.updateOne(ctx,
+		bson.M{"_id": userID, "lastUsedStep": bson.M{"$lt": step}},
+		bson.M{"$set": bson.M{"lastUsedStep": step}},
+	)
+}
+
+func (r *mongoTwoFactorRepo) UseRecoveryCode(ctx context.Context, userID string, hash string) error {
+	return r.updateOne(ctx,
+		bson.M{"_id": userID, "recoveryCodes": hash},
+		bson.M{"$pull": bson.M{"recoveryCodes": hash}},
+	)
+}
+
+func (r *mongoTwoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userID string, recoveryCodes []string) error {
+	return r.updateOne(ctx,
+		bson.M{"_id": userID, "enabledAt": bson.M{"$ne": nil}},
+		bson.M{"$set": bson.M{"recoveryCodes": recoveryCodes}},
+	)
+}
+
+func (r *mongoTwoFactorRepo) Delete(ctx context.Context, userID string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoTwoFactorRepo) updateOne(ctx context.Context, filter bson.M, update bson.M) error {
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memTwoFactorRepo struct {
+	table *memTable[models.TwoFactor]
+}
+
+func (r *memTwoFactorRepo) Begin(ctx context.Context, twoFactor *models.TwoFactor) error {
+	result := ErrNotFound
+	r.table.update(twoFactor.UserID, func(existing *models.TwoFactor) {
+		if existing.Enabled() {
+			result = ErrDuplicate
+			return
+		}
+		*existing = *twoFactor
+		result = nil
+	})
+	if result == ErrNotFound {
+		return r.table.insert(twoFactor.UserID, *twoFactor, nil)
+	}
+	return result
+}
+
+func (r *memTwoFactorRepo) FindByUser(ctx context.Context, userID string) (*models.TwoFactor, error) {
+	twoFactor, ok := r.table.get(userID)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &twoFactor, nil
+}
+
+func (r *memTwoFactorRepo) Enable(ctx context.Context, userID string, enabledAt time.Time, step int64, recoveryCodes []string) error {
+	return r.updateIf(userID, func(twoFactor *models.TwoFactor) bool {
+		if twoFactor.Enabled() {
+			return false
+		}
+		twoFactor.EnabledAt = &enabledAt
+		twoFactor.LastUsedStep = step
+		twoFactor.RecoveryCodes = recoveryCodes
+		return true
+	})
+}
+
+func (r *memTwoFactorRepo) UseStep(ctx context.Context, userID string, step int64) error {
+	return r.updateIf(userID, func(twoFactor *models.TwoFactor) bool {
+		if twoFactor.LastUsedStep >= step {
+			return false
+		}
+		twoFactor.LastUsedStep = step
+		return true
+	})
+}
+
+func (r *memTwoFactorRepo) UseRecoveryCode(ctx context.Context, userID string, hash string) error {
+	return r.updateIf(userID, func(twoFactor *models.TwoFactor) bool {
+		i := slices.Index(twoFactor.RecoveryCodes, hash)
+		if i < 0 {
+			return false
+		}
+		twoFactor.RecoveryCodes = slices.Delete(slices.Clone(twoFactor.RecoveryCodes), i, i+1)
+		return true
+	})
+}
+
+func (r *memTwoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userID string, recoveryCodes []string) error {
+	return r.updateIf(userID, func(twoFactor *models.TwoFactor) bool {
+		if !twoFactor.Enabled() {
+			return false
+		}
+		twoFactor.RecoveryCodes = recoveryCodes
+		return true
+	})
+}
+
+func (r *memTwoFactorRepo) Delete(ctx context.Context, userID string) error {
+	if !r.table.delete(userID) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// updateIf applies fn under the table lock; fn reports whether the row matched
+func (r *memTwoFactorRepo) updateIf(userID string, fn func(twoFactor *models.TwoFactor) bool) error {
+	matched := false
+	r.table.update(userID, func(twoFactor *models.TwoFactor) {
+		matched = fn(twoFactor)
+	})
+	if !matched {
+		return ErrNotFound
+	}
+	return nil
+}
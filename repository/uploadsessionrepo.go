@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UploadSessionRepo stores resumable upload sessions
+type UploadSessionRepo interface {
+	Create(ctx context.Context, session *models.UploadSession) error
+	FindByID(ctx context.Context, id string) (*models.UploadSession, error)
+	UpdateOffset(ctx context.Context, id string, offset int64) error
+	MarkCompleted(ctx context.Context, id string, videoID string) error
+	Delete(ctx context.Context, id string) error
+	// ListExpired returns up to limit sessions that expired before now, oldest first
+	ListExpired(ctx context.Context, now time.Time, limit int64) ([]models.UploadSession, error)
+}
+
+type mongoUploadSessionRepo struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoUploadSessionRepo) Create(ctx context.Context, session *models.UploadSession) error {
+	_, err := r.collection.InsertOne(ctx, session)
+	return translate(err)
+}
+
+func (r *mongoUploadSessionRepo) FindByID(ctx context.Context, id string) (*models.UploadSession, error) {
+	var session models.UploadSession
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&session); err != nil {
+		return nil, translate(err)
+	}
+	return &session, nil
+}
+
+func (r *mongoUploadSessionRepo) UpdateOffset(ctx context.Context, id string, offset int64) error {
+	return r.set(ctx, id, bson.M{"offset": offset})
+}
+
+func (r *mongoUploadSessionRepo) MarkCompleted(ctx context.Context, id string, videoID string) error {
+	return r.set(ctx, id, bson.M{"status": models.UploadStatusCompleted, "videoId": videoID})
+}
+
+func (r *mongoUploadSessionRepo) Delete(ctx context.Context, id string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUploadSessionRepo) ListExpired(ctx context.Context, now time.Time, limit int64) ([]models.UploadSession, error) {
+	cursor, err := r.collection.Find(ctx,
+		bson.M{"expiresAt": bson.M{"$lt": now}},
+		options.Find().SetSort(bson.M{"expiresAt": 1}).SetLimit(limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	sessions := []models.UploadSession{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *mongoUploadSessionRepo) set(ctx context.Context, id string, fields bson.M) error {
+	fields["updatedAt"] = time.Now()
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memUploadSessionRepo struct {
+	table *memTable[models.UploadSession]
+}
+
+func (r *memUploadSessionRepo) Create(ctx context.Context, session *models.UploadSession) error {
+	return r.table.insert(session.ID, *session, nil)
+}
+
+func (r *memUploadSessionRepo) FindByID(ctx context.Context, id string) (*models.UploadSession, error) {
+	session, ok := r.table.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+func (r *memUploadSessionRepo) UpdateOffset(ctx context.Context, id string, offset int64) error {
+	return r.set(id, func(session *models.UploadSession) { session.Offset = offset })
+}
+
+func (r *memUploadSessionRepo) MarkCompleted(ctx context.Context, id string, videoID string) error {
+	return r.set(id, func(session *models.UploadSession) {
+		session.Status = models.UploadStatusCompleted
+		session.VideoID = videoID
+	})
+}
+
+func (r *memUploadSessionRepo) Delete(ctx context.Context, id string) error {
+	if !r.table.delete(id) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memUploadSessionRepo) ListExpired(ctx context.Context, now time.Time, limit int64) ([]models.UploadSession, error) {
+	sessions := r.table.filter(
+		func(session models.UploadSession) bool { return session.ExpiresAt.Before(now) },
+		func(a, b models.UploadSession) bool { return a.ExpiresAt.Before(b.ExpiresAt) },
+	)
+	if int64(len(sessions)) > limit {
+		sessions = sessions[:limit]
+	}
+	return sessions, nil
+}
+
+func (r *memUploadSessionRepo) set(id string, fn func(session *models.UploadSession)) error {
+	ok := r.table.update(id, func(session *models.UploadSession) {
+		fn(session)
+		session.UpdatedAt = time.Now()
+	})
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,369 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserQuery filters the user list staff browse; empty fields match everything
+type UserQuery struct {
+	Username  string // prefix, case-insensitive
+	Role      string
+	Suspended bool // only suspended accounts
+	Skip      int64
+	Limit     int64
+}
+
+// ProfileUpdate holds the profile fields to change; nil fields are left as they are
+type ProfileUpdate struct {
+	Username *string
+	Bio      *string
+	Links    *[]models.ProfileLink
+}
+
+// UserRepo stores user accounts
+type UserRepo interface {
+	Create(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id string) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	ExistsByUsernameOrEmail(ctx context.Context, username string, email string) (bool, error)
+	UpdatePassword(ctx context.Context, id string, hashedPassword string) error
+	SetChannel(ctx context.Context, id string, channel models.Channel) error
+	SetRole(ctx context.Context, id string, role string) error
+	// UpdateProfile returns ErrDuplicate if the new username is taken
+	UpdateProfile(ctx context.Context, id string, update ProfileUpdate) error
+	SetAvatar(ctx context.Context, id string, avatarURL string) error
+	SetCoverImage(ctx context.Context, id string, coverURL string) error
+	// MarkEmailVerified records that the user owns email, unless their address has changed since
+	MarkEmailVerified(ctx context.Context, id string, email string, verifiedAt time.Time) error
+	Suspend(ctx context.Context, id string, suspendedAt time.Time, reason string) error
+	Unsuspend(ctx context.Context, id string) error
+	// List returns matching users, newest first
+	List(ctx context.Context, query UserQuery) ([]models.User, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type mongoUserRepo struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoUserRepo) Create(ctx context.Context, user *models.User) error {
+	_, err := r.collection.InsertOne(ctx, user)
+	return translate(err)
+}
+
+func (r *mongoUserRepo) FindByID(ctx context.Context, id string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mongoUserRepo) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"username": username})
+}
+
+func (r *mongoUserRepo) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"email": email})
+}
+
+func (r *mongoUserRepo) ExistsByUsernameOrEmail(ctx context.Context, username string, email string) (bool, error) {
+	filter := bson.M{
+		"$or": []bson.M{
+			{"username": username},
+			{"email": email},
+		},
+	}
+	err := r.collection.FindOne(ctx, filter).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *mongoUserRepo) UpdatePassword(ctx context.Context, id string, hashedPassword string) error {
+	return r.set(ctx, id, bson.M{"password": hashedPassword})
+}
+
+func (r *mongoUserRepo) SetChannel(ctx context.Context, id string, channel models.Channel) error {
+	return r.set(ctx, id, bson.M{"channelName": channel})
+}
+
+func (r *mongoUserRepo) SetRole(ctx context.Context, id string, role string) error {
+	return r.set(ctx, id, bson.M{"role": role})
+}
+
+func (r *mongoUserRepo) UpdateProfile(ctx context.Context, id string, update ProfileUpdate) error {
+	fields := bson.M{}
+	if update.Username != nil {
+		fields["username"] = *update.Username
+	}
+	if update.Bio != nil {
+		fields["bio"] = *update.Bio
+	}
+	if update.Links != nil {
+		fields["links"] = *update.Links
+	}
+	return r.set(ctx, id, fields)
+}
+
+func (r *mongoUserRepo) SetAvatar(ctx context.Context, id string, avatarURL string) error {
+	return r.set(ctx, id, bson.M{"avatar": avatarURL})
+}
+
+func (r *mongoUserRepo) SetCoverImage(ctx context.Context, id string, coverURL string) error {
+	return r.set(ctx, id, bson.M{"coverImage": coverURL})
+}
+
+func (r *mongoUserRepo) MarkEmailVerified(ctx context.Context, id string, email string, verifiedAt time.Time) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "email": email},
+		bson.M{"$set": bson.M{"emailVerifiedAt": verifiedAt, "updatedAt": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUserRepo) Suspend(ctx context.Context, id string, suspendedAt time.Time, reason string) error {
+	return r.set(ctx, id, bson.M{"suspendedAt": suspendedAt, "suspendedReason": reason})
+}
+
+func (r *mongoUserRepo) Unsuspend(ctx context.Context, id string) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$unset": bson.M{"suspendedAt": "", "suspendedReason": ""},
+		"$set":   bson.M{"updatedAt": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUserRepo) List(ctx context.Context, query UserQuery) ([]models.User, error) {
+	filter := bson.M{}
+	if query.Username != "" {
+		filter["username"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(query.Username), Options: "i"}
+	}
+	if query.Role == models.RoleUser {
+		// Accounts created before roles existed have no role field
+		filter["role"] = bson.M{"$in": bson.A{models.RoleUser, "", nil}}
+	} else if query.Role != "" {
+		filter["role"] = query.Role
+	}
+	if query.Suspended {
+		filter["suspendedAt"] = bson.M{"$ne": nil}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(query.Skip).
+		SetLimit(query.Limit)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []models.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *mongoUserRepo) Delete(ctx context.Context, id string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUserRepo) findOne(ctx context.Context, filter bson.M) (*models.User, error) {
+	var user models.User
+	if err := r.collection.FindOne(ctx, filter).Decode(&user); err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+// set updates the given fields and bumps updatedAt
+func (r *mongoUserRepo) set(ctx context.Context, id string, fields bson.M) error {
+	fields["updatedAt"] = time.Now()
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+	if err != nil {
+		return translate(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memUserRepo struct {
+	table *memTable[models.User]
+}
+
+func (r *memUserRepo) Create(ctx context.Context, user *models.User) error {
+	// Matches the unique username and email indexes on the Mongo side
+	return r.table.insert(user.ID, *user, func(existing models.User) bool {
+		return existing.Username == user.Username || existing.Email == user.Email
+	})
+}
+
+func (r *memUserRepo) FindByID(ctx context.Context, id string) (*models.User, error) {
+	user, ok := r.table.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *memUserRepo) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.findOne(func(user models.User) bool { return user.Username == username })
+}
+
+func (r *memUserRepo) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findOne(func(user models.User) bool { return user.Email == email })
+}
+
+func (r *memUserRepo) ExistsByUsernameOrEmail(ctx context.Context, username string, email string) (bool, error) {
+	_, ok := r.table.find(func(user models.User) bool {
+		return user.Username == username || user.Email == email
+	})
+	return ok, nil
+}
+
+func (r *memUserRepo) UpdatePassword(ctx context.Context, id string, hashedPassword string) error {
+	return r.set(id, func(user *models.User) { user.Password = hashedPassword })
+}
+
+func (r *memUserRepo) SetChannel(ctx context.Context, id string, channel models.Channel) error {
+	return r.set(id, func(user *models.User) { user.ChannelName = channel })
+}
+
+func (r *memUserRepo) SetRole(ctx context.Context, id string, role string) error {
+	return r.set(id, func(user *models.User) { user.Role = role })
+}
+
+func (r *memUserRepo) UpdateProfile(ctx context.Context, id string, update ProfileUpdate) error {
+	// Matches the unique username index on the Mongo side
+	return r.table.updateUnique(id, func(user *models.User) {
+		if update.Username != nil {
+			user.Username = *update.Username
+		}
+		if update.Bio != nil {
+			user.Bio = *update.Bio
+		}
+		if update.Links != nil {
+			user.Links = *update.Links
+		}
+		user.UpdatedAt = time.Now()
+	}, func(updated models.User, existing models.User) bool {
+		return existing.Username == updated.Username
+	})
+}
+
+func (r *memUserRepo) SetAvatar(ctx context.Context, id string, avatarURL string) error {
+	return r.set(id, func(user *models.User) { user.Avatar = avatarURL })
+}
+
+func (r *memUserRepo) SetCoverImage(ctx context.Context, id string, coverURL string) error {
+	return r.set(id, func(user *models.User) { user.CoverImage = coverURL })
+}
+
+func (r *memUserRepo) MarkEmailVerified(ctx context.Context, id string, email string, verifiedAt time.Time) error {
+	marked := false
+	r.table.update(id, func(user *models.User) {
+		if user.Email != email {
+			return
+		}
+		user.EmailVerifiedAt = &verifiedAt
+		user.UpdatedAt = time.Now()
+		marked = true
+	})
+	if !marked {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memUserRepo) Suspend(ctx context.Context, id string, suspendedAt time.Time, reason string) error {
+	return r.set(id, func(user *models.User) {
+		user.SuspendedAt = &suspendedAt
+		user.SuspendedReason = reason
+	})
+}
+
+func (r *memUserRepo) Unsuspend(ctx context.Context, id string) error {
+	return r.set(id, func(user *models.User) {
+		user.SuspendedAt = nil
+		user.SuspendedReason = ""
+	})
+}
+
+func (r *memUserRepo) List(ctx context.Context, query UserQuery) ([]models.User, error) {
+	prefix := strings.ToLower(query.Username)
+	users := r.table.filter(
+		func(user models.User) bool {
+			if !strings.HasPrefix(strings.ToLower(user.Username), prefix) {
+				return false
+			}
+			if query.Role != "" && models.RoleRank(user.Role) != models.RoleRank(query.Role) {
+				return false
+			}
+			return !query.Suspended || user.Suspended()
+		},
+		func(a, b models.User) bool {
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.After(b.CreatedAt)
+			}
+			return a.ID > b.ID
+		},
+	)
+	return page(users, query.Skip, query.Limit), nil
+}
+
+func (r *memUserRepo) findOne(match func(user models.User) bool) (*models.User, error) {
+	user, ok := r.table.find(match)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *memUserRepo) set(id string, fn func(user *models.User)) error {
+	ok := r.table.update(id, func(user *models.User) {
+		fn(user)
+		user.UpdatedAt = time.Now()
+	})
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memUserRepo) Delete(ctx context.Context, id string) error {
+	if !r.table.delete(id) {
+		return ErrNotFound
+	}
+	return nil
+}
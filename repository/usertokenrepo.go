@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserTokenRepo stores the single-use tokens behind email verification and
+// password reset links and login challenges
+type UserTokenRepo interface {
+	Create(ctx context.Context, token *models.UserToken) error
+	// Find returns the token if it has the given purpose and hasn't expired, without using it up
+	Find(ctx context.Context, id string, purpose string, now time.Time) (*models.UserToken, error)
+	// Consume deletes and returns the token if it exists, has the given purpose and
+	// hasn't expired; otherwise it returns ErrNotFound. A token can only be consumed once.
+	Consume(ctx context.Context, id string, purpose string, now time.Time) (*models.UserToken, error)
+	DeleteByUser(ctx context.Context, userID string, purpose string) error
+	// RecordFailure counts a wrong code entered against the token and deletes
+	// it once maxFailures is reached
+	RecordFailure(ctx context.Context, id string, maxFailures int) error
+}
+
+type mongoUserTokenRepo struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoUserTokenRepo) Create(ctx context.Context, token *models.UserToken) error {
+	_, err := r.collection.InsertOne(ctx, token)
+	return translate(err)
+}
+
+func (r *mongoUserTokenRepo) Find(ctx context.Context, id string, purpose string, now time.Time) (*models.UserToken, error) {
+	var token models.UserToken
+	filter := bson.M{"_id": id, "purpose": purpose, "expiresAt": bson.M{"$gt": now}}
+	if err := r.collection.FindOne(ctx, filter).Decode(&token); err != nil {
+		return nil, translate(err)
+	}
+	return &token, nil
+}
+
+func (r *mongoUserTokenRepo) Consume(ctx context.Context, id string, purpose string, now time.Time) (*models.UserToken, error) {
+	var token models.UserToken
+	filter := bson.M{"_id": id, "purpose": purpose, "expiresAt": bson.M{"$gt": now}}
+	if err := r.collection.FindOneAndDelete(ctx, filter).Decode(&token); err != nil {
+		return nil, translate(err)
+	}
+	return &token, nil
+}
+
+func (r *mongoUserTokenRepo) DeleteByUser(ctx context.Context, userID string, purpose string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID, "purpose": purpose})
+	return err
+}
+
+func (r *mongoUserTokenRepo) RecordFailure(ctx context.Context, id string, maxFailures int) error {
+	var token models.UserToken
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id},
+		bson.M{"$inc": bson.M{"failures": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&token)
+	if err != nil {
+		return translate(err)
+	}
+	if token.Failures >= maxFailures {
+		_, err = r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	}
+	return err
+}
+
+type memUserTokenRepo struct {
+	table *memTable[models.UserToken]
+}
+
+func (r *memUserTokenRepo) Create(ctx context.Context, token *models.UserToken) error {
+	return r.table.insert(token.ID, *token, nil)
+}
+
+func (r *memUserTokenRepo) Find(ctx context.Context, id string, purpose string, now time.Time) (*models.UserToken, error) {
+	token, ok := r.table.get(id)
+	if !ok || token.Purpose != purpose || !now.Before(token.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	return &token, nil
+}
+
+func (r *memUserTokenRepo) Consume(ctx context.Context, id string, purpose string, now time.Time) (*models.UserToken, error) {
+	consumed := false
+	var token models.UserToken
+	// Check and delete under one lock, so two requests can't both use the token
+	r.table.deleteWhere(func(row models.UserToken) bool {
+		if row.ID != id || row.Purpose != purpose || !now.Before(row.ExpiresAt) {
+			return false
+		}
+		consumed, token = true, row
+		return true
+	})
+	if !consumed {
+		return nil, ErrNotFound
+	}
+	return &token, nil
+}
+
+func (r *memUserTokenRepo) DeleteByUser(ctx context.Context, userID string, purpose string) error {
+	r.table.deleteWhere(func(row models.UserToken) bool {
+		return row.UserID == userID && row.Purpose == purpose
+	})
+	return nil
+}
+
+func (r *memUserTokenRepo) RecordFailure(ctx context.Context, id string, maxFailures int) error {
+	failures := 0
+	if !r.table.update(id, func(token *models.UserToken) {
+		token.Failures++
+		failures = token.Failures
+	}) {
+		return ErrNotFound
+	}
+	if failures >= maxFailures {
+		r.table.delete(id)
+	}
+	return nil
+}
@@ -0,0 +1,517 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Sort orders accepted by VideoRepo.List
+const (
+	VideoSortNewest = "newest"
+	VideoSortViews  = "views"
+	VideoSortLikes  = "likes"
+)
+
+// VideoCursor is the position of the last video on the previous page. Time is
+// used when sorting by newest, Count when sorting by views or likes, and ID
+// breaks ties.
+type VideoCursor struct {
+	Time  time.Time
+	Count int64
+	ID    string
+}
+
+// VideoQuery filters and orders a video listing
+type VideoQuery struct {
+	OwnerID   string
+	ChannelID string
+	// ChannelIDs matches videos from any of these channels, when not empty
+	ChannelIDs []string
+	Tag        string
+	From       *time.Time // created at or after
+	To         *time.Time // created before
+	Sort       string
+	After      *VideoCursor
+	// IncludeHidden also returns unlisted, private, scheduled, unfinished and taken
+	// down videos, for owners browsing their own uploads
+	IncludeHidden bool
+	Now           time.Time
+	Limit         int64
+}
+
+// VideoRepo stores video documents
+type VideoRepo interface {
+	Create(ctx context.Context, video *models.Video) error
+	FindByID(ctx context.Context, id string) (*models.Video, error)
+	FindByIDs(ctx context.Context, ids []string) ([]models.Video, error)
+	Delete(ctx context.Context, id string) error
+	IncrementViews(ctx context.Context, id string) error
+	UpdateProbe(ctx context.Context, id string, duration string, durationSeconds float64, width int, height int) error
+	MarkReady(ctx context.Context, id string, url string, thumbnail string) error
+	SetHLS(ctx context.Context, id string, hlsURL string, renditions []models.Rendition) error
+	SetPreview(ctx context.Context, id string, spriteURL string, vttURL string) error
+	SetCustomThumbnail(ctx context.Context, id string, thumbnail string) error
+	SetStatus(ctx context.Context, id string, status string) error
+	// SetTakedown takes the video down with the given reason, or restores it when takenDownAt is nil
+	SetTakedown(ctx context.Context, id string, takenDownAt *time.Time, reason string) error
+	UpdateMetadata(ctx context.Context, video *models.Video) error
+	AdjustLikes(ctx context.Context, id string, delta int) error
+	List(ctx context.Context, query VideoQuery) ([]models.Video, error)
+}
+
+type mongoVideoRepo struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoVideoRepo) Create(ctx context.Context, video *models.Video) error {
+	// Only the references are stored; the owner and channel are joined in on read
+	stored := *video
+	stored.Owner = nil
+	stored.Channel = nil
+	_, err := r.collection.InsertOne(ctx, stored)
+	return translate(err)
+}
+
+func (r *mongoVideoRepo) FindByID(ctx context.Context, id string) (*models.Video, error) {
+	videos, err := r.aggregate(ctx, bson.M{"_id": id})
+	if err != nil {
+		return nil, err
+	}
+	if len(videos) == 0 {
+		return nil, ErrNotFound
+	}
+	return &videos[0], nil
+}
+
+func (r *mongoVideoRepo) FindByIDs(ctx context.Context, ids []string) ([]models.Video, error) {
+	return r.aggregate(ctx, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+// aggregate returns the videos matching filter with their owner and channel filled in
+func (r *mongoVideoRepo) aggregate(ctx context.Context, filter bson.M, stages ...bson.M) ([]models.Video, error) {
+	pipeline := append([]bson.M{{"$match": filter}}, stages...)
+	pipeline = append(pipeline, videoLookups()...)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	videos := []models.Video{}
+	if err := cursor.All(ctx, &videos); err != nil {
+		return nil, err
+	}
+	return videos, nil
+}
+
+func (r *mongoVideoRepo) Delete(ctx context.Context, id string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoVideoRepo) IncrementViews(ctx context.Context, id string) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"views": 1}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoVideoRepo) UpdateProbe(ctx context.Context, id string, duration string, durationSeconds float64, width int, height int) error {
+	return r.set(ctx, id, bson.M{
+		"duration":        duration,
+		"durationSeconds": durationSeconds,
+		"width":           width,
+		"height":          height,
+	})
+}
+
+// MarkReady stores the processed file and poster frame; a custom thumbnail set by the owner is kept
+func (r *mongoVideoRepo) MarkReady(ctx context.Context, id string, url string, thumbnail string) error {
+	update := bson.A{bson.M{"$set": bson.M{
+		"url": url,
+		"thumbnail": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$customThumbnail", true}}, "$thumbnail", thumbnail,
+		}},
+		"status":    models.VideoStatusReady,
+		"updatedAt": time.Now(),
+	}}}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoVideoRepo) SetHLS(ctx context.Context, id string, hlsURL string, renditions []models.Rendition) error {
+	return r.set(ctx, id, bson.M{
+		"hlsUrl":     hlsURL,
+		"renditions": renditions,
+	})
+}
+
+func (r *mongoVideoRepo) SetPreview(ctx context.Context, id string, spriteURL string, vttURL string) error {
+	return r.set(ctx, id, bson.M{
+		"previewSprite": spriteURL,
+		"previewVtt":    vttURL,
+	})
+}
+
+func (r *mongoVideoRepo) SetCustomThumbnail(ctx context.Context, id string, thumbnail string) error {
+	return r.set(ctx, id, bson.M{
+		"thumbnail":       thumbnail,
+		"customThumbnail": true,
+	})
+}
+
+func (r *mongoVideoRepo) SetStatus(ctx context.Context, id string, status string) error {
+	return r.set(ctx, id, bson.M{"status": status})
+}
+
+func (r *mongoVideoRepo) SetTakedown(ctx context.Context, id string, takenDownAt *time.Time, reason string) error {
+	if takenDownAt == nil {
+		result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+			"$unset": bson.M{"takenDownAt": "", "takedownReason": ""},
+			"$set":   bson.M{"updatedAt": time.Now()},
+		})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return ErrNotFound
+		}
+		return nil
+	}
+	return r.set(ctx, id, bson.M{"takenDownAt": *takenDownAt, "takedownReason": reason})
+}
+
+// UpdateMetadata saves the fields the owner can edit
+func (r *mongoVideoRepo) UpdateMetadata(ctx context.Context, video *models.Video) error {
+	return r.set(ctx, video.ID, bson.M{
+		"title":       video.Title,
+		"description": video.Description,
+		"tags":        video.Tags,
+		"category":    video.Category,
+		"language":    video.Language,
+		"visibility":  video.Visibility,
+		"publishAt":   video.PublishAt,
+	})
+}
+
+func (r *mongoVideoRepo) AdjustLikes(ctx context.Context, id string, delta int) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"likes": delta}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoVideoRepo) List(ctx context.Context, query VideoQuery) ([]models.Video, error) {
+	conditions := bson.A{}
+	if query.OwnerID != "" {
+		conditions = append(conditions, bson.M{"ownerId": query.OwnerID})
+	}
+	if query.ChannelID != "" {
+		conditions = append(conditions, bson.M{"channelId": query.ChannelID})
+	}
+	if len(query.ChannelIDs) > 0 {
+		conditions = append(conditions, bson.M{"channelId": bson.M{"$in": query.ChannelIDs}})
+	}
+	if query.Tag != "" {
+		conditions = append(conditions, bson.M{"tags": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(query.Tag) + "$", Options: "i"}})
+	}
+	if query.From != nil {
+		conditions = append(conditions, bson.M{"createdAt": bson.M{"$gte": *query.From}})
+	}
+	if query.To != nil {
+		conditions = append(conditions, bson.M{"createdAt": bson.M{"$lt": *query.To}})
+	}
+	if !query.IncludeHidden {
+		conditions = append(conditions,
+			bson.M{"visibility": bson.M{"$nin": bson.A{models.VisibilityUnlisted, models.VisibilityPrivate}}},
+			bson.M{"status": bson.M{"$nin": bson.A{models.VideoStatusProcessing, models.VideoStatusFailed}}},
+			bson.M{"$or": bson.A{bson.M{"publishAt": nil}, bson.M{"publishAt": bson.M{"$lte": query.Now}}}},
+			bson.M{"takenDownAt": nil},
+		)
+	}
+
+	field := videoSortField(query.Sort)
+	if query.After != nil {
+		var value interface{} = query.After.Count
+		if field == "createdAt" {
+			value = query.After.Time
+		}
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{field: bson.M{"$lt": value}},
+			bson.M{field: value, "_id": bson.M{"$lt": query.After.ID}},
+		}})
+	}
+
+	filter := bson.M{}
+	if len(conditions) > 0 {
+		filter["$and"] = conditions
+	}
+	stages := []bson.M{{"$sort": bson.D{{Key: field, Value: -1}, {Key: "_id", Value: -1}}}}
+	if query.Limit > 0 {
+		stages = append(stages, bson.M{"$limit": query.Limit})
+	}
+	return r.aggregate(ctx, filter, stages...)
+}
+
+func videoSortField(sort string) string {
+	switch sort {
+	case VideoSortViews:
+		return "views"
+	case VideoSortLikes:
+		return "likes"
+	default:
+		return "createdAt"
+	}
+}
+
+// set updates the given fields and bumps updatedat
+func (r *mongoVideoRepo) set(ctx context.Context, id string, fields bson.M) error {
+	fields["updatedAt"] = time.Now()
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memVideoRepo struct {
+	table    *memTable[models.Video]
+	users    *memTable[models.User]
+	channels *memTable[models.Channel]
+}
+
+func (r *memVideoRepo) Create(ctx context.Context, video *models.Video) error {
+	stored := *video
+	stored.Owner = nil
+	stored.Channel = nil
+	return r.table.insert(video.ID, stored, nil)
+}
+
+func (r *memVideoRepo) FindByID(ctx context.Context, id string) (*models.Video, error) {
+	video, ok := r.table.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	r.hydrate(&video)
+	return &video, nil
+}
+
+func (r *memVideoRepo) FindByIDs(ctx context.Context, ids []string) ([]models.Video, error) {
+	videos := []models.Video{}
+	for _, id := range ids {
+		if video, ok := r.table.get(id); ok {
+			r.hydrate(&video)
+			videos = append(videos, video)
+		}
+	}
+	return videos, nil
+}
+
+// hydrate fills in the owner and channel the way the Mongo lookups do
+func (r *memVideoRepo) hydrate(video *models.Video) {
+	video.Owner = userSummary(r.users, video.OwnerID)
+	video.Channel = nil
+	if channel, ok := r.channels.get(video.ChannelID); ok {
+		video.Channel = &channel
+	}
+}
+
+func (r *memVideoRepo) Delete(ctx context.Context, id string) error {
+	if !r.table.delete(id) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memVideoRepo) IncrementViews(ctx context.Context, id string) error {
+	if !r.table.update(id, func(video *models.Video) { video.Views++ }) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memVideoRepo) UpdateProbe(ctx context.Context, id string, duration string, durationSeconds float64, width int, height int) error {
+	return r.set(id, func(video *models.Video) {
+		video.Duration = duration
+		video.DurationSeconds = durationSeconds
+		video.Width = width
+		video.Height = height
+	})
+}
+
+func (r *memVideoRepo) MarkReady(ctx context.Context, id string, url string, thumbnail string) error {
+	return r.set(id, func(video *models.Video) {
+		video.URL = url
+		if !video.CustomThumbnail {
+			video.Thumbnail = thumbnail
+		}
+		video.Status = models.VideoStatusReady
+	})
+}
+
+func (r *memVideoRepo) SetHLS(ctx context.Context, id string, hlsURL string, renditions []models.Rendition) error {
+	return r.set(id, func(video *models.Video) {
+		video.HLSURL = hlsURL
+		video.Renditions = renditions
+	})
+}
+
+func (r *memVideoRepo) SetPreview(ctx context.Context, id string, spriteURL string, vttURL string) error {
+	return r.set(id, func(video *models.Video) {
+		video.PreviewSprite = spriteURL
+		video.PreviewVTT = vttURL
+	})
+}
+
+func (r *memVideoRepo) SetCustomThumbnail(ctx context.Context, id string, thumbnail string) error {
+	return r.set(id, func(video *models.Video) {
+		video.Thumbnail = thumbnail
+		video.CustomThumbnail = true
+	})
+}
+
+func (r *memVideoRepo) SetStatus(ctx context.Context, id string, status string) error {
+	return r.set(id, func(video *models.Video) { video.Status = status })
+}
+
+func (r *memVideoRepo) SetTakedown(ctx context.Context, id string, takenDownAt *time.Time, reason string) error {
+	return r.set(id, func(video *models.Video) {
+		video.TakenDownAt = takenDownAt
+		video.TakedownReason = reason
+	})
+}
+
+func (r *memVideoRepo) UpdateMetadata(ctx context.Context, video *models.Video) error {
+	return r.set(video.ID, func(stored *models.Video) {
+		stored.Title = video.Title
+		stored.Description = video.Description
+		stored.Tags = video.Tags
+		stored.Category = video.Category
+		stored.Language = video.Language
+		stored.Visibility = video.Visibility
+		stored.PublishAt = video.PublishAt
+	})
+}
+
+func (r *memVideoRepo) AdjustLikes(ctx context.Context, id string, delta int) error {
+	if !r.table.update(id, func(video *models.Video) { video.Likes += delta }) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memVideoRepo) List(ctx context.Context, query VideoQuery) ([]models.Video, error) {
+	// sortKey returns the value a video is ordered by; times compare as nanoseconds
+	sortKey := func(video models.Video) int64 {
+		switch query.Sort {
+		case VideoSortViews:
+			return int64(video.Views)
+		case VideoSortLikes:
+			return int64(video.Likes)
+		default:
+			return video.CreatedAt.UnixNano()
+		}
+	}
+	var afterKey int64
+	if query.After != nil {
+		afterKey = query.After.Count
+		if query.Sort != VideoSortViews && query.Sort != VideoSortLikes {
+			afterKey = query.After.Time.UnixNano()
+		}
+	}
+
+	match := func(video models.Video) bool {
+		if query.OwnerID != "" && video.OwnerID != query.OwnerID {
+			return false
+		}
+		if query.ChannelID != "" && video.ChannelID != query.ChannelID {
+			return false
+		}
+		if len(query.ChannelIDs) > 0 && !slices.Contains(query.ChannelIDs, video.ChannelID) {
+			return false
+		}
+		if query.Tag != "" && !slices.ContainsFunc(video.Tags, func(tag string) bool { return strings.EqualFold(tag, query.Tag) }) {
+			return false
+		}
+		if query.From != nil && video.CreatedAt.Before(*query.From) {
+			return false
+		}
+		if query.To != nil && !video.CreatedAt.Before(*query.To) {
+			return false
+		}
+		if !query.IncludeHidden {
+			if video.Visibility == models.VisibilityUnlisted || video.Visibility == models.VisibilityPrivate {
+				return false
+			}
+			if video.Status == models.VideoStatusProcessing || video.Status == models.VideoStatusFailed {
+				return false
+			}
+			if !video.IsPublished(query.Now) || video.TakenDownAt != nil {
+				return false
+			}
+		}
+		if query.After != nil {
+			key := sortKey(video)
+			if key > afterKey || (key == afterKey && video.ID >= query.After.ID) {
+				return false
+			}
+		}
+		return true
+	}
+	less := func(a, b models.Video) bool {
+		if sortKey(a) != sortKey(b) {
+			return sortKey(a) > sortKey(b)
+		}
+		return a.ID > b.ID
+	}
+
+	videos := page(r.table.filter(match, less), 0, query.Limit)
+	for i := range videos {
+		r.hydrate(&videos[i])
+	}
+	return videos, nil
+}
+
+func (r *memVideoRepo) set(id string, fn func(video *models.Video)) error {
+	ok := r.table.update(id, func(video *models.Video) {
+		fn(video)
+		video.UpdatedAt = time.Now()
+	})
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"yt_backend/models"
+)
+
+// seedVideos stores public, ready videos; some share a creation time or a like
+// count so the ID tie-break is exercised
+func seedVideos(t *testing.T, videos VideoRepo, base time.Time) []string {
+	t.Helper()
+	var ids []string
+	for i := 0; i < 7; i++ {
+		video := &models.Video{
+			ID:         fmt.Sprintf("v%d", i),
+			Visibility: models.VisibilityPublic,
+			Status:     models.VideoStatusReady,
+			Likes:      i % 3,
+			CreatedAt:  base.Add(time.Duration(i/2) * time.Minute),
+		}
+		if err := videos.Create(context.Background(), video); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		ids = append(ids, video.ID)
+	}
+	return ids
+}
+
+// pageThrough lists every video a page at a time, as the cursor handed to
+// clients does
+func pageThrough(t *testing.T, videos VideoRepo, query VideoQuery) []string {
+	t.Helper()
+	var ids []string
+	for pages := 0; pages < 10; pages++ {
+		page, err := videos.List(context.Background(), query)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		for _, video := range page {
+			ids = append(ids, video.ID)
+		}
+		if int64(len(page)) < query.Limit {
+			return ids
+		}
+		last := page[len(page)-1]
+		query.After = &VideoCursor{Time: last.CreatedAt, Count: int64(last.Likes), ID: last.ID}
+	}
+	t.Fatal("pagination did not finish")
+	return nil
+}
+
+func TestVideoListKeysetPagination(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		// Newest first; videos created together come in descending ID order
+		{VideoSortNewest, []string{"v6", "v5", "v4", "v3", "v2", "v1", "v0"}},
+		// Most liked first: v2 and v5 have 2 likes, v1 and v4 have 1, v0, v3 and v6 none
+		{VideoSortLikes, []string{"v5", "v2", "v4", "v1", "v6", "v3", "v0"}},
+	}
+	for _, test := range tests {
+		t.Run(test.sort, func(t *testing.T) {
+			videos := NewMemoryRepositories().Videos
+			seedVideos(t, videos, base)
+
+			for _, limit := range []int64{1, 2, 3, 7, 10} {
+				got := pageThrough(t, videos, VideoQuery{Sort: test.sort, Now: base.Add(time.Hour), Limit: limit})
+				if !slices.Equal(got, test.want) {
+					t.Errorf("limit %d: got %v, want %v", limit, got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestVideoListPaginationSkipsHiddenVideos(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	videos := NewMemoryRepositories().Videos
+	seedVideos(t, videos, base)
+
+	later := base.Add(48 * time.Hour)
+	hidden := []*models.Video{
+		{ID: "private", Visibility: models.VisibilityPrivate, Status: models.VideoStatusReady, CreatedAt: base},
+		{ID: "unlisted", Visibility: models.VisibilityUnlisted, Status: models.VideoStatusReady, CreatedAt: base},
+		{ID: "processing", Visibility: models.VisibilityPublic, Status: models.VideoStatusProcessing, CreatedAt: base},
+		{ID: "scheduled", Visibility: models.VisibilityPublic, Status: models.VideoStatusReady, CreatedAt: base, PublishAt: &later},
+	}
+	for _, video := range hidden {
+		if err := videos.Create(ctx, video); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	got := pageThrough(t, videos, VideoQuery{Sort: VideoSortNewest, Now: base.Add(time.Hour), Limit: 2})
+	if len(got) != 7 || slices.ContainsFunc(got, func(id string) bool { return id[0] != 'v' }) {
+		t.Errorf("public listing = %v, want only the seven public videos", got)
+	}
+
+	all := pageThrough(t, videos, VideoQuery{Sort: VideoSortNewest, Now: base.Add(time.Hour), Limit: 2, IncludeHidden: true})
+	if len(all) != 11 {
+		t.Errorf("listing with hidden videos returned %d videos, want 11", len(all))
+	}
+}
+
+func TestVideoListByChannels(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	videos := NewMemoryRepositories().Videos
+	for i, channelID := range []string{"c1", "c2", "c3", "c1"} {
+		video := &models.Video{
+			ID:         fmt.Sprintf("v%d", i),
+			ChannelID:  channelID,
+			Visibility: models.VisibilityPublic,
+			Status:     models.VideoStatusReady,
+			CreatedAt:  base.Add(time.Duration(i) * time.Minute),
+		}
+		if err := videos.Create(ctx, video); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	got := pageThrough(t, videos, VideoQuery{ChannelIDs: []string{"c1", "c3"}, Sort: VideoSortNewest, Now: base.Add(time.Hour), Limit: 2})
+	if want := []string{"v3", "v2", "v0"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+
+	"yt_backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WatchHistoryRepo stores the videos a user has watched
+type WatchHistoryRepo interface {
+	Add(ctx context.Context, entry *models.VideoWatchEntry) error
+	ListByUser(ctx context.Context, userID string, skip int64, limit int64) ([]models.VideoWatchEntry, error)
+	Delete(ctx context.Context, userID string, videoID string) error
+	DeleteByUser(ctx context.Context, userID string) error
+	DeleteByVideo(ctx context.Context, videoID string) error
+}
+
+type mongoWatchHistoryRepo struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoWatchHistoryRepo) Add(ctx context.Context, entry *models.VideoWatchEntry) error {
+	stored := *entry
+	stored.Video = nil
+	_, err := r.collection.InsertOne(ctx, stored)
+	return translate(err)
+}
+
+func (r *mongoWatchHistoryRepo) ListByUser(ctx context.Context, userID string, skip int64, limit int64) ([]models.VideoWatchEntry, error) {
+	pipeline := []bson.M{
+		{
+			"$match": bson.M{"userId": userID},
+		},
+		{
+			"$sort": bson.M{"watchedAt": -1},
+		},
+		{
+			"$skip": skip,
+		},
+		{
+			"$limit": limit,
+		},
+		{
+			// Join the watched video along with its owner and channel
+			"$lookup": bson.M{
+				"from":         "videos",
+				"localField":   "videoId",
+				"foreignField": "_id",
+				"pipeline":     videoLookups(),
+				"as":           "video",
+			},
+		},
+		{
+			"$unwind": bson.M{"path": "$video", "preserveNullAndEmptyArrays": true},
+		},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.VideoWatchEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *mongoWatchHistoryRepo) Delete(ctx context.Context, userID string, videoID string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"userId": userID, "videoId": videoID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoWatchHistoryRepo) DeleteByUser(ctx context.Context, userID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	return err
+}
+
+func (r *mongoWatchHistoryRepo) DeleteByVideo(ctx context.Context, videoID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"videoId": videoID})
+	return err
+}
+
+type memWatchHistoryRepo struct {
+	table  *memTable[models.VideoWatchEntry]
+	videos *memVideoRepo
+}
+
+func (r *memWatchHistoryRepo) Add(ctx context.Context, entry *models.VideoWatchEntry) error {
+	stored := *entry
+	stored.Video = nil
+	return r.table.insert(entry.ID, stored, nil)
+}
+
+func (r *memWatchHistoryRepo) ListByUser(ctx context.Context, userID string, skip int64, limit int64) ([]models.VideoWatchEntry, error) {
+	entries := r.table.filter(
+		func(entry models.VideoWatchEntry) bool { return entry.UserID == userID },
+		func(a, b models.VideoWatchEntry) bool { return a.WatchedAt.After(b.WatchedAt) },
+	)
+	entries = page(entries, skip, limit)
+	for i := range entries {
+		if video, err := r.videos.FindByID(ctx, entries[i].VideoID); err == nil {
+			entries[i].Video = video
+		}
+	}
+	return entries, nil
+}
+
+func (r *memWatchHistoryRepo) Delete(ctx context.Context, userID string, videoID string) error {
+	// Remove a single entry, matching DeleteOne on the Mongo side
+	entry, ok := r.table.find(func(entry models.VideoWatchEntry) bool {
+		return entry.UserID == userID && entry.VideoID == videoID
+	})
+	if !ok || !r.table.delete(entry.ID) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memWatchHistoryRepo) DeleteByUser(ctx context.Context, userID string) error {
+	r.table.deleteWhere(func(entry models.VideoWatchEntry) bool { return entry.UserID == userID })
+	return nil
+}
+
+func (r *memWatchHistoryRepo) DeleteByVideo(ctx context.Context, videoID string) error {
+	r.table.deleteWhere(func(entry models.VideoWatchEntry) bool { return entry.VideoID == videoID })
+	return nil
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"yt_backend/controllers"
+	"yt_backend/middleware"
+	"yt_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminRoutes registers the moderation API; everything needs at least the
+// moderator role, and managing roles and reading the audit log need admin
+func AdminRoutes(incomingRoutes *gin.Engine) {
+	adminRoutes := incomingRoutes.Group("/admin", middleware.AuthMiddleware(), middleware.RequireRole(models.RoleModerator))
+	{
+		adminRoutes.GET("/users", controllers.ListUsers)
+		adminRoutes.POST("/users/:userId/suspend", controllers.SuspendUser)
+		adminRoutes.POST("/users/:userId/unsuspend", controllers.UnsuspendUser)
+		adminRoutes.PUT("/users/:userId/role", middleware.RequireRole(models.RoleAdmin), controllers.SetUserRole)
+		adminRoutes.POST("/videos/:videoId/takedown", controllers.TakeDownVideo)
+		adminRoutes.POST("/videos/:videoId/restore", controllers.RestoreVideo)
+		adminRoutes.DELETE("/comments/:commentId", controllers.RemoveComment)
+		adminRoutes.GET("/audit-logs", middleware.RequireRole(models.RoleAdmin), controllers.ListAuditLogs)
+	}
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"yt_backend/controllers"
+	"yt_backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ChannelRoutes(incomingRoutes *gin.Engine) {
+	channelRoutes := incomingRoutes.Group("/channels")
+	{
+		channelRoutes.POST("", middleware.AuthMiddleware(), controllers.CreateChannel)
+		channelRoutes.GET("/by-handle/:handle", controllers.GetChannelByHandle)
+		channelRoutes.GET("/:channelId", controllers.GetChannel)
+		channelRoutes.PATCH("/:channelId", middleware.AuthMiddleware(), controllers.UpdateChannel)
+		channelRoutes.DELETE("/:channelId", middleware.AuthMiddleware(), controllers.DeleteChannel)
+		channelRoutes.PUT("/:channelId/banner", middleware.AuthMiddleware(), controllers.UpdateChannelBanner)
+		channelRoutes.DELETE("/:channelId/banner", middleware.AuthMiddleware(), controllers.DeleteChannelBanner)
+	}
+}
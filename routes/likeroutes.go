@@ -3,12 +3,13 @@ package routes
 import (
 	"yt_backend/controllers"
 	"yt_backend/middleware"
+	"yt_backend/models"
 
 	"github.com/gin-gonic/gin"
 )
 
 func LikeRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.POST("/video/:videoID/like", middleware.AuthMiddleware(), controllers.LikeVideo)
-	incomingRoutes.DELETE("/video/:videoID/like", middleware.AuthMiddleware(), controllers.RemoveLike)
+	incomingRoutes.POST("/video/:videoID/like", middleware.AuthMiddleware(models.ScopeLikesWrite), controllers.LikeVideo)
+	incomingRoutes.DELETE("/video/:videoID/like", middleware.AuthMiddleware(models.ScopeLikesWrite), controllers.RemoveLike)
 	incomingRoutes.GET("/video/:videoID/likes", controllers.CountVideoLikes)
 }
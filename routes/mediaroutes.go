@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"log"
+
+	"yt_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MediaRoutes serves uploaded files from disk when the local media store is configured
+func MediaRoutes(incomingRoutes *gin.Engine) {
+	store, err := utils.GetMediaStore()
+	if err != nil {
+		log.Fatalf("Failed to configure media store: %v", err)
+	}
+
+	if localStore, ok := store.(*utils.LocalStore); ok {
+		incomingRoutes.Static("/media", localStore.Dir)
+	}
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"yt_backend/controllers"
+	"yt_backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func OIDCRoutes(incomingRoutes *gin.Engine) {
+	oidcRoutes := incomingRoutes.Group("/auth/oidc")
+	{
+		oidcRoutes.GET("/providers", controllers.ListOIDCProviders)
+		oidcRoutes.POST("/:provider/start", controllers.StartOIDCLogin)
+		oidcRoutes.POST("/:provider/callback", controllers.FinishOIDCLogin)
+	}
+
+	identityRoutes := incomingRoutes.Group("/users/identities", middleware.AuthMiddleware())
+	{
+		identityRoutes.GET("", controllers.ListIdentities)
+		identityRoutes.POST("/:provider", controllers.LinkOIDCIdentity)
+		identityRoutes.DELETE("/:identityId", controllers.UnlinkIdentity)
+	}
+}
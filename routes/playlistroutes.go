@@ -2,13 +2,18 @@ package routes
 
 import (
 	"yt_backend/controllers"
+	"yt_backend/middleware"
+	"yt_backend/models"
 
 	"github.com/gin-gonic/gin"
 )
 
 func PlaylistRoutes(incomingroutes *gin.Engine) {
-	incomingroutes.POST("/create", controllers.CreatePlaylist)
-	incomingroutes.POST("/add/:playlistId", controllers.AddToPlaylist)
-	incomingroutes.POST("/remove/:playlistId", controllers.RemoveFromPlaylist)
-	incomingroutes.DELETE("/:playlistId", controllers.DeletePlaylist)
+	playlistRoutes := incomingroutes.Group("/playlists", middleware.AuthMiddleware(models.ScopePlaylistsWrite))
+	{
+		playlistRoutes.POST("/create", controllers.CreatePlaylist)
+		playlistRoutes.POST("/add/:playlistId", controllers.AddToPlaylist)
+		playlistRoutes.POST("/remove/:playlistId", controllers.RemoveFromPlaylist)
+		playlistRoutes.DELETE("/:playlistId", controllers.DeletePlaylist)
+	}
 }
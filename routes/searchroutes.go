@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"yt_backend/controllers"
+	"yt_backend/middleware"
+	"yt_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SearchRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/search", middleware.OptionalAuth(models.ScopeVideosRead), controllers.SearchVideos)
+	incomingRoutes.GET("/search/suggestions", controllers.SearchSuggestions)
+}
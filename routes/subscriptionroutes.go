@@ -3,12 +3,16 @@ package routes
 import (
 	"yt_backend/controllers"
 	"yt_backend/middleware"
+	"yt_backend/models"
 
 	"github.com/gin-gonic/gin"
 )
 
 func SubscriptionRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.POST("/subscribe/:videoId", middleware.AuthMiddleware(), controllers.Subscribe)
-	incomingRoutes.DELETE("/unsubscribe/:videoId", middleware.AuthMiddleware(), controllers.Unsubscribe)
+	incomingRoutes.POST("/subscribe/:videoId", middleware.AuthMiddleware(models.ScopeSubscriptionsWrite), controllers.Subscribe)
+	incomingRoutes.DELETE("/unsubscribe/:videoId", middleware.AuthMiddleware(models.ScopeSubscriptionsWrite), controllers.Unsubscribe)
 	incomingRoutes.GET("/subscribers/count/:channelId", controllers.CountSubscribers)
+	incomingRoutes.POST("/channels/:channelId/subscription", middleware.AuthMiddleware(models.ScopeSubscriptionsWrite), controllers.SubscribeToChannel)
+	incomingRoutes.DELETE("/channels/:channelId/subscription", middleware.AuthMiddleware(models.ScopeSubscriptionsWrite), controllers.UnsubscribeFromChannel)
+	incomingRoutes.GET("/feed/subscriptions", middleware.AuthMiddleware(models.ScopeVideosRead), controllers.SubscriptionFeed)
 }
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"yt_backend/controllers"
+	"yt_backend/middleware"
+	"yt_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func UploadRoutes(incomingRoutes *gin.Engine) {
+	uploadRoutes := incomingRoutes.Group("/uploads", middleware.AuthMiddleware(models.ScopeVideosWrite))
+	{
+		uploadRoutes.POST("", controllers.CreateUpload)
+		uploadRoutes.PATCH("/:uploadId", controllers.UploadChunk)
+		uploadRoutes.PUT("/:uploadId", controllers.UploadChunk)
+		uploadRoutes.HEAD("/:uploadId", controllers.GetUploadStatus)
+		uploadRoutes.GET("/:uploadId", controllers.GetUploadStatus)
+		uploadRoutes.POST("/:uploadId/finalize", controllers.FinalizeUpload)
+		uploadRoutes.DELETE("/:uploadId", controllers.CancelUpload)
+	}
+}
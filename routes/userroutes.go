@@ -12,9 +12,36 @@ func UserRoutes(incomingRoutes *gin.Engine) {
 	{
 		userRoutes.POST("/signup", controllers.SignUp)
 		userRoutes.POST("/login", controllers.Login)
+		userRoutes.POST("/login/2fa", controllers.CompleteMFALogin)
 		userRoutes.POST("/logout", middleware.AuthMiddleware(), controllers.Logout)
-		userRoutes.PUT("/change-password", middleware.AuthMiddleware(), controllers.ChangePassword)
+		userRoutes.POST("/refresh", controllers.RefreshToken)
+		userRoutes.GET("/sessions", middleware.AuthMiddleware(), controllers.ListSessions)
+		userRoutes.DELETE("/sessions", middleware.AuthMiddleware(), controllers.RevokeOtherSessions)
+		userRoutes.DELETE("/sessions/:sessionId", middleware.AuthMiddleware(), controllers.RevokeSession)
+		userRoutes.PUT("/change-password", middleware.AuthMiddleware(), middleware.RequireFreshMFA(), controllers.ChangePassword)
+		userRoutes.POST("/verify-email", controllers.VerifyEmail)
+		userRoutes.POST("/verify-email/resend", middleware.AuthMiddleware(), controllers.ResendVerificationEmail)
+		userRoutes.POST("/forgot-password", controllers.ForgotPassword)
+		userRoutes.POST("/reset-password", controllers.ResetPassword)
+		userRoutes.GET("/2fa", middleware.AuthMiddleware(), controllers.TwoFactorStatus)
+		userRoutes.POST("/2fa/setup", middleware.AuthMiddleware(), controllers.SetupTwoFactor)
+		userRoutes.POST("/2fa/enable", middleware.AuthMiddleware(), controllers.EnableTwoFactor)
+		userRoutes.POST("/2fa/verify", middleware.AuthMiddleware(), controllers.VerifyTwoFactor)
+		userRoutes.POST("/2fa/recovery-codes", middleware.AuthMiddleware(), middleware.RequireFreshMFA(), controllers.RegenerateRecoveryCodes)
+		userRoutes.DELETE("/2fa", middleware.AuthMiddleware(), middleware.RequireFreshMFA(), controllers.DisableTwoFactor)
+		userRoutes.GET("/api-keys", middleware.AuthMiddleware(), controllers.ListAPIKeys)
+		userRoutes.POST("/api-keys", middleware.AuthMiddleware(), middleware.RequireFreshMFA(), controllers.CreateAPIKey)
+		userRoutes.DELETE("/api-keys/:keyId", middleware.AuthMiddleware(), controllers.RevokeAPIKey)
+		userRoutes.GET("/me", middleware.AuthMiddleware(), controllers.GetMe)
+		userRoutes.PATCH("/me", middleware.AuthMiddleware(), controllers.UpdateProfile)
+		userRoutes.DELETE("/me", middleware.AuthMiddleware(), middleware.RequireFreshMFA(), controllers.DeleteAccount)
+		userRoutes.GET("/me/export", middleware.AuthMiddleware(), middleware.RequireFreshMFA(), controllers.ExportAccount)
+		userRoutes.PUT("/me/avatar", middleware.AuthMiddleware(), controllers.UpdateAvatar)
+		userRoutes.DELETE("/me/avatar", middleware.AuthMiddleware(), controllers.DeleteAvatar)
+		userRoutes.PUT("/me/cover-image", middleware.AuthMiddleware(), controllers.UpdateCoverImage)
+		userRoutes.DELETE("/me/cover-image", middleware.AuthMiddleware(), controllers.DeleteCoverImage)
 		userRoutes.PUT("/create-channel", middleware.AuthMiddleware(), controllers.CreateChannel)
 		userRoutes.GET("/subscribed-to-channel", middleware.AuthMiddleware(), controllers.SubscribedToChannel)
+		userRoutes.GET("/:userId", controllers.GetProfile)
 	}
 }
@@ -3,12 +3,13 @@ package routes
 import (
 	"yt_backend/controllers"
 	"yt_backend/middleware"
+	"yt_backend/models"
 
 	"github.com/gin-gonic/gin"
 )
 
 func VideoCommentRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.POST("/:videoId/post-comment", middleware.AuthMiddleware(), controllers.PostComment)
-	incomingRoutes.DELETE("/:videoId/:commentId", middleware.AuthMiddleware(), controllers.DeleteComment)
-	incomingRoutes.PUT("/video/:commentId",middleware.AuthMiddleware(),controllers.EditComment)
+	incomingRoutes.POST("/:videoId/post-comment", middleware.AuthMiddleware(models.ScopeCommentsWrite), controllers.PostComment)
+	incomingRoutes.DELETE("/:videoId/:commentId", middleware.AuthMiddleware(models.ScopeCommentsWrite), controllers.DeleteComment)
+	incomingRoutes.PUT("/video/:commentId",middleware.AuthMiddleware(models.ScopeCommentsWrite),controllers.EditComment)
 }
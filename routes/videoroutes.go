@@ -3,11 +3,19 @@ package routes
 import (
 	"yt_backend/controllers"
 	"yt_backend/middleware"
+	"yt_backend/models"
 	"github.com/gin-gonic/gin"
 )
 
 func VideoRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.POST("/videos/upload", middleware.AuthMiddleware(), controllers.UploadVideo)
-	incomingRoutes.DELETE("/videos/:videoId", middleware.AuthMiddleware(), controllers.DeleteVideo)
-	incomingRoutes.GET("/videos/:videoId/views", controllers.IncrementViews)
+	incomingRoutes.POST("/videos/upload", middleware.AuthMiddleware(models.ScopeVideosWrite), controllers.UploadVideo)
+	incomingRoutes.GET("/videos", middleware.OptionalAuth(models.ScopeVideosRead), controllers.ListVideos)
+	incomingRoutes.GET("/videos/:videoId", middleware.OptionalAuth(models.ScopeVideosRead), controllers.GetVideo)
+	incomingRoutes.GET("/channels/:channelId/videos", middleware.OptionalAuth(models.ScopeVideosRead), controllers.ListChannelVideos)
+	incomingRoutes.DELETE("/videos/:videoId", middleware.AuthMiddleware(models.ScopeVideosWrite), middleware.RequireFreshMFA(), controllers.DeleteVideo)
+	incomingRoutes.PATCH("/videos/:videoId", middleware.AuthMiddleware(models.ScopeVideosWrite), controllers.UpdateVideo)
+	incomingRoutes.GET("/videos/:videoId/views", middleware.OptionalAuth(), controllers.IncrementViews)
+	incomingRoutes.GET("/videos/:videoId/status", middleware.AuthMiddleware(models.ScopeVideosRead), controllers.GetVideoStatus)
+	incomingRoutes.GET("/videos/:videoId/hls/*file", middleware.OptionalAuth(models.ScopeVideosRead), controllers.StreamVideo)
+	incomingRoutes.PUT("/videos/:videoId/thumbnail", middleware.AuthMiddleware(models.ScopeVideosWrite), controllers.UploadThumbnail)
 }
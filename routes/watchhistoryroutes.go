@@ -3,6 +3,7 @@ package routes
 import (
 	"yt_backend/controllers"
 	"yt_backend/middleware"
+	"yt_backend/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,8 +11,8 @@ import (
 func SetupWatchHistoryRoutes(incomingRoutes *gin.Engine) {
 	watchHistory := incomingRoutes.Group("/watch-history")
 	{
-		watchHistory.POST("/add", middleware.AuthMiddleware(), controllers.AddVideoToWatchHistory)
-		watchHistory.GET("/history", middleware.AuthMiddleware(), controllers.GetWatchHistory)
-		watchHistory.DELETE("/delete/:video_id", middleware.AuthMiddleware(), controllers.DeleteVideoFromWatchHistory)
+		watchHistory.POST("/add", middleware.AuthMiddleware(models.ScopeHistoryWrite), controllers.AddVideoToWatchHistory)
+		watchHistory.GET("/history", middleware.AuthMiddleware(models.ScopeHistoryRead), controllers.GetWatchHistory)
+		watchHistory.DELETE("/delete/:video_id", middleware.AuthMiddleware(models.ScopeHistoryWrite), controllers.DeleteVideoFromWatchHistory)
 	}
 }
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"yt_backend/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WellKnownRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/.well-known/jwks.json", controllers.JWKS)
+}
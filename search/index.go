@@ -0,0 +1,134 @@
+// Package search keeps a full-text index of the publicly listed videos
+package search
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"yt_backend/models"
+	"yt_backend/repository"
+)
+
+// Index is a full-text index over videos
+type Index interface {
+	// Index adds or replaces a document
+	Index(ctx context.Context, doc Document) error
+	// Remove drops a document; removing a missing document is not an error
+	Remove(ctx context.Context, videoID string) error
+	// Search returns the documents matching the query, best match first
+	Search(ctx context.Context, query Query) (*Result, error)
+	// Suggest completes the last word of a partially typed query
+	Suggest(ctx context.Context, prefix string, limit int) ([]string, error)
+}
+
+// Document is the searchable projection of a video
+type Document struct {
+	ID              string     `bson:"_id"`
+	Title           string     `bson:"title"`
+	Description     string     `bson:"description"`
+	Tags            []string   `bson:"tags"`
+	ChannelID       string     `bson:"channelId"`
+	ChannelName     string     `bson:"channelName"`
+	DurationSeconds float64    `bson:"durationSeconds"`
+	CreatedAt       time.Time  `bson:"createdAt"`
+	PublishAt       *time.Time `bson:"publishAt"`
+}
+
+// Query describes a search request. Zero values disable a filter.
+type Query struct {
+	Text          string
+	ChannelID     string
+	MinDuration   float64 // seconds, inclusive
+	MaxDuration   float64 // seconds, exclusive
+	UploadedAfter *time.Time
+	Now           time.Time // scheduled videos are hidden until their publish time
+	Offset        int64
+	Limit         int64
+}
+
+// Hit is a matching video and its relevance score
+type Hit struct {
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// Result is one page of hits
+type Result struct {
+	Hits  []Hit
+	Total int64
+	// CorrectedQuery is set when misspelled words were replaced with indexed ones
+	CorrectedQuery string
+}
+
+// Field weights: a match in the title counts more than one in the description
+const (
+	titleWeight       = 10
+	tagWeight         = 5
+	channelNameWeight = 3
+	descriptionWeight = 1
+)
+
+// DocumentFromVideo builds the document for a video, reporting false when the
+// video should not be searchable (unlisted, private or not ready)
+func DocumentFromVideo(video *models.Video) (Document, bool) {
+	if video.Visibility == models.VisibilityUnlisted || video.Visibility == models.VisibilityPrivate {
+		return Document{}, false
+	}
+	if video.Status != "" && video.Status != models.VideoStatusReady {
+		return Document{}, false
+	}
+	if video.TakenDownAt != nil {
+		return Document{}, false
+	}
+
+	channelName := ""
+	if video.Channel != nil {
+		channelName = video.Channel.ChannelName
+	}
+	return Document{
+		ID:              video.ID,
+		Title:           video.Title,
+		Description:     video.Description,
+		Tags:            video.Tags,
+		ChannelID:       video.ChannelID,
+		ChannelName:     channelName,
+		DurationSeconds: video.DurationSeconds,
+		CreatedAt:       video.CreatedAt,
+		PublishAt:       video.PublishAt,
+	}, true
+}
+
+// Sync brings the index in line with the current state of a video
+func Sync(ctx context.Context, index Index, video *models.Video) error {
+	if doc, ok := DocumentFromVideo(video); ok {
+		return index.Index(ctx, doc)
+	}
+	return index.Remove(ctx, video.ID)
+}
+
+// Reindex adds every listed video to the index, e.g. after the index was created
+func Reindex(ctx context.Context, index Index, videos repository.VideoRepo) error {
+	query := repository.VideoQuery{Sort: repository.VideoSortNewest, IncludeHidden: true, Limit: 200}
+	indexed := 0
+	for {
+		page, err := videos.List(ctx, query)
+		if err != nil {
+			return err
+		}
+		for i := range page {
+			if err := Sync(ctx, index, &page[i]); err != nil {
+				return err
+			}
+			indexed++
+		}
+		if int64(len(page)) < query.Limit {
+			break
+		}
+		last := page[len(page)-1]
+		query.After = &repository.VideoCursor{Time: last.CreatedAt, ID: last.ID}
+	}
+
+	log.Printf("search: indexed %d videos", indexed)
+	return nil
+}
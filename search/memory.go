@@ -0,0 +1,190 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryIndex is an in-process inverted index, used with the in-memory repositories
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	docs     map[string]Document
+	postings map[string]map[string]float64 // term -> document ID -> weighted term frequency
+	docTerms map[string][]string           // document ID -> terms, for removal
+}
+
+// NewMemoryIndex creates an empty in-memory index
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		docs:     make(map[string]Document),
+		postings: make(map[string]map[string]float64),
+		docTerms: make(map[string][]string),
+	}
+}
+
+func (idx *MemoryIndex) Index(ctx context.Context, doc Document) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(doc.ID)
+
+	weights := make(map[string]float64)
+	addField := func(text string, weight float64) {
+		for _, token := range Tokenize(text) {
+			weights[token] += weight
+		}
+	}
+	addField(doc.Title, titleWeight)
+	addField(strings.Join(doc.Tags, " "), tagWeight)
+	addField(doc.ChannelName, channelNameWeight)
+	addField(doc.Description, descriptionWeight)
+
+	terms := make([]string, 0, len(weights))
+	for term, weight := range weights {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[string]float64)
+		}
+		idx.postings[term][doc.ID] = weight
+		terms = append(terms, term)
+	}
+	idx.docs[doc.ID] = doc
+	idx.docTerms[doc.ID] = terms
+	return nil
+}
+
+func (idx *MemoryIndex) Remove(ctx context.Context, videoID string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(videoID)
+	return nil
+}
+
+func (idx *MemoryIndex) remove(videoID string) {
+	for _, term := range idx.docTerms[videoID] {
+		delete(idx.postings[term], videoID)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.docs, videoID)
+	delete(idx.docTerms, videoID)
+}
+
+func (idx *MemoryIndex) Search(ctx context.Context, query Query) (*Result, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	terms, corrected := idx.correct(Tokenize(query.Text))
+	result := &Result{Hits: []Hit{}}
+	if corrected {
+		result.CorrectedQuery = strings.Join(terms, " ")
+	}
+
+	// Score with tf-idf, then favour documents matching more of the query words
+	scores := make(map[string]float64)
+	matched := make(map[string]int)
+	total := float64(len(idx.docs))
+	for _, term := range terms {
+		postings := idx.postings[term]
+		idf := math.Log(1 + total/float64(len(postings)+1))
+		for id, weight := range postings {
+			if !query.matches(idx.docs[id]) {
+				continue
+			}
+			scores[id] += weight * idf
+			matched[id]++
+		}
+	}
+
+	for id, score := range scores {
+		coverage := float64(matched[id]) / float64(len(terms))
+		result.Hits = append(result.Hits, Hit{ID: id, Score: score * coverage})
+	}
+	sort.Slice(result.Hits, func(i, j int) bool {
+		a, b := result.Hits[i], result.Hits[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return idx.docs[a.ID].CreatedAt.After(idx.docs[b.ID].CreatedAt)
+	})
+
+	result.Total = int64(len(result.Hits))
+	start := min(max(query.Offset, 0), result.Total)
+	end := result.Total
+	if query.Limit > 0 {
+		end = min(start+query.Limit, result.Total)
+	}
+	result.Hits = result.Hits[start:end]
+	return result, nil
+}
+
+// correct replaces words that aren't indexed with the closest indexed word
+func (idx *MemoryIndex) correct(terms []string) ([]string, bool) {
+	corrected := false
+	out := make([]string, len(terms))
+	for i, term := range terms {
+		out[i] = term
+		if _, ok := idx.postings[term]; ok || maxTypos(term) == 0 {
+			continue
+		}
+
+		candidates := make(map[string]int64)
+		for candidate, postings := range idx.postings {
+			candidates[candidate] = int64(len(postings))
+		}
+		if replacement, ok := closestTerm(term, candidates); ok {
+			out[i] = replacement
+			corrected = true
+		}
+	}
+	return out, corrected
+}
+
+func (idx *MemoryIndex) Suggest(ctx context.Context, prefix string, limit int) ([]string, error) {
+	typed, partial := splitPrefix(prefix)
+	if partial == "" {
+		return []string{}, nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	// Most used words first
+	var words []string
+	for term := range idx.postings {
+		if strings.HasPrefix(term, partial) {
+			words = append(words, term)
+		}
+	}
+	sort.Slice(words, func(i, j int) bool {
+		a, b := len(idx.postings[words[i]]), len(idx.postings[words[j]])
+		if a != b {
+			return a > b
+		}
+		return words[i] < words[j]
+	})
+	if len(words) > limit {
+		words = words[:limit]
+	}
+	return completions(typed, words), nil
+}
+
+// matches applies the query filters to a document
+func (q *Query) matches(doc Document) bool {
+	if q.ChannelID != "" && doc.ChannelID != q.ChannelID {
+		return false
+	}
+	if q.MinDuration > 0 && doc.DurationSeconds < q.MinDuration {
+		return false
+	}
+	if q.MaxDuration > 0 && doc.DurationSeconds >= q.MaxDuration {
+		return false
+	}
+	if q.UploadedAfter != nil && doc.CreatedAt.Before(*q.UploadedAfter) {
+		return false
+	}
+	return doc.PublishAt == nil || !doc.PublishAt.After(q.Now)
+}
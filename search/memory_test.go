@@ -0,0 +1,207 @@
+package search
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"yt_backend/models"
+)
+
+var testNow = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestIndex(t *testing.T, docs ...Document) *MemoryIndex {
+	t.Helper()
+	index := NewMemoryIndex()
+	for _, doc := range docs {
+		if err := index.Index(context.Background(), doc); err != nil {
+			t.Fatalf("Index(%s): %v", doc.ID, err)
+		}
+	}
+	return index
+}
+
+func hitIDs(result *Result) []string {
+	ids := make([]string, len(result.Hits))
+	for i, hit := range result.Hits {
+		ids[i] = hit.ID
+	}
+	return ids
+}
+
+func TestSearchRanksTitleAboveDescription(t *testing.T) {
+	index := newTestIndex(t,
+		Document{ID: "description", Title: "Weekend vlog", Description: "We bake sourdough bread"},
+		Document{ID: "title", Title: "Sourdough bread from scratch"},
+		Document{ID: "tag", Title: "Morning routine", Tags: []string{"sourdough"}},
+		Document{ID: "unrelated", Title: "Guitar lesson"},
+	)
+
+	result, err := index.Search(context.Background(), Query{Text: "sourdough", Now: testNow})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if want := []string{"title", "tag", "description"}; !slices.Equal(hitIDs(result), want) {
+		t.Errorf("hits = %v, want %v", hitIDs(result), want)
+	}
+	if result.Total != 3 {
+		t.Errorf("Total = %d, want 3", result.Total)
+	}
+}
+
+func TestSearchPrefersDocumentsMatchingMoreWords(t *testing.T) {
+	index := newTestIndex(t,
+		Document{ID: "one", Title: "Bread"},
+		Document{ID: "both", Title: "Sourdough bread"},
+	)
+
+	result, _ := index.Search(context.Background(), Query{Text: "sourdough bread", Now: testNow})
+	if ids := hitIDs(result); len(ids) != 2 || ids[0] != "both" {
+		t.Errorf("hits = %v, want both first", ids)
+	}
+}
+
+func TestSearchCorrectsTypos(t *testing.T) {
+	index := newTestIndex(t, Document{ID: "v1", Title: "Sourdough bread"})
+
+	result, _ := index.Search(context.Background(), Query{Text: "sourdouh bred", Now: testNow})
+	if !slices.Equal(hitIDs(result), []string{"v1"}) {
+		t.Errorf("hits = %v, want v1", hitIDs(result))
+	}
+	if result.CorrectedQuery != "sourdough bread" {
+		t.Errorf("CorrectedQuery = %q, want %q", result.CorrectedQuery, "sourdough bread")
+	}
+
+	// Short words must match exactly
+	result, _ = index.Search(context.Background(), Query{Text: "brd", Now: testNow})
+	if len(result.Hits) != 0 || result.CorrectedQuery != "" {
+		t.Errorf("short typo matched %v (corrected to %q)", hitIDs(result), result.CorrectedQuery)
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	later := testNow.Add(time.Hour)
+	uploaded := testNow.Add(-24 * time.Hour)
+	index := newTestIndex(t,
+		Document{ID: "short", Title: "cooking", ChannelID: "c1", DurationSeconds: 60, CreatedAt: uploaded},
+		Document{ID: "long", Title: "cooking", ChannelID: "c2", DurationSeconds: 1200, CreatedAt: uploaded},
+		Document{ID: "old", Title: "cooking", ChannelID: "c1", DurationSeconds: 300, CreatedAt: uploaded.AddDate(-1, 0, 0)},
+		Document{ID: "scheduled", Title: "cooking", ChannelID: "c1", DurationSeconds: 300, CreatedAt: uploaded, PublishAt: &later},
+	)
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"channel", Query{ChannelID: "c2"}, []string{"long"}},
+		{"min duration", Query{MinDuration: 240}, []string{"long", "old"}},
+		{"max duration", Query{MaxDuration: 240}, []string{"short"}},
+		{"uploaded after", Query{UploadedAfter: &uploaded}, []string{"long", "short"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.query.Text = "cooking"
+			test.query.Now = testNow
+			result, err := index.Search(context.Background(), test.query)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			got := hitIDs(result)
+			slices.Sort(got)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("hits = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	result, _ := index.Search(context.Background(), Query{Text: "cooking", Now: later})
+	if !slices.Contains(hitIDs(result), "scheduled") {
+		t.Errorf("scheduled video missing once published: %v", hitIDs(result))
+	}
+}
+
+func TestSearchPagination(t *testing.T) {
+	var docs []Document
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		docs = append(docs, Document{ID: id, Title: "cooking", CreatedAt: testNow.Add(time.Duration(i) * time.Minute)})
+	}
+	index := newTestIndex(t, docs...)
+
+	var pages []string
+	for offset := int64(0); offset < 6; offset += 2 {
+		result, _ := index.Search(context.Background(), Query{Text: "cooking", Now: testNow.Add(time.Hour), Offset: offset, Limit: 2})
+		if result.Total != 5 {
+			t.Fatalf("Total = %d, want 5", result.Total)
+		}
+		pages = append(pages, hitIDs(result)...)
+	}
+	// Equal scores fall back to the newest upload
+	if want := []string{"e", "d", "c", "b", "a"}; !slices.Equal(pages, want) {
+		t.Errorf("pages = %v, want %v", pages, want)
+	}
+}
+
+func TestIndexReplacesAndRemovesDocuments(t *testing.T) {
+	ctx := context.Background()
+	index := newTestIndex(t, Document{ID: "v1", Title: "Sourdough bread"})
+
+	if err := index.Index(ctx, Document{ID: "v1", Title: "Guitar lesson"}); err != nil {
+		t.Fatalf("Index: %v", err)
+	}
+	if result, _ := index.Search(ctx, Query{Text: "bread", Now: testNow}); len(result.Hits) != 0 {
+		t.Errorf("old title still matches: %v", hitIDs(result))
+	}
+	if result, _ := index.Search(ctx, Query{Text: "guitar", Now: testNow}); !slices.Equal(hitIDs(result), []string{"v1"}) {
+		t.Errorf("new title hits = %v, want v1", hitIDs(result))
+	}
+
+	if err := index.Remove(ctx, "v1"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := index.Remove(ctx, "v1"); err != nil {
+		t.Errorf("removing a missing document = %v, want nil", err)
+	}
+	if result, _ := index.Search(ctx, Query{Text: "guitar", Now: testNow}); len(result.Hits) != 0 {
+		t.Errorf("removed document still matches: %v", hitIDs(result))
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	index := newTestIndex(t,
+		Document{ID: "v1", Title: "Sourdough starter"},
+		Document{ID: "v2", Title: "Sourdough bread"},
+		Document{ID: "v3", Title: "Soup"},
+	)
+
+	got, _ := index.Suggest(context.Background(), "easy sou", 5)
+	// The most used completion comes first
+	if want := []string{"easy sourdough", "easy soup"}; !slices.Equal(got, want) {
+		t.Errorf("Suggest = %v, want %v", got, want)
+	}
+	if got, _ := index.Suggest(context.Background(), "sourdough ", 5); len(got) != 0 {
+		t.Errorf("Suggest after a finished word = %v, want none", got)
+	}
+}
+
+func TestSyncLeavesHiddenVideosOut(t *testing.T) {
+	ctx := context.Background()
+	index := NewMemoryIndex()
+	video := &models.Video{ID: "v1", Title: "Sourdough", Visibility: models.VisibilityPublic, Status: models.VideoStatusReady}
+
+	if err := Sync(ctx, index, video); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if result, _ := index.Search(ctx, Query{Text: "sourdough", Now: testNow}); len(result.Hits) != 1 {
+		t.Fatalf("public video not indexed")
+	}
+
+	video.Visibility = models.VisibilityPrivate
+	if err := Sync(ctx, index, video); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if result, _ := index.Search(ctx, Query{Text: "sourdough", Now: testNow}); len(result.Hits) != 0 {
+		t.Errorf("private video still indexed")
+	}
+}
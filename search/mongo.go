@@ -0,0 +1,275 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoIndex searches with a MongoDB text index. Documents live in their own
+// collection so the index can be rebuilt without touching the videos, and a
+// term collection counts the indexed words for typo correction and suggestions.
+type MongoIndex struct {
+	documents *mongo.Collection
+	terms     *mongo.Collection
+}
+
+// NewMongoIndex creates the search collections' indexes if needed
+func NewMongoIndex(ctx context.Context, db *mongo.Database) (*MongoIndex, error) {
+	idx := &MongoIndex{
+		documents: db.Collection("search_documents"),
+		terms:     db.Collection("search_terms"),
+	}
+
+	// Our tokenizer does the stop word handling, so the text index must not stem
+	_, err := idx.documents.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "title", Value: "text"},
+			{Key: "tags", Value: "text"},
+			{Key: "channelName", Value: "text"},
+			{Key: "description", Value: "text"},
+		},
+		Options: options.Index().
+			SetName("video_text").
+			SetDefaultLanguage("none").
+			SetWeights(bson.D{
+				{Key: "title", Value: titleWeight},
+				{Key: "tags", Value: tagWeight},
+				{Key: "channelName", Value: channelNameWeight},
+				{Key: "description", Value: descriptionWeight},
+			}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search index: %v", err)
+	}
+
+	_, err = idx.terms.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "count", Value: -1}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search term index: %v", err)
+	}
+	return idx, nil
+}
+
+// documentTerms lists the distinct words of a document
+func documentTerms(doc Document) map[string]bool {
+	terms := make(map[string]bool)
+	for _, text := range []string{doc.Title, strings.Join(doc.Tags, " "), doc.ChannelName, doc.Description} {
+		for _, token := range Tokenize(text) {
+			terms[token] = true
+		}
+	}
+	return terms
+}
+
+func (idx *MongoIndex) Index(ctx context.Context, doc Document) error {
+	var previous Document
+	err := idx.documents.FindOne(ctx, bson.M{"_id": doc.ID}).Decode(&previous)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	_, err = idx.documents.ReplaceOne(ctx, bson.M{"_id": doc.ID}, doc, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	// Count each word once per document
+	added := documentTerms(doc)
+	removed := map[string]bool{}
+	if previous.ID != "" {
+		for term := range documentTerms(previous) {
+			if added[term] {
+				delete(added, term)
+			} else {
+				removed[term] = true
+			}
+		}
+	}
+	return idx.adjustTerms(ctx, added, removed)
+}
+
+func (idx *MongoIndex) Remove(ctx context.Context, videoID string) error {
+	var previous Document
+	err := idx.documents.FindOneAndDelete(ctx, bson.M{"_id": videoID}).Decode(&previous)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return idx.adjustTerms(ctx, nil, documentTerms(previous))
+}
+
+// adjustTerms updates the document counts of the vocabulary
+func (idx *MongoIndex) adjustTerms(ctx context.Context, added map[string]bool, removed map[string]bool) error {
+	var writes []mongo.WriteModel
+	for term := range added {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": term}).
+			SetUpdate(bson.M{"$inc": bson.M{"count": 1}}).
+			SetUpsert(true))
+	}
+	for term := range removed {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": term}).
+			SetUpdate(bson.M{"$inc": bson.M{"count": -1}}))
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	if _, err := idx.terms.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return err
+	}
+	if len(removed) > 0 {
+		_, err := idx.terms.DeleteMany(ctx, bson.M{"count": bson.M{"$lte": 0}})
+		return err
+	}
+	return nil
+}
+
+func (idx *MongoIndex) Search(ctx context.Context, query Query) (*Result, error) {
+	terms, corrected, err := idx.correct(ctx, Tokenize(query.Text))
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Hits: []Hit{}}
+	if corrected {
+		result.CorrectedQuery = strings.Join(terms, " ")
+	}
+	if len(terms) == 0 {
+		return result, nil
+	}
+
+	filter := bson.M{"$text": bson.M{"$search": strings.Join(terms, " ")}}
+	conditions := bson.A{bson.M{"$or": bson.A{bson.M{"publishAt": nil}, bson.M{"publishAt": bson.M{"$lte": query.Now}}}}}
+	if query.ChannelID != "" {
+		conditions = append(conditions, bson.M{"channelId": query.ChannelID})
+	}
+	if query.MinDuration > 0 {
+		conditions = append(conditions, bson.M{"durationSeconds": bson.M{"$gte": query.MinDuration}})
+	}
+	if query.MaxDuration > 0 {
+		conditions = append(conditions, bson.M{"durationSeconds": bson.M{"$lt": query.MaxDuration}})
+	}
+	if query.UploadedAfter != nil {
+		conditions = append(conditions, bson.M{"createdAt": bson.M{"$gte": *query.UploadedAfter}})
+	}
+	filter["$and"] = conditions
+
+	total, err := idx.documents.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	result.Total = total
+
+	opts := options.Find().
+		SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "createdAt", Value: -1}}).
+		SetSkip(max(query.Offset, 0))
+	if query.Limit > 0 {
+		opts.SetLimit(query.Limit)
+	}
+	cursor, err := idx.documents.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []struct {
+		ID    string  `bson:"_id"`
+		Score float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &hits); err != nil {
+		return nil, err
+	}
+	for _, hit := range hits {
+		result.Hits = append(result.Hits, Hit{ID: hit.ID, Score: hit.Score})
+	}
+	return result, nil
+}
+
+// correct replaces words that aren't indexed with the closest indexed word.
+// Candidates share the first letter and are at most two characters longer or shorter.
+func (idx *MongoIndex) correct(ctx context.Context, terms []string) ([]string, bool, error) {
+	corrected := false
+	out := make([]string, len(terms))
+	for i, term := range terms {
+		out[i] = term
+		if maxTypos(term) == 0 {
+			continue
+		}
+
+		known, err := idx.terms.CountDocuments(ctx, bson.M{"_id": term})
+		if err != nil {
+			return nil, false, err
+		}
+		if known > 0 {
+			continue
+		}
+
+		first, _ := utf8.DecodeRuneInString(term)
+		length := utf8.RuneCountInString(term)
+		filter := bson.M{
+			"_id": bson.M{"$regex": "^" + regexp.QuoteMeta(string(first))},
+			"$expr": bson.M{"$and": bson.A{
+				bson.M{"$gte": bson.A{bson.M{"$strLenCP": "$_id"}, length - 2}},
+				bson.M{"$lte": bson.A{bson.M{"$strLenCP": "$_id"}, length + 2}},
+			}},
+		}
+		cursor, err := idx.terms.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "count", Value: -1}}).SetLimit(1000))
+		if err != nil {
+			return nil, false, err
+		}
+		var rows []struct {
+			Term  string `bson:"_id"`
+			Count int64  `bson:"count"`
+		}
+		if err := cursor.All(ctx, &rows); err != nil {
+			return nil, false, err
+		}
+
+		candidates := make(map[string]int64, len(rows))
+		for _, row := range rows {
+			candidates[row.Term] = row.Count
+		}
+		if replacement, ok := closestTerm(term, candidates); ok {
+			out[i] = replacement
+			corrected = true
+		}
+	}
+	return out, corrected, nil
+}
+
+func (idx *MongoIndex) Suggest(ctx context.Context, prefix string, limit int) ([]string, error) {
+	typed, partial := splitPrefix(prefix)
+	if partial == "" {
+		return []string{}, nil
+	}
+
+	cursor, err := idx.terms.Find(ctx,
+		bson.M{"_id": bson.M{"$regex": "^" + regexp.QuoteMeta(partial)}},
+		options.Find().SetSort(bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Term string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	words := make([]string, 0, len(rows))
+	for _, row := range rows {
+		words = append(words, row.Term)
+	}
+	return completions(typed, words), nil
+}
@@ -0,0 +1,112 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopWords are too common to be worth indexing
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "in": true, "is": true, "it": true, "of": true,
+	"on": true, "or": true, "the": true, "to": true, "with": true,
+}
+
+// Tokenize lowercases text and splits it into indexable words
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	tokens := make([]string, 0, len(words))
+	for _, word := range words {
+		if !stopWords[word] {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// maxTypos is how many edits a word of the given length may be away from an indexed word
+func maxTypos(word string) int {
+	switch n := len([]rune(word)); {
+	case n <= 3:
+		return 0
+	case n <= 7:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// closestTerm picks the candidate with the fewest edits from word, within maxTypos.
+// Ties go to the candidate with the higher weight (e.g. document frequency).
+func closestTerm(word string, candidates map[string]int64) (string, bool) {
+	limit := maxTypos(word)
+	best, bestDistance, bestWeight := "", limit+1, int64(-1)
+	for candidate, weight := range candidates {
+		distance := levenshtein(word, candidate, limit)
+		if distance < bestDistance || (distance == bestDistance && weight > bestWeight) {
+			best, bestDistance, bestWeight = candidate, distance, weight
+		}
+	}
+	return best, bestDistance <= limit
+}
+
+// levenshtein returns the edit distance between a and b, or limit+1 once it is
+// known to exceed limit
+func levenshtein(a string, b string, limit int) int {
+	ar, br := []rune(a), []rune(b)
+	if abs(len(ar)-len(br)) > limit {
+		return limit + 1
+	}
+
+	previous := make([]int, len(br)+1)
+	current := make([]int, len(br)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		previous, current = current, previous
+	}
+	return previous[len(br)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// splitPrefix separates the words already typed from the one being completed
+func splitPrefix(prefix string) ([]string, string) {
+	tokens := Tokenize(prefix)
+	// A trailing space means the last word is finished, so there is nothing to complete
+	if len(tokens) == 0 || strings.HasSuffix(prefix, " ") {
+		return tokens, ""
+	}
+	return tokens[:len(tokens)-1], tokens[len(tokens)-1]
+}
+
+// completions joins the typed words with each completed last word
+func completions(typed []string, words []string) []string {
+	suggestions := make([]string, 0, len(words))
+	for _, word := range words {
+		suggestions = append(suggestions, strings.Join(append(append([]string{}, typed...), word), " "))
+	}
+	return suggestions
+}
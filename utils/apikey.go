@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// apiKeyPrefix starts every API key, so they are easy to tell from access
+// tokens and for secret scanners to spot
+const apiKeyPrefix = "ytk_"
+
+// NewAPIKey returns a new API key "ytk_<id>_<secret>" along with its public id,
+// used to look the key up, and the hash of the secret to store. The key itself
+// is never stored.
+func NewAPIKey() (string, string, string, error) {
+	id := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+	prefix := hex.EncodeToString(id)
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+	return apiKeyPrefix + prefix + "_" + encoded, prefix, HashOneTimeToken(encoded), nil
+}
+
+// IsAPIKey reports whether a bearer credential is an API key rather than an access token
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
+
+// ParseAPIKey splits an API key into its public id and secret
+func ParseAPIKey(key string) (string, string, bool) {
+	prefix, secret, found := strings.Cut(strings.TrimPrefix(key, apiKeyPrefix), "_")
+	if !IsAPIKey(key) || !found || len(prefix) != 16 || secret == "" {
+		return "", "", false
+	}
+	return prefix, secret, true
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"mime/multipart"
+	"net/http"
 	"os"
-	"path/filepath"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/admin"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
 )
 
@@ -32,113 +35,152 @@ func NewCloudinaryService() (*CloudinaryService, error) {
 	return &CloudinaryService{cld: cld}, nil
 }
 
-// SaveToTempFile saves a multipart file to a temporary file and returns the file path
-func SaveToTempFile(file *multipart.FileHeader) (string, error) {
-	// Create a temporary file
-	tempFile, err := os.CreateTemp("", "upload-*"+filepath.Ext(file.Filename))
-	if err != nil {
-		return "", err
+// Put uploads the content to Cloudinary under the given key
+func (s *CloudinaryService) Put(ctx context.Context, key string, content io.Reader, opts PutOptions) (string, error) {
+	resourceType := opts.ResourceType
+	if resourceType == "" {
+		resourceType = "auto"
 	}
-	defer tempFile.Close()
 
-	// Open the uploaded file
-	src, err := file.Open()
-	if err != nil {
-		return "", err
+	// Cloudinary keeps the extension as part of the public ID only for raw files
+	publicID := key
+	if resourceType != "raw" {
+		publicID = strings.TrimSuffix(key, path.Ext(key))
 	}
-	defer src.Close()
 
-	// Copy the file content to the temporary file
-	if _, err := io.Copy(tempFile, src); err != nil {
-		return "", err
+	overwrite := true
+	uploadParams := uploader.UploadParams{
+		PublicID:     publicID,
+		ResourceType: resourceType,
+		Overwrite:    &overwrite,
 	}
 
-	return tempFile.Name(), nil
+	// Upload the file
+	result, err := s.cld.Upload.Upload(ctx, content, uploadParams)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %v", err)
+	}
+	if result.Error.Message != "" {
+		return "", fmt.Errorf("failed to upload file: %s", result.Error.Message)
+	}
+
+	return result.SecureURL, nil
 }
 
-// HandleUpload handles the complete process of saving and uploading files to Cloudinary
-func HandleUpload(ctx context.Context, file *multipart.FileHeader, folder string, resourceType string) (string, error) {
-	// Initialize Cloudinary service
-	cloudinaryService, err := NewCloudinaryService()
-	if err != nil {
-		return "", err
+// Delete deletes a file from Cloudinary using its URL
+func (s *CloudinaryService) Delete(ctx context.Context, fileURL string) error {
+	resourceType, publicID := extractPublicID(fileURL)
+	if publicID == "" {
+		return fmt.Errorf("invalid Cloudinary URL")
+	}
+	// Avatars can come from elsewhere, e.g. a sign-in provider; never delete
+	// an asset with the same public ID from another cloud's URL
+	if !strings.Contains(fileURL, "/"+s.cld.Config.Cloud.CloudName+"/") {
+		return fmt.Errorf("URL does not belong to this Cloudinary cloud")
 	}
 
-	// Save to temporary file
-	tempFilePath, err := SaveToTempFile(file)
+	// Delete the file
+	result, err := s.cld.Upload.Destroy(ctx, uploader.DestroyParams{
+		PublicID:     publicID,
+		ResourceType: resourceType,
+	})
 	if err != nil {
-		return "", err
+		return fmt.Errorf("failed to delete file from Cloudinary: %v", err)
+	}
+	if result.Result == "not found" {
+		return ErrMediaNotFound
 	}
 
-	// Open the file
-	fileHandle, err := os.Open(tempFilePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to open file: %v", err)
+	fmt.Printf("Successfully deleted file from Cloudinary: %s\n", publicID)
+	return nil
+}
+
+// SignedURL returns the delivery URL; assets are uploaded with public delivery
+func (s *CloudinaryService) SignedURL(ctx context.Context, fileURL string, expiry time.Duration) (string, error) {
+	if _, publicID := extractPublicID(fileURL); publicID == "" {
+		return "", fmt.Errorf("invalid Cloudinary URL")
 	}
-	defer fileHandle.Close()
-	defer os.Remove(tempFilePath) // Clean up the temporary file
+	return fileURL, nil
+}
 
-	// Upload options
-	useFilename := true
-	uniqueFilename := true
-	uploadParams := uploader.UploadParams{
-		Folder:         folder,
-		ResourceType:   resourceType,
-		UseFilename:    &useFilename,
-		UniqueFilename: &uniqueFilename,
+// Stat looks up the asset through the Cloudinary admin API
+func (s *CloudinaryService) Stat(ctx context.Context, fileURL string) (*MediaInfo, error) {
+	resourceType, publicID := extractPublicID(fileURL)
+	if publicID == "" {
+		return nil, fmt.Errorf("invalid Cloudinary URL")
 	}
 
-	// Upload the file
-	result, err := cloudinaryService.cld.Upload.Upload(ctx, fileHandle, uploadParams)
+	result, err := s.cld.Admin.Asset(ctx, admin.AssetParams{
+		AssetType: api.AssetType(resourceType),
+		PublicID:  publicID,
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to upload file: %v", err)
+		return nil, fmt.Errorf("failed to stat file on Cloudinary: %v", err)
+	}
+	if result.Error.Message != "" {
+		return nil, ErrMediaNotFound
 	}
 
-	fmt.Printf("Successfully uploaded file to Cloudinary: %s\n", result.SecureURL)
-	return result.SecureURL, nil
-}
+	contentType := result.ResourceType
+	if result.Format != "" {
+		contentType = result.ResourceType + "/" + result.Format
+	}
 
-// HandleImageUpload is a convenience function for uploading images
-func HandleImageUpload(ctx context.Context, file *multipart.FileHeader, imageType string) (string, error) {
-	return HandleUpload(ctx, file, imageType, "image")
+	return &MediaInfo{
+		URL:         fileURL,
+		Key:         publicID,
+		Size:        int64(result.Bytes),
+		ContentType: contentType,
+		CreatedAt:   result.CreatedAt,
+	}, nil
 }
 
-// HandleVideoUpload is a convenience function for uploading videos
-func HandleVideoUpload(ctx context.Context, file *multipart.FileHeader, folder string) (string, error) {
-	return HandleUpload(ctx, file, folder, "video")
-}
+// Open downloads the asset from its delivery URL
+func (s *CloudinaryService) Open(ctx context.Context, fileURL string) (io.ReadCloser, error) {
+	if _, publicID := extractPublicID(fileURL); publicID == "" {
+		return nil, fmt.Errorf("invalid Cloudinary URL")
+	}
 
-// DeleteFromCloudinary deletes a file from Cloudinary using its URL
-func DeleteFromCloudinary(ctx context.Context, fileURL string) error {
-	// Initialize Cloudinary service
-	cloudinaryService, err := NewCloudinaryService()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	// Extract public ID from URL
-	// Cloudinary URL format: https://res.cloudinary.com/<cloud_name>/<resource_type>/upload/<public_id>
-	// We need to extract the public_id part
-	publicID := extractPublicID(fileURL)
-	if publicID == "" {
-		return fmt.Errorf("invalid Cloudinary URL")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file from Cloudinary: %v", err)
 	}
 
-	// Delete the file
-	_, err = cloudinaryService.cld.Upload.Destroy(ctx, uploader.DestroyParams{
-		PublicID: publicID,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to delete file from Cloudinary: %v", err)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrMediaNotFound
+	case resp.StatusCode != http.StatusOK:
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download file from Cloudinary: %s", resp.Status)
 	}
+	return resp.Body, nil
+}
 
-	fmt.Printf("Successfully deleted file from Cloudinary: %s\n", publicID)
+// DeletePrefix deletes every asset whose public ID starts with prefix, for each resource type
+func (s *CloudinaryService) DeletePrefix(ctx context.Context, prefix string) error {
+	for _, assetType := range []api.AssetType{api.Image, api.Video, api.File} {
+		result, err := s.cld.Admin.DeleteAssetsByPrefix(ctx, admin.DeleteAssetsByPrefixParams{
+			AssetType: assetType,
+			Prefix:    api.CldAPIArray{prefix},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete files from Cloudinary: %v", err)
+		}
+		if result.Error.Message != "" {
+			return fmt.Errorf("failed to delete files from Cloudinary: %s", result.Error.Message)
+		}
+	}
 	return nil
 }
 
-// extractPublicID extracts the public ID from a Cloudinary URL
-func extractPublicID(url string) string {
-	// Split the URL by '/'
+// extractPublicID extracts the resource type and public ID from a Cloudinary URL
+func extractPublicID(url string) (string, string) {
+	// Cloudinary URL format: https://res.cloudinary.com/<cloud_name>/<resource_type>/upload/v<version>/<public_id>.<ext>
 	parts := strings.Split(url, "/")
 
 	// Find the index of "upload"
@@ -149,13 +191,33 @@ func extractPublicID(url string) string {
 			break
 		}
 	}
+	if uploadIndex < 1 || uploadIndex+1 >= len(parts) {
+		return "", ""
+	}
 
-	// If we found "upload" and there's a part after it
-	if uploadIndex != -1 && uploadIndex+1 < len(parts) {
-		// Get the public ID (remove file extension)
-		publicID := parts[uploadIndex+1]
-		return strings.TrimSuffix(publicID, filepath.Ext(publicID))
+	resourceType := parts[uploadIndex-1]
+	rest := parts[uploadIndex+1:]
+
+	// Skip the version segment
+	if len(rest) > 1 && isVersionSegment(rest[0]) {
+		rest = rest[1:]
+	}
+
+	publicID := strings.Join(rest, "/")
+	if resourceType != "raw" {
+		publicID = strings.TrimSuffix(publicID, path.Ext(publicID))
 	}
+	return resourceType, publicID
+}
 
-	return ""
+func isVersionSegment(segment string) bool {
+	if len(segment) < 2 || segment[0] != 'v' {
+		return false
+	}
+	for _, r := range segment[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
 }
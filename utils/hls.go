@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// HLSMasterPlaylist is the name of the master playlist inside an HLS output directory
+	HLSMasterPlaylist = "master.m3u8"
+	// HLSVariantPlaylist is the name of each rendition's media playlist
+	HLSVariantPlaylist = "index.m3u8"
+
+	hlsSegmentSeconds = 6
+	hlsAudioBitrate   = 128000
+)
+
+// HLSKeyPrefix is the media store prefix every HLS file of a video is stored under
+func HLSKeyPrefix(videoID string) string {
+	return "hls/" + videoID + "/"
+}
+
+// HLSRendition is one rung of the bitrate ladder
+type HLSRendition struct {
+	Name         string
+	Width        int
+	Height       int
+	VideoBitrate int // bits per second
+}
+
+// Bandwidth is the peak bitrate advertised in the master playlist
+func (r HLSRendition) Bandwidth() int {
+	return r.VideoBitrate*3/2 + hlsAudioBitrate
+}
+
+// hlsLadder lists the renditions we encode, from highest to lowest
+var hlsLadder = []HLSRendition{
+	{Name: "1080p", Height: 1080, VideoBitrate: 5000000},
+	{Name: "720p", Height: 720, VideoBitrate: 2800000},
+	{Name: "480p", Height: 480, VideoBitrate: 1400000},
+	{Name: "360p", Height: 360, VideoBitrate: 800000},
+	{Name: "240p", Height: 240, VideoBitrate: 400000},
+}
+
+// HLSLadder returns the renditions for a source of the given size; sources are
+// never upscaled, so a small source gets a single rendition at its own height
+func HLSLadder(sourceWidth int, sourceHeight int) []HLSRendition {
+	var ladder []HLSRendition
+	for _, rendition := range hlsLadder {
+		if rendition.Height <= sourceHeight {
+			ladder = append(ladder, rendition)
+		}
+	}
+	if len(ladder) == 0 {
+		lowest := hlsLadder[len(hlsLadder)-1]
+		ladder = []HLSRendition{{
+			Name:         fmt.Sprintf("%dp", evenDimension(sourceHeight)),
+			Height:       evenDimension(sourceHeight),
+			VideoBitrate: lowest.VideoBitrate,
+		}}
+	}
+
+	// Keep the source aspect ratio; H.264 needs even dimensions
+	for i := range ladder {
+		ladder[i].Width = ladder[i].Height
+		if sourceHeight > 0 {
+			ladder[i].Width = evenDimension(sourceWidth * ladder[i].Height / sourceHeight)
+		}
+	}
+	return ladder
+}
+
+func evenDimension(value int) int {
+	if value < 2 {
+		return 2
+	}
+	return value - value%2
+}
+
+// TranscodeToHLS encodes the input into every rendition of the ladder in a single
+// ffmpeg pass. outputDir receives master.m3u8 plus one directory per rendition
+// holding its index.m3u8 and .ts segments. Keyframes are forced on segment
+// boundaries so players can switch renditions cleanly.
+func TranscodeToHLS(ctx context.Context, inputPath string, outputDir string, ladder []HLSRendition, hasAudio bool, durationSeconds float64, onProgress func(float64)) error {
+	if len(ladder) == 0 {
+		return fmt.Errorf("no HLS renditions to encode")
+	}
+	for _, rendition := range ladder {
+		if err := os.MkdirAll(filepath.Join(outputDir, rendition.Name), 0o755); err != nil {
+			return fmt.Errorf("failed to create HLS directory: %v", err)
+		}
+	}
+
+	// Split the video once and scale a copy for each rendition
+	filters := []string{fmt.Sprintf("[0:v]split=%d%s", len(ladder), streamLabels("v", len(ladder)))}
+	for i, rendition := range ladder {
+		filters = append(filters, fmt.Sprintf("[v%d]scale=%d:%d[v%dout]", i, rendition.Width, rendition.Height, i))
+	}
+
+	args := []string{
+		"-y",
+		"-v", "error",
+		"-i", inputPath,
+		"-filter_complex", strings.Join(filters, ";"),
+	}
+
+	var streamMap []string
+	for i, rendition := range ladder {
+		index := strconv.Itoa(i)
+		args = append(args,
+			"-map", "[v"+index+"out]",
+			"-c:v:"+index, "libx264",
+			"-b:v:"+index, strconv.Itoa(rendition.VideoBitrate),
+			"-maxrate:v:"+index, strconv.Itoa(rendition.VideoBitrate*3/2),
+			"-bufsize:v:"+index, strconv.Itoa(rendition.VideoBitrate*2),
+		)
+		entry := "v:" + index
+		if hasAudio {
+			args = append(args, "-map", "a:0")
+			entry += ",a:" + index
+		}
+		streamMap = append(streamMap, entry+",name:"+rendition.Name)
+	}
+	if hasAudio {
+		args = append(args, "-c:a", "aac", "-b:a", strconv.Itoa(hlsAudioBitrate), "-ac", "2")
+	}
+
+	args = append(args,
+		"-preset", "veryfast",
+		"-pix_fmt", "yuv420p",
+		"-sc_threshold", "0",
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", hlsSegmentSeconds),
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(hlsSegmentSeconds),
+		"-hls_playlist_type", "vod",
+		"-hls_segment_type", "mpegts",
+		"-hls_segment_filename", filepath.Join(outputDir, "%v", "segment_%04d.ts"),
+		"-master_pl_name", HLSMasterPlaylist,
+		"-var_stream_map", strings.Join(streamMap, " "),
+		"-progress", "pipe:1",
+		"-nostats",
+		filepath.Join(outputDir, "%v", HLSVariantPlaylist),
+	)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	return runFFmpeg(cmd, durationSeconds, onProgress)
+}
+
+// streamLabels returns "[v0][v1]..." for n outputs
+func streamLabels(prefix string, n int) string {
+	var labels strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&labels, "[%s%d]", prefix, i)
+	}
+	return labels.String()
+}
+
+// HLSContentType returns the MIME type for a file produced by TranscodeToHLS
+func HLSContentType(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".ts":
+		return "video/mp2t"
+	case ".m4s", ".mp4":
+		return "video/mp4"
+	default:
+		return "application/octet-stream"
+	}
+}
@@ -1,76 +1,72 @@
 package utils
 
 import (
-	"context"
+	"errors"
 	"os"
 	"time"
-	"yt_backend/db"
-	"yt_backend/models"
 
 	"github.com/golang-jwt/jwt/v5"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
+	"github.com/google/uuid"
 )
 
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID string) (string, error) {
-	for {
-		// Create the claims
-		claims := Claims{
-			userID,
-			jwt.RegisteredClaims{
-				ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // Token expires in 24 hours
-				IssuedAt:  jwt.NewNumericDate(time.Now()),
-				NotBefore: jwt.NewNumericDate(time.Now()),
-			},
-		}
+// AccessTokenTTL is how long an access token is valid, from ACCESS_TOKEN_TTL (default 15 minutes)
+func AccessTokenTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("ACCESS_TOKEN_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return 15 * time.Minute
+}
 
-		// Create the token
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+// tokenIssuer is the iss claim, from JWT_ISSUER; other services can require it
+func tokenIssuer() string {
+	return os.Getenv("JWT_ISSUER")
+}
 
-		// Sign the token with the secret key
-		secretKey := os.Getenv("JWT_SECRET")
-		if secretKey == "" {
-			secretKey = "your-secret-key" // Default key, should be changed in production
-		}
+// GenerateToken issues a short-lived access token for the user's session
+func GenerateToken(userID string, sessionID string) (string, error) {
+	if signingKeys == nil {
+		return "", errors.New("signing keys are not loaded")
+	}
 
-		signedToken, err := token.SignedString([]byte(secretKey))
-		if err != nil {
-			return "", err
-		}
+	// Create the claims
+	claims := Claims{
+		userID,
+		sessionID,
+		jwt.RegisteredClaims{
+			ID:        uuid.New().String(), // jti, the key used to revoke this token
+			Issuer:    tokenIssuer(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL())),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
 
-		// Check if token is blacklisted
-		blacklistCollection := db.GetCollection("token_blacklist")
-		var blacklistEntry models.TokenBlacklist
-		filter := bson.M{"token": signedToken}
-		err = blacklistCollection.FindOne(context.TODO(), filter).Decode(&blacklistEntry)
-		if err == mongo.ErrNoDocuments {
-			// Token is not blacklisted, we can use it
-			return signedToken, nil
-		} else if err != nil {
-			// Handle other errors
-			return "", err
-		}
+	// Create the token, naming the key so verifiers can find it in the JWKS
+	key := signingKeys.Signing
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
 
-		// Token is blacklisted, generate a new one
-		continue
-	}
+	return token.SignedString(key.Private)
 }
 
 func VerifyToken(tokenString string) (*Claims, error) {
-	// Parse the token
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		secretKey := os.Getenv("JWT_SECRET")
-		if secretKey == "" {
-			secretKey = "your-secret-key" // Default key, should be changed in production
-		}
-		return []byte(secretKey), nil
-	})
+	// Only the asymmetric algorithms we sign with are accepted
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{
+		jwt.SigningMethodRS256.Alg(),
+		jwt.SigningMethodEdDSA.Alg(),
+	})}
+	if issuer := tokenIssuer(); issuer != "" {
+		options = append(options, jwt.WithIssuer(issuer))
+	}
 
+	// Parse the token
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, verificationKey, options...)
 	if err != nil {
 		return nil, err
 	}
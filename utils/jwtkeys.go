@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a key tokens are signed or verified with. Private is nil for
+// keys that are only kept to verify tokens signed before a rotation.
+type SigningKey struct {
+	ID      string // kid, the RFC 7638 thumbprint of the public key
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// KeySet holds the key new tokens are signed with and every key tokens are accepted from
+type KeySet struct {
+	Signing *SigningKey
+	byID    map[string]*SigningKey
+	ordered []*SigningKey
+}
+
+var signingKeys *KeySet
+
+// LoadSigningKeys reads the signing key from JWT_SIGNING_KEY and any keys that
+// are still accepted after a rotation from JWT_VERIFY_KEYS (comma separated).
+// Both name PEM files holding RSA (2048 bits or more) or Ed25519 keys; verify
+// keys may be public keys. It fails when no signing key is configured.
+func LoadSigningKeys() error {
+	path := os.Getenv("JWT_SIGNING_KEY")
+	if path == "" {
+		return errors.New("JWT_SIGNING_KEY is not set; create a key with \"go run . keygen\"")
+	}
+
+	signing, err := readKeyFile(path)
+	if err != nil {
+		return err
+	}
+	if signing.Private == nil {
+		return fmt.Errorf("%s holds a public key; the signing key must be private", path)
+	}
+
+	set := &KeySet{Signing: signing, byID: make(map[string]*SigningKey)}
+	set.add(signing)
+	for _, path := range strings.Split(os.Getenv("JWT_VERIFY_KEYS"), ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		key, err := readKeyFile(path)
+		if err != nil {
+			return err
+		}
+		set.add(key)
+	}
+
+	signingKeys = set
+	return nil
+}
+
+func (s *KeySet) add(key *SigningKey) {
+	if _, ok := s.byID[key.ID]; ok {
+		return
+	}
+	s.byID[key.ID] = key
+	s.ordered = append(s.ordered, key)
+}
+
+// verificationKey picks the key a token names in its kid header
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	if signingKeys == nil {
+		return nil, errors.New("signing keys are not loaded")
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, ok := signingKeys.byID[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("key %q does not sign with %s", kid, token.Method.Alg())
+	}
+	return key.Public, nil
+}
+
+func readKeyFile(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %v", err)
+	}
+	key, err := ParseSigningKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return key, nil
+}
+
+// ParseSigningKey reads a PEM encoded RSA or Ed25519 private or public key
+func ParseSigningKey(data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Private, key.Public = k, &k.PublicKey
+	case ed25519.PrivateKey:
+		key.Private, key.Public = k, k.Public()
+	case *rsa.PublicKey, ed25519.PublicKey:
+		key.Public = k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T; use RSA or Ed25519", parsed)
+	}
+
+	switch public := key.Public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	}
+
+	jwk := publicJWK(key.Public)
+	key.ID = jwkThumbprint(jwk)
+	return key, nil
+}
+
+// GenerateSigningKey creates a new private key ("rsa" or "ed25519") and returns it PEM encoded
+func GenerateSigningKey(kind string) ([]byte, error) {
+	var private interface{}
+	var err error
+	switch kind {
+	case "rsa":
+		private, err = rsa.GenerateKey(rand.Reader, 3072)
+	case "ed25519":
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unknown key type %q; use rsa or ed25519", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// JWKS returns the public half of every accepted key as a JSON Web Key Set
+func JWKS() map[string]interface{} {
+	keys := []map[string]string{}
+	if signingKeys != nil {
+		for _, key := range signingKeys.ordered {
+			jwk := publicJWK(key.Public)
+			jwk["kid"] = key.ID
+			jwk["alg"] = key.Method.Alg()
+			jwk["use"] = "sig"
+			keys = append(keys, jwk)
+		}
+	}
+	return map[string]interface{}{"keys": keys}
+}
+
+// publicJWK holds the required JWK members of a public key
+func publicJWK(public crypto.PublicKey) map[string]string {
+	encode := base64.RawURLEncoding.EncodeToString
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA",
+			"n":   encode(k.N.Bytes()),
+			"e":   encode(big.NewInt(int64(k.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   encode(k),
+		}
+	}
+	return nil
+}
+
+// jwkThumbprint computes the RFC 7638 thumbprint; encoding/json sorts map keys
+// and the required members contain nothing that needs escaping
+func jwkThumbprint(jwk map[string]string) string {
+	data, _ := json.Marshal(jwk)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
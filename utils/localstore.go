@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalStore keeps media on the local filesystem. Files are served by the
+// /media route, so it is meant for development and tests.
+type LocalStore struct {
+	Dir     string
+	BaseURL string
+}
+
+// NewLocalStore creates a local filesystem media store rooted at dir
+func NewLocalStore(dir string, baseURL string) (*LocalStore, error) {
+	if dir == "" {
+		dir = "uploads"
+	}
+	if baseURL == "" {
+		baseURL = "http://localhost:8080/media"
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create media directory: %v", err)
+	}
+
+	return &LocalStore{Dir: dir, BaseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// Put writes the content to disk under the given key
+func (s *LocalStore) Put(ctx context.Context, key string, content io.Reader, opts PutOptions) (string, error) {
+	key = cleanKey(key)
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create media directory: %v", err)
+	}
+
+	// Write to a temporary file first so readers never see partial content
+	tempFile, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := io.Copy(tempFile, content); err != nil {
+		tempFile.Close()
+		return "", fmt.Errorf("failed to write file: %v", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return "", fmt.Errorf("failed to write file: %v", err)
+	}
+
+	if err := os.Rename(tempFile.Name(), filePath); err != nil {
+		return "", fmt.Errorf("failed to store file: %v", err)
+	}
+
+	return s.BaseURL + "/" + key, nil
+}
+
+// Delete removes the file behind the URL
+func (s *LocalStore) Delete(ctx context.Context, fileURL string) error {
+	filePath, err := s.filePathFromURL(fileURL)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrMediaNotFound
+		}
+		return fmt.Errorf("failed to delete file: %v", err)
+	}
+	return nil
+}
+
+// SignedURL returns the public URL; local files are served without signatures
+func (s *LocalStore) SignedURL(ctx context.Context, fileURL string, expiry time.Duration) (string, error) {
+	if _, err := s.filePathFromURL(fileURL); err != nil {
+		return "", err
+	}
+	return fileURL, nil
+}
+
+// Stat returns size and type information for the file behind the URL
+func (s *LocalStore) Stat(ctx context.Context, fileURL string) (*MediaInfo, error) {
+	filePath, err := s.filePathFromURL(fileURL)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrMediaNotFound
+		}
+		return nil, err
+	}
+
+	return &MediaInfo{
+		URL:         fileURL,
+		Key:         strings.TrimPrefix(fileURL, s.BaseURL+"/"),
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(filepath.Ext(filePath)),
+		CreatedAt:   info.ModTime(),
+	}, nil
+}
+
+// Open opens the file behind the URL for reading
+func (s *LocalStore) Open(ctx context.Context, fileURL string) (io.ReadCloser, error) {
+	filePath, err := s.filePathFromURL(fileURL)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrMediaNotFound
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// DeletePrefix removes every file whose key starts with prefix
+func (s *LocalStore) DeletePrefix(ctx context.Context, prefix string) error {
+	dirPath, err := s.filePath(prefix)
+	if err != nil {
+		return err
+	}
+
+	// Prefixes ending in "/" name a directory; anything else matches file names in the parent
+	if strings.HasSuffix(prefix, "/") {
+		return os.RemoveAll(dirPath)
+	}
+	matches, err := filepath.Glob(dirPath + "*")
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		if err := os.RemoveAll(match); err != nil {
+			return fmt.Errorf("failed to delete file: %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *LocalStore) filePathFromURL(fileURL string) (string, error) {
+	if !strings.HasPrefix(fileURL, s.BaseURL+"/") {
+		return "", fmt.Errorf("URL does not belong to the local media store")
+	}
+	return s.filePath(strings.TrimPrefix(fileURL, s.BaseURL+"/"))
+}
+
+// filePath maps a key to a path inside Dir, rejecting keys that escape it
+func (s *LocalStore) filePath(key string) (string, error) {
+	cleaned := cleanKey(key)
+	if cleaned == "" {
+		return "", fmt.Errorf("invalid media key %q", key)
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(cleaned)), nil
+}
+
+// cleanKey resolves "." and ".." in a key without letting it climb above the
+// root and drops the leading "/", so the URL and the file on disk agree
+func cleanKey(key string) string {
+	return strings.TrimPrefix(path.Clean("/"+key), "/")
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// LoginPolicy decides when failed logins lock out an account or IP address
+type LoginPolicy struct {
+	MaxFailures int           // failures allowed before the first lockout
+	Lockout     time.Duration // length of the first lockout; each further failure doubles it
+	MaxLockout  time.Duration
+}
+
+// AccountLoginPolicy applies to failed logins against one account: after
+// LOGIN_MAX_FAILURES (default 5) failures it is locked for LOGIN_LOCKOUT
+// (default 1 minute), doubling up to LOGIN_MAX_LOCKOUT (default 1 hour)
+func AccountLoginPolicy() LoginPolicy {
+	return LoginPolicy{
+		MaxFailures: envInt("LOGIN_MAX_FAILURES", 5),
+		Lockout:     envDuration("LOGIN_LOCKOUT", time.Minute),
+		MaxLockout:  envDuration("LOGIN_MAX_LOCKOUT", time.Hour),
+	}
+}
+
+// IPLoginPolicy applies to failed logins from one IP address, against any
+// account; it allows LOGIN_MAX_FAILURES_PER_IP (default 50) failures so
+// users behind a shared address aren't locked out by each other's typos
+func IPLoginPolicy() LoginPolicy {
+	policy := AccountLoginPolicy()
+	policy.MaxFailures = envInt("LOGIN_MAX_FAILURES_PER_IP", 50)
+	return policy
+}
+
+// LoginFailureMemory is how long failed logins are remembered after the last
+// one, from LOGIN_FAILURE_MEMORY (default 24 hours)
+func LoginFailureMemory() time.Duration {
+	return envDuration("LOGIN_FAILURE_MEMORY", 24*time.Hour)
+}
+
+// LockoutAfter is how long to lock logins after the given number of failures,
+// or zero while they are still allowed
+func (p LoginPolicy) LockoutAfter(failures int) time.Duration {
+	if failures < p.MaxFailures {
+		return 0
+	}
+	lockout := p.Lockout
+	for i := p.MaxFailures; i < failures && lockout < p.MaxLockout; i++ {
+		lockout *= 2
+	}
+	return min(lockout, p.MaxLockout)
+}
+
+func envInt(name string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(name)); err == nil && value > 0 {
+		return value
+	}
+	return fallback
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(name)); err == nil && value > 0 {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutAfterDoublesUpToTheMaximum(t *testing.T) {
+	policy := LoginPolicy{MaxFailures: 3, Lockout: time.Minute, MaxLockout: 10 * time.Minute}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{5, 4 * time.Minute},
+		{6, 8 * time.Minute},
+		{7, 10 * time.Minute},
+		{1000, 10 * time.Minute},
+	}
+	for _, test := range tests {
+		if got := policy.LockoutAfter(test.failures); got != test.want {
+			t.Errorf("LockoutAfter(%d) = %v, want %v", test.failures, got, test.want)
+		}
+	}
+}
+
+func TestLoginPoliciesFromEnv(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "4")
+	t.Setenv("LOGIN_LOCKOUT", "30s")
+	t.Setenv("LOGIN_MAX_LOCKOUT", "not a duration")
+	t.Setenv("LOGIN_MAX_FAILURES_PER_IP", "-1")
+
+	account := AccountLoginPolicy()
+	if account.MaxFailures != 4 || account.Lockout != 30*time.Second || account.MaxLockout != time.Hour {
+		t.Errorf("AccountLoginPolicy = %+v", account)
+	}
+	// Invalid values fall back to the defaults
+	if ip := IPLoginPolicy(); ip.MaxFailures != 50 || ip.Lockout != 30*time.Second {
+		t.Errorf("IPLoginPolicy = %+v", ip)
+	}
+}
@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrMediaNotFound is returned when a stored object does not exist
+var ErrMediaNotFound = errors.New("media not found")
+
+// MediaStore is the storage backend used for every uploaded file.
+// Objects are identified by the public URL returned from Put, which is
+// what the controllers persist on the documents.
+type MediaStore interface {
+	// Put stores the content under key and returns its public URL
+	Put(ctx context.Context, key string, content io.Reader, opts PutOptions) (string, error)
+	// Delete removes the object behind a URL previously returned by Put
+	Delete(ctx context.Context, fileURL string) error
+	// SignedURL returns a time limited URL that can be used to read the object
+	SignedURL(ctx context.Context, fileURL string, expiry time.Duration) (string, error)
+	// Stat returns metadata about a stored object
+	Stat(ctx context.Context, fileURL string) (*MediaInfo, error)
+	// Open returns a reader for the object behind the URL
+	Open(ctx context.Context, fileURL string) (io.ReadCloser, error)
+	// DeletePrefix removes every object whose key starts with prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// PutOptions describes the object being stored
+type PutOptions struct {
+	ResourceType string // "image", "video" or "raw"
+	ContentType  string
+	Size         int64 // -1 when unknown
+}
+
+// MediaInfo holds the metadata returned by Stat
+type MediaInfo struct {
+	URL         string    `json:"url"`
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"contentType"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+var (
+	mediaStore     MediaStore
+	mediaStoreErr  error
+	mediaStoreOnce sync.Once
+)
+
+// GetMediaStore returns the media store selected by the MEDIA_STORE
+// environment variable ("cloudinary", "local" or "s3"). Cloudinary is used
+// when the variable is not set.
+func GetMediaStore() (MediaStore, error) {
+	mediaStoreOnce.Do(func() {
+		mediaStore, mediaStoreErr = newMediaStore(MediaStoreKind())
+	})
+	return mediaStore, mediaStoreErr
+}
+
+// SetMediaStore overrides the configured media store
+func SetMediaStore(store MediaStore) {
+	mediaStoreOnce.Do(func() {})
+	mediaStore, mediaStoreErr = store, nil
+}
+
+// MediaStoreKind returns the configured media store name
+func MediaStoreKind() string {
+	kind := strings.ToLower(os.Getenv("MEDIA_STORE"))
+	if kind == "" {
+		kind = "cloudinary"
+	}
+	return kind
+}
+
+func newMediaStore(kind string) (MediaStore, error) {
+	switch kind {
+	case "cloudinary":
+		return NewCloudinaryService()
+	case "local":
+		return NewLocalStore(os.Getenv("MEDIA_LOCAL_DIR"), os.Getenv("MEDIA_PUBLIC_URL"))
+	case "s3":
+		return NewS3StoreFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown MEDIA_STORE %q", kind)
+	}
+}
+
+// NewMediaKey builds a unique object key inside folder, keeping the
+// extension of the original file name
+func NewMediaKey(folder string, filename string) string {
+	name := uuid.New().String() + strings.ToLower(filepath.Ext(filename))
+	return path.Join(folder, name)
+}
+
+// HandleUpload stores a multipart file in the configured media store and returns its URL
+func HandleUpload(ctx context.Context, file *multipart.FileHeader, folder string, resourceType string) (string, error) {
+	store, err := GetMediaStore()
+	if err != nil {
+		return "", err
+	}
+
+	// Open the uploaded file
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %v", err)
+	}
+	defer src.Close()
+
+	fileURL, err := store.Put(ctx, NewMediaKey(folder, file.Filename), src, PutOptions{
+		ResourceType: resourceType,
+		ContentType:  file.Header.Get("Content-Type"),
+		Size:         file.Size,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Successfully uploaded file: %s\n", fileURL)
+	return fileURL, nil
+}
+
+// HandleImageUpload is a convenience function for uploading images
+func HandleImageUpload(ctx context.Context, file *multipart.FileHeader, imageType string) (string, error) {
+	return HandleUpload(ctx, file, imageType, "image")
+}
+
+// HandleVideoUpload is a convenience function for uploading videos
+func HandleVideoUpload(ctx context.Context, file *multipart.FileHeader, folder string) (string, error) {
+	return HandleUpload(ctx, file, folder, "video")
+}
+
+// DeleteMedia deletes a file from the configured media store using its URL
+func DeleteMedia(ctx context.Context, fileURL string) error {
+	store, err := GetMediaStore()
+	if err != nil {
+		return err
+	}
+	return store.Delete(ctx, fileURL)
+}
+
+// DeleteMediaPrefix deletes every file stored under a key prefix from the configured media store
+func DeleteMediaPrefix(ctx context.Context, prefix string) error {
+	store, err := GetMediaStore()
+	if err != nil {
+		return err
+	}
+	return store.DeletePrefix(ctx, prefix)
+}
+
+// SourceKey is where an uploaded video waits in the media store until it has
+// been processed, so whichever instance claims the job can read it
+func SourceKey(videoID string, ext string) string {
+	return "sources/" + videoID + ext
+}
+
+// PutFile stores a file from disk in the configured media store and returns its URL
+func PutFile(ctx context.Context, key string, filePath string, opts PutOptions) (string, error) {
+	store, err := GetMediaStore()
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err == nil {
+		opts.Size = info.Size()
+	}
+	return store.Put(ctx, key, file, opts)
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// EmailVerificationTTL is how long an email verification link works, from
+// EMAIL_VERIFICATION_TTL (default 48 hours)
+func EmailVerificationTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("EMAIL_VERIFICATION_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return 48 * time.Hour
+}
+
+// PasswordResetTTL is how long a password reset link works, from
+// PASSWORD_RESET_TTL (default 1 hour)
+func PasswordResetTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("PASSWORD_RESET_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return time.Hour
+}
+
+// NewOneTimeToken returns a random token for an emailed link along with the
+// hash to store. The token itself is never stored.
+func NewOneTimeToken() (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+	return token, HashOneTimeToken(token), nil
+}
+
+// HashOneTimeToken hashes a token for storage and lookup
+func HashOneTimeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// AppURL builds a link to a page of the web app, whose address is read from
+// APP_URL (default http://localhost:3000)
+func AppURL(path string, query url.Values) string {
+	base := os.Getenv("APP_URL")
+	if base == "" {
+		base = "http://localhost:3000"
+	}
+	link := strings.TrimRight(base, "/") + path
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	return link
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	previewTileWidth  = 160
+	previewTileHeight = 90
+	previewColumns    = 10
+	previewMaxTiles   = 100
+	previewMinSeconds = 2
+)
+
+// PreviewKeyPrefix is the media store prefix for a video's sprite sheet and thumbnail track
+func PreviewKeyPrefix(videoID string) string {
+	return "previews/" + videoID + "/"
+}
+
+// SpriteSheet describes the grid of frames written by GenerateSpriteSheet
+type SpriteSheet struct {
+	IntervalSeconds float64
+	Count           int
+	Columns         int
+	Rows            int
+	TileWidth       int
+	TileHeight      int
+	DurationSeconds float64
+}
+
+// NewSpriteSheet lays out at most 100 tiles evenly over the video, one every
+// two seconds for short videos
+func NewSpriteSheet(durationSeconds float64) SpriteSheet {
+	interval := math.Max(previewMinSeconds, math.Ceil(durationSeconds/previewMaxTiles))
+	count := int(math.Ceil(durationSeconds / interval))
+	if count < 1 {
+		count = 1
+	}
+	columns := min(count, previewColumns)
+
+	return SpriteSheet{
+		IntervalSeconds: interval,
+		Count:           count,
+		Columns:         columns,
+		Rows:            (count + columns - 1) / columns,
+		TileWidth:       previewTileWidth,
+		TileHeight:      previewTileHeight,
+		DurationSeconds: durationSeconds,
+	}
+}
+
+// GenerateSpriteSheet writes a single JPEG holding one letterboxed frame per interval
+func GenerateSpriteSheet(ctx context.Context, inputPath string, outputPath string, sheet SpriteSheet) error {
+	filter := fmt.Sprintf(
+		"fps=1/%s,scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,tile=%dx%d",
+		strconv.FormatFloat(sheet.IntervalSeconds, 'f', -1, 64),
+		sheet.TileWidth, sheet.TileHeight,
+		sheet.TileWidth, sheet.TileHeight,
+		sheet.Columns, sheet.Rows,
+	)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-y",
+		"-v", "error",
+		"-i", inputPath,
+		"-vf", filter,
+		"-frames:v", "1",
+		"-q:v", "5",
+		outputPath,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to generate sprite sheet: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// WriteThumbnailVTT writes a WebVTT thumbnail track pointing each interval at its
+// tile in the sprite image using media fragments (#xywh=x,y,w,h)
+func WriteThumbnailVTT(w io.Writer, sheet SpriteSheet, spriteURL string) error {
+	if _, err := io.WriteString(w, "WEBVTT\n"); err != nil {
+		return err
+	}
+
+	for i := 0; i < sheet.Count; i++ {
+		start := float64(i) * sheet.IntervalSeconds
+		end := math.Min(start+sheet.IntervalSeconds, sheet.DurationSeconds)
+		if end <= start {
+			end = start + sheet.IntervalSeconds
+		}
+		x := (i % sheet.Columns) * sheet.TileWidth
+		y := (i / sheet.Columns) * sheet.TileHeight
+
+		_, err := fmt.Fprintf(w, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			formatVTTTimestamp(start), formatVTTTimestamp(end), spriteURL, x, y, sheet.TileWidth, sheet.TileHeight)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatVTTTimestamp formats seconds as HH:MM:SS.mmm
+func formatVTTTimestamp(seconds float64) string {
+	millis := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", millis/3600000, millis/60000%60, millis/1000%60, millis%1000)
+}
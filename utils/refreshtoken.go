@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"strings"
+	"time"
+)
+
+// RefreshTokenTTL is how long a session can go unused before it expires, from
+// REFRESH_TOKEN_TTL (default 30 days). Every refresh extends it.
+func RefreshTokenTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return 30 * 24 * time.Hour
+}
+
+// NewRefreshToken returns a refresh token for the session, "<sessionId>.<secret>",
+// along with the hash of the secret to store. The secret itself is never stored.
+func NewRefreshToken(sessionID string) (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+	return sessionID + "." + encoded, HashRefreshSecret(encoded), nil
+}
+
+// ParseRefreshToken splits a refresh token into its session ID and secret
+func ParseRefreshToken(token string) (string, string, bool) {
+	sessionID, secret, found := strings.Cut(token, ".")
+	if !found || sessionID == "" || secret == "" {
+		return "", "", false
+	}
+	return sessionID, secret, true
+}
+
+// HashRefreshSecret hashes a refresh token secret for storage and comparison
+func HashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
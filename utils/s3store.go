@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Store keeps media in an S3 compatible bucket (AWS S3, MinIO, R2, ...)
+type S3Store struct {
+	client  *minio.Client
+	bucket  string
+	baseURL string
+}
+
+// NewS3StoreFromEnv creates an S3 store from the S3_* environment variables
+func NewS3StoreFromEnv() (*S3Store, error) {
+	endpoint := os.Getenv("S3_ENDPOINT")
+	bucket := os.Getenv("S3_BUCKET")
+	if endpoint == "" || bucket == "" {
+		return nil, fmt.Errorf("S3_ENDPOINT and S3_BUCKET must be set")
+	}
+
+	useSSL := os.Getenv("S3_USE_SSL") != "false"
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(os.Getenv("S3_ACCESS_KEY"), os.Getenv("S3_SECRET_KEY"), ""),
+		Secure: useSSL,
+		Region: os.Getenv("S3_REGION"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %v", err)
+	}
+
+	// Objects are addressed path-style unless a public base URL (e.g. a CDN) is configured
+	baseURL := os.Getenv("S3_PUBLIC_URL")
+	if baseURL == "" {
+		scheme := "https"
+		if !useSSL {
+			scheme = "http"
+		}
+		baseURL = fmt.Sprintf("%s://%s/%s", scheme, endpoint, bucket)
+	}
+
+	return &S3Store{client: client, bucket: bucket, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// Put uploads the content to the bucket under the given key
+func (s *S3Store) Put(ctx context.Context, key string, content io.Reader, opts PutOptions) (string, error) {
+	size := opts.Size
+	if size == 0 {
+		size = -1
+	}
+
+	_, err := s.client.PutObject(ctx, s.bucket, key, content, size, minio.PutObjectOptions{
+		ContentType: opts.ContentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %v", err)
+	}
+
+	return s.baseURL + "/" + key, nil
+}
+
+// Delete removes the object behind the URL
+func (s *S3Store) Delete(ctx context.Context, fileURL string) error {
+	key, err := s.keyFromURL(fileURL)
+	if err != nil {
+		return err
+	}
+
+	if err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete file from S3: %v", err)
+	}
+	return nil
+}
+
+// SignedURL returns a presigned GET URL for the object
+func (s *S3Store) SignedURL(ctx context.Context, fileURL string, expiry time.Duration) (string, error) {
+	key, err := s.keyFromURL(fileURL)
+	if err != nil {
+		return "", err
+	}
+
+	signed, err := s.client.PresignedGetObject(ctx, s.bucket, key, expiry, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign URL: %v", err)
+	}
+	return signed.String(), nil
+}
+
+// Stat returns the object metadata
+func (s *S3Store) Stat(ctx context.Context, fileURL string) (*MediaInfo, error) {
+	key, err := s.keyFromURL(fileURL)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrMediaNotFound
+		}
+		return nil, fmt.Errorf("failed to stat file on S3: %v", err)
+	}
+
+	return &MediaInfo{
+		URL:         fileURL,
+		Key:         key,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		CreatedAt:   info.LastModified,
+	}, nil
+}
+
+// Open streams the object from the bucket
+func (s *S3Store) Open(ctx context.Context, fileURL string) (io.ReadCloser, error) {
+	key, err := s.keyFromURL(fileURL)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file on S3: %v", err)
+	}
+
+	// GetObject is lazy, so stat it to surface a missing key before returning
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrMediaNotFound
+		}
+		return nil, fmt.Errorf("failed to open file on S3: %v", err)
+	}
+	return object, nil
+}
+
+// DeletePrefix removes every object whose key starts with prefix
+func (s *S3Store) DeletePrefix(ctx context.Context, prefix string) error {
+	objects := s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true})
+	for result := range s.client.RemoveObjects(ctx, s.bucket, objects, minio.RemoveObjectsOptions{}) {
+		if result.Err != nil {
+			return fmt.Errorf("failed to delete %s from S3: %v", result.ObjectName, result.Err)
+		}
+	}
+	return nil
+}
+
+func (s *S3Store) keyFromURL(fileURL string) (string, error) {
+	if !strings.HasPrefix(fileURL, s.baseURL+"/") {
+		return "", fmt.Errorf("URL does not belong to the S3 media store")
+	}
+	return strings.TrimPrefix(fileURL, s.baseURL+"/"), nil
+}
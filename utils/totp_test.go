@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors, "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTPMatchesRFC6238(t *testing.T) {
+	// The RFC lists 8 digit codes; ours are their last 6 digits
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, vector := range vectors {
+		step, ok := ValidateTOTP(rfcSecret, vector.code, time.Unix(vector.unix, 0))
+		if !ok || step != vector.unix/totpPeriod {
+			t.Errorf("ValidateTOTP(%s at %d) = %d, %v; want step %d", vector.code, vector.unix, step, ok, vector.unix/totpPeriod)
+		}
+	}
+
+	// Secrets are accepted however the user typed them
+	if _, ok := ValidateTOTP(strings.ToLower(rfcSecret), "287082", time.Unix(59, 0)); !ok {
+		t.Error("lowercase secret rejected")
+	}
+}
+
+func TestValidateTOTPReturnsTheCodesStep(t *testing.T) {
+	issued := time.Unix(59, 0)
+	step := issued.Unix() / totpPeriod
+
+	// One period of drift either way is accepted, and the code keeps its own
+	// step so it can't be used again in the next period
+	for _, now := range []time.Time{issued.Add(-totpPeriod * time.Second), issued.Add(totpPeriod * time.Second)} {
+		got, ok := ValidateTOTP(rfcSecret, "287082", now)
+		if !ok || got != step {
+			t.Errorf("at %d: ValidateTOTP = %d, %v; want step %d", now.Unix(), got, ok, step)
+		}
+	}
+	if _, ok := ValidateTOTP(rfcSecret, "287082", issued.Add(2*totpPeriod*time.Second)); ok {
+		t.Error("code accepted two periods late")
+	}
+}
+
+func TestValidateTOTPRejectsMalformedInput(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "abcdef"} {
+		if _, ok := ValidateTOTP(rfcSecret, code, now); ok {
+			t.Errorf("ValidateTOTP(%q) accepted", code)
+		}
+	}
+	if _, ok := ValidateTOTP("not base32!", "287082", now); ok {
+		t.Error("invalid secret accepted")
+	}
+}
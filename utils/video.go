@@ -1,49 +1,185 @@
 package utils
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"mime/multipart"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
-// GetVideoDuration gets the duration of a video file in HH:MM:SS format
-func GetVideoDuration(file *multipart.FileHeader) (string, error) {
-	// Save to temporary file
-	tempFilePath, err := SaveToTempFile(file)
+// SaveToTempFile saves a multipart file to a temporary file and returns the file path
+func SaveToTempFile(file *multipart.FileHeader) (string, error) {
+	// Create a temporary file
+	tempFile, err := os.CreateTemp("", "upload-*"+filepath.Ext(file.Filename))
 	if err != nil {
-		return "", fmt.Errorf("failed to save file: %v", err)
+		return "", err
 	}
-	defer os.Remove(tempFilePath)
+	defer tempFile.Close()
 
-	// Use ffprobe to get video duration
-	cmd := exec.Command("ffprobe",
+	// Open the uploaded file
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	// Copy the file content to the temporary file
+	if _, err := io.Copy(tempFile, src); err != nil {
+		return "", err
+	}
+
+	return tempFile.Name(), nil
+}
+
+// VideoProbe holds the metadata ffprobe reports for a video file
+type VideoProbe struct {
+	DurationSeconds float64
+	Width           int
+	Height          int
+	HasAudio        bool
+}
+
+// Duration returns the probed duration in HH:MM:SS format
+func (p *VideoProbe) Duration() string {
+	return FormatDuration(p.DurationSeconds)
+}
+
+// FormatDuration converts seconds to HH:MM:SS format
+func FormatDuration(duration float64) string {
+	hours := int(duration) / 3600
+	minutes := (int(duration) % 3600) / 60
+	seconds := int(duration) % 60
+
+	return fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
+}
+
+// ProbeVideo reads the duration and dimensions of a video file on disk
+func ProbeVideo(ctx context.Context, filePath string) (*VideoProbe, error) {
+	// Use ffprobe to get the duration and the type and dimensions of every stream
+	cmd := exec.CommandContext(ctx, "ffprobe",
 		"-v", "error",
-		"-show_entries", "format=duration",
-		"-of", "default=noprint_wrappers=1:nokey=1",
-		tempFilePath,
+		"-show_entries", "stream=codec_type,width,height:format=duration",
+		"-of", "json",
+		filePath,
 	)
 
 	output, err := cmd.Output()
 	if err != nil {
-		return "", fmt.Errorf("failed to get video duration: %v", err)
+		return nil, fmt.Errorf("failed to probe video: %v", err)
+	}
+
+	var result struct {
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %v", err)
 	}
 
 	// Convert string output to float64
-	durationStr := strings.TrimSpace(string(output))
-	duration, err := strconv.ParseFloat(durationStr, 64)
+	duration, err := strconv.ParseFloat(strings.TrimSpace(result.Format.Duration), 64)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse video duration: %v", err)
+		return nil, fmt.Errorf("failed to parse video duration: %v", err)
 	}
 
-	// Convert to HH:MM:SS format
-	hours := int(duration) / 3600
-	minutes := (int(duration) % 3600) / 60
-	seconds := int(duration) % 60
+	probe := &VideoProbe{DurationSeconds: duration}
+	hasVideo := false
+	for _, stream := range result.Streams {
+		switch {
+		case stream.CodecType == "video" && !hasVideo:
+			hasVideo = true
+			probe.Width = stream.Width
+			probe.Height = stream.Height
+		case stream.CodecType == "audio":
+			probe.HasAudio = true
+		}
+	}
+	if !hasVideo {
+		return nil, fmt.Errorf("file has no video stream")
+	}
+	return probe, nil
+}
 
-	// Format the duration
-	durationFormatted := fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
-	return durationFormatted, nil
+// TranscodeToMP4 re-encodes a video to H.264/AAC MP4 with the index at the front
+// so it can start playing before it is fully downloaded. onProgress receives
+// the fraction of the input processed so far.
+func TranscodeToMP4(ctx context.Context, inputPath string, outputPath string, durationSeconds float64, onProgress func(float64)) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-y",
+		"-v", "error",
+		"-i", inputPath,
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-crf", "23",
+		"-pix_fmt", "yuv420p",
+		"-c:a", "aac",
+		"-b:a", "128k",
+		"-movflags", "+faststart",
+		"-progress", "pipe:1",
+		"-nostats",
+		outputPath,
+	)
+	return runFFmpeg(cmd, durationSeconds, onProgress)
+}
+
+// ExtractFrame writes a single JPEG frame taken at the given offset
+func ExtractFrame(ctx context.Context, inputPath string, outputPath string, atSeconds float64) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-y",
+		"-v", "error",
+		"-ss", strconv.FormatFloat(atSeconds, 'f', 3, 64),
+		"-i", inputPath,
+		"-frames:v", "1",
+		"-q:v", "3",
+		outputPath,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to extract frame: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// runFFmpeg runs an ffmpeg command started with "-progress pipe:1" and reports progress
+func runFFmpeg(cmd *exec.Cmd, durationSeconds float64, onProgress func(float64)) error {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %v", err)
+	}
+
+	// ffmpeg writes key=value lines; out_time_us is the position reached in the input
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if !found || key != "out_time_us" || durationSeconds <= 0 || onProgress == nil {
+			continue
+		}
+		if micros, err := strconv.ParseFloat(value, 64); err == nil {
+			onProgress(math.Min(micros/1e6/durationSeconds, 1))
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("ffmpeg failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
 }